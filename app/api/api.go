@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
+	"net"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/metrics"
 )
 
 type (
@@ -13,26 +17,105 @@ type (
 		cfg        *config.Config
 		log        *logrus.Logger
 		controller *Controller
+		limiter    RateLimiter
+		probes     *Probes
+		registry   *metrics.Registry
+		requests   *metrics.CounterVec
+		latency    *metrics.HistogramVec
+		server     *http.Server
 	}
 )
 
-func NewApi(controller *Controller, log *logrus.Logger, cfg *config.Config) *Api {
-	return &Api{
+func NewApi(controller *Controller, limiter RateLimiter, probes *Probes, registry *metrics.Registry, log *logrus.Logger, cfg *config.Config) *Api {
+	a := &Api{
 		cfg:        cfg,
 		log:        log,
 		controller: controller,
+		limiter:    limiter,
+		probes:     probes,
+		registry:   registry,
+		requests:   registry.Counter("cdmon2_http_requests_total", "Number of HTTP requests.", "method", "route", "status"),
+		latency:    registry.Histogram("cdmon2_http_request_duration_seconds", "Latency of the HTTP requests.", metrics.DefaultBuckets, "method", "route", "status"),
 	}
+	a.server = &http.Server{
+		Addr:         ":" + cfg.APIPort,
+		Handler:      a.RequestID(a.AccessLog(a.routes())),
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+	return a
+}
+
+// Start serves the API at its port until it's shut down
+func (a *Api) Start() error {
+	l, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return err
+	}
+	a.log.Infof("starting hosting service at port %s", a.cfg.APIPort)
+	return a.Serve(l)
+}
+
+// Serve serves the API on the listener until it's shut down
+func (a *Api) Serve(l net.Listener) error {
+	err := a.server.Serve(l)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
 }
 
-func (a Api) Start() {
+// Shutdown stops accepting new connections, and waits for the in-flight requests until the context is done
+func (a *Api) Shutdown(ctx context.Context) error {
+	a.log.Info("shutting down hosting service")
+	a.probes.SetShuttingDown()
+	return a.server.Shutdown(ctx)
+}
+
+// Handler returns the API routes
+func (a *Api) Handler() http.Handler {
+	return a.server.Handler
+}
+
+func (a *Api) routes() http.Handler {
 	router := mux.NewRouter()
+	router.Use(a.Instrument)
 
-	router.HandleFunc("/health", a.controller.Health).Methods(http.MethodGet)
-	router.HandleFunc("/hosting", a.controller.CreateHosting).Methods(http.MethodPost)
-	router.HandleFunc("/hosting", a.controller.GetHostings).Methods(http.MethodGet)
-	router.HandleFunc("/hosting/{uuid}", a.controller.RemoveHosting).Methods(http.MethodDelete)
-	router.HandleFunc("/hosting", a.controller.UpdateHosting).Methods(http.MethodPut)
+	// The probes and the metrics are not rate limited, so they can be polled as often as needed
+	router.HandleFunc("/livez", a.probes.Livez).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", a.probes.Readyz).Methods(http.MethodGet)
+	router.Handle("/metrics", a.registry).Methods(http.MethodGet)
 
-	a.log.Infof("starting hosting service at port %s", a.cfg.APIPort)
-	a.log.Info(http.ListenAndServe(":"+a.cfg.APIPort, router))
+	c := a.controller
+	limited := router.NewRoute().Subrouter()
+	limited.Use(a.RateLimit)
+
+	// Reading endpoints are granted to every role. Hostings can be managed by operators,
+	// while customers, projects, credentials and the server capacity can be managed by admins only.
+	// The consistency of the server is checked with a GET of /admin/reconcile, and repaired with a POST.
+	limited.HandleFunc("/health", c.Health).Methods(http.MethodGet)
+	limited.HandleFunc("/hosting", c.Authorize(domain.RoleOperator, c.CreateHosting)).Methods(http.MethodPost)
+	limited.HandleFunc("/hosting", c.Authorize(domain.RoleReadOnly, c.GetHostings)).Methods(http.MethodGet)
+	limited.HandleFunc("/hosting/{uuid}", c.Authorize(domain.RoleOperator, c.RemoveHosting)).Methods(http.MethodDelete)
+	limited.HandleFunc("/hosting", c.Authorize(domain.RoleOperator, c.UpdateHosting)).Methods(http.MethodPut)
+	limited.HandleFunc("/customer", c.Authorize(domain.RoleAdmin, c.CreateCustomer)).Methods(http.MethodPost)
+	limited.HandleFunc("/customer", c.Authorize(domain.RoleReadOnly, c.GetCustomers)).Methods(http.MethodGet)
+	limited.HandleFunc("/customer", c.Authorize(domain.RoleAdmin, c.UpdateCustomer)).Methods(http.MethodPut)
+	limited.HandleFunc("/customer/{uuid}", c.Authorize(domain.RoleReadOnly, c.GetCustomer)).Methods(http.MethodGet)
+	limited.HandleFunc("/customer/{uuid}", c.Authorize(domain.RoleAdmin, c.RemoveCustomer)).Methods(http.MethodDelete)
+	limited.HandleFunc("/customer/{uuid}/hosting", c.Authorize(domain.RoleReadOnly, c.GetCustomerHostings)).Methods(http.MethodGet)
+	limited.HandleFunc("/customer/{uuid}/usage", c.Authorize(domain.RoleReadOnly, c.GetCustomerUsage)).Methods(http.MethodGet)
+	limited.HandleFunc("/customer/{uuid}/project", c.Authorize(domain.RoleAdmin, c.CreateProject)).Methods(http.MethodPost)
+	limited.HandleFunc("/customer/{uuid}/project", c.Authorize(domain.RoleReadOnly, c.GetProjects)).Methods(http.MethodGet)
+	limited.HandleFunc("/project", c.Authorize(domain.RoleAdmin, c.UpdateProject)).Methods(http.MethodPut)
+	limited.HandleFunc("/project/{uuid}", c.Authorize(domain.RoleReadOnly, c.GetProject)).Methods(http.MethodGet)
+	limited.HandleFunc("/project/{uuid}", c.Authorize(domain.RoleAdmin, c.RemoveProject)).Methods(http.MethodDelete)
+	limited.HandleFunc("/apikey", c.Authorize(domain.RoleAdmin, c.CreateAPIKey)).Methods(http.MethodPost)
+	limited.HandleFunc("/apikey", c.Authorize(domain.RoleAdmin, c.GetAPIKeys)).Methods(http.MethodGet)
+	limited.HandleFunc("/apikey/{uuid}", c.Authorize(domain.RoleAdmin, c.RemoveAPIKey)).Methods(http.MethodDelete)
+	limited.HandleFunc("/admin/reload", c.Authorize(domain.RoleAdmin, c.ReloadCapacity)).Methods(http.MethodPost)
+	limited.HandleFunc("/admin/reconcile", c.Authorize(domain.RoleAdmin, c.Reconcile)).Methods(http.MethodGet, http.MethodPost)
+
+	return router
 }
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApi_Shutdown(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusTeapot)
+	}
+	a := &Api{log: log, probes: NewProbes(), server: &http.Server{Handler: http.HandlerFunc(handler)}}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	served := make(chan error, 1)
+	go func() { served <- a.Serve(l) }()
+
+	// given an in-flight request
+	status := make(chan int, 1)
+	go func() {
+		r, err := http.Get("http://" + addr + "/hosting")
+		if err != nil {
+			status <- 0
+			return
+		}
+		r.Body.Close()
+		status <- r.StatusCode
+	}()
+	<-started
+
+	// when the API is shut down
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- a.Shutdown(ctx) }()
+
+	// then the new connections are refused
+	refused := false
+	for deadline := time.Now().Add(5 * time.Second); !refused && time.Now().Before(deadline); {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			refused = true
+		} else {
+			conn.Close()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	assert.True(t, refused)
+
+	// while the shutdown waits for the in-flight request
+	select {
+	case err := <-shutdown:
+		t.Fatalf("the shutdown didn't wait for the in-flight request: %v", err)
+	default:
+	}
+
+	// which is finished
+	close(release)
+	assert.Equal(t, http.StatusTeapot, <-status)
+	assert.NoError(t, <-shutdown)
+	assert.NoError(t, <-served)
+}
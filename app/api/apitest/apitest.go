@@ -0,0 +1,78 @@
+// Package apitest serves the real API, with its services and repositories, over a memory store,
+// so the API and its clients can be tested end to end without Redis.
+package apitest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/metrics"
+	"github.com/theskyinflames/cdmon2/app/ratelimit"
+	"github.com/theskyinflames/cdmon2/app/repository"
+	"github.com/theskyinflames/cdmon2/app/service"
+	"github.com/theskyinflames/cdmon2/app/store"
+)
+
+// AdminKey is the bootstrap API key of the service
+const AdminKey = "admin-key"
+
+type (
+	// Service is the API being served. Its services are exposed, so the tests can set up their data directly.
+	Service struct {
+		*httptest.Server
+		Config          *config.Config
+		ServerService   *service.ServerService
+		CustomerService *service.CustomerService
+		AuthService     *service.AuthService
+	}
+)
+
+// New serves the real API over a memory store, with a server of the given cores, mb of memory and mb of disk.
+// The authentication is enabled, and the minimal sizes of a hosting are 1. The service is closed along with the test.
+func New(t *testing.T, cores, memorymb, diskmb int) *Service {
+	cfg := &config.Config{
+		TotalNumberOfCores:    cores,
+		TotalSizeOfMemoryMb:   memorymb,
+		TotalSizeOfDiskMb:     diskmb,
+		MinimalNumberOfCores:  1,
+		MinimalSizeOfMemoryMb: 1,
+		MinimalSizeOfDiskMb:   1,
+		AuthEnabled:           true,
+		BootstrapAPIKey:       AdminKey,
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	server, err := domain.NewServer(cfg)
+	require.NoError(t, err)
+	memory := store.NewMemoryStore()
+	hostings := repository.NewHostingReposytoryMap(cfg, memory)
+	customers := repository.NewCustomerRepositoryMap(cfg, memory)
+	projects := repository.NewProjectRepositoryMap(cfg, memory)
+
+	serverService := service.NewServer(hostings, customers, projects, server, cfg, log)
+	customerService := service.NewCustomer(customers, projects, hostings, serverService, cfg, log)
+	authService := service.NewAuth(repository.NewAPIKeyRepositoryMap(cfg, memory), cfg, log)
+	require.NoError(t, authService.Bootstrap(context.Background()))
+
+	controller := api.NewController(serverService, customerService, authService, log, cfg)
+	a := api.NewApi(controller, ratelimit.NewMemoryLimiter(), api.NewProbes(), metrics.NewRegistry(), log, cfg)
+
+	s := &Service{
+		Server:          httptest.NewServer(a.Handler()),
+		Config:          cfg,
+		ServerService:   serverService,
+		CustomerService: customerService,
+		AuthService:     authService,
+	}
+	t.Cleanup(s.Close)
+	return s
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+const (
+	APIKeyHeader        = "X-API-Key"
+	AuthorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+type (
+	AuthService interface {
+		AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error)
+		AuthenticateJWT(ctx context.Context, token string) (*domain.Principal, error)
+		CreateAPIKey(ctx context.Context, name string, role domain.Role, tenant domain.UUID) (*domain.APIKey, string, error)
+		GetAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+		RemoveAPIKey(ctx context.Context, uuid domain.UUID) error
+	}
+
+	principalKey struct{}
+
+	// authentication is the outcome of authenticating a request, kept in it so it's only done once
+	authentication struct {
+		principal *domain.Principal
+		err       error
+	}
+	authenticationKey struct{}
+
+	AuthErrorRs struct {
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	CreateAPIKeyRq struct {
+		Name   string      `json:"name"`
+		Role   domain.Role `json:"role"`
+		Tenant domain.UUID `json:"tenant,omitempty"`
+	}
+	CreateAPIKeyRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		Key    string `json:"key,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	GetAPIKeysRs struct {
+		APIKeys []domain.APIKey
+		ErrMsg  string `json:"error,omitempty"`
+	}
+
+	RemoveAPIKeyRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+)
+
+// PrincipalFrom returns the authenticated caller of the request, if any
+func PrincipalFrom(ctx context.Context) (*domain.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*domain.Principal)
+	return principal, ok
+}
+
+// caller returns the authenticated caller of the request. It's nil when the authentication is disabled.
+func caller(r *http.Request) *domain.Principal {
+	principal, _ := PrincipalFrom(r.Context())
+	return principal
+}
+
+// Authorize wraps the handler, so it's only called for authenticated callers which are granted with the role
+func (c *Controller) Authorize(role domain.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.cfg.AuthEnabled {
+			next(w, r)
+			return
+		}
+
+		principal, err := c.authenticate(r)
+		if err != nil {
+			rs := AuthErrorRs{ErrMsg: err.Error()}
+			switch errors.Cause(err) {
+			case app.AuthErrorUnauthenticated:
+				w.Header().Set("WWW-Authenticate", `Bearer realm="cdmon2"`)
+				c.respondWithJson(w, http.StatusUnauthorized, &rs, r)
+			default:
+				c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+			}
+			return
+		}
+		if entry := accessEntryOf(r); entry != nil {
+			entry.caller = principal
+		}
+
+		if !principal.Role.Allows(role) {
+			err = errors.Wrapf(app.AuthErrorForbidden, "the role %s is required", string(role))
+			c.respondWithJson(w, http.StatusForbidden, &AuthErrorRs{ErrMsg: err.Error()}, r)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+// authenticate returns the caller of the request, unless the request has already been authenticated
+func (c *Controller) authenticate(r *http.Request) (*domain.Principal, error) {
+	if a, ok := r.Context().Value(authenticationKey{}).(*authentication); ok {
+		return a.principal, a.err
+	}
+
+	if key := r.Header.Get(APIKeyHeader); len(key) > 0 {
+		return c.authService.AuthenticateAPIKey(r.Context(), key)
+	}
+
+	authorization := r.Header.Get(AuthorizationHeader)
+	if strings.HasPrefix(authorization, bearerPrefix) {
+		return c.authService.AuthenticateJWT(r.Context(), strings.TrimPrefix(authorization, bearerPrefix))
+	}
+
+	return nil, errors.Wrap(app.AuthErrorUnauthenticated, "there are not credentials")
+}
+
+func (c *Controller) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		rq CreateAPIKeyRq
+		rs CreateAPIKeyRs
+	)
+
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
+		return
+	}
+
+	apiKey, key, err := c.authService.CreateAPIKey(r.Context(), rq.Name, rq.Role, rq.Tenant)
+	if err != nil {
+		rs = CreateAPIKeyRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DomainErrorInvalid:
+			c.respondWithJson(w, http.StatusBadRequest, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = CreateAPIKeyRs{UUID: string(apiKey.UUID), Key: key}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var rs GetAPIKeysRs
+
+	apiKeys, err := c.authService.GetAPIKeys(r.Context())
+	if err != nil {
+		rs = GetAPIKeysRs{ErrMsg: err.Error()}
+		c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		return
+	}
+
+	rs = GetAPIKeysRs{APIKeys: apiKeys}
+	if len(apiKeys) > 0 {
+		c.respondWithJson(w, http.StatusFound, &rs, r)
+	} else {
+		c.respondWithJson(w, http.StatusOK, &rs, r)
+	}
+}
+
+func (c *Controller) RemoveAPIKey(w http.ResponseWriter, r *http.Request) {
+	var rs RemoveAPIKeyRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	err := c.authService.RemoveAPIKey(r.Context(), domain.UUID(uuid))
+	if err != nil {
+		rs = RemoveAPIKeyRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = RemoveAPIKeyRs{UUID: uuid}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
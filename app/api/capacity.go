@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	ReloadCapacityRs struct {
+		Changes      []string             `json:"changes"`
+		ServerStatus *domain.ServerStatus `json:"server_status,omitempty"`
+		ErrMsg       string               `json:"error,omitempty"`
+	}
+)
+
+// ReloadCapacity applies the capacity settings of the configuration, as the SIGHUP signal does
+func (c *Controller) ReloadCapacity(w http.ResponseWriter, r *http.Request) {
+	var (
+		rs ReloadCapacityRs
+	)
+
+	changes, err := c.serverService.ReloadCapacity(r.Context())
+	if err != nil {
+		rs = ReloadCapacityRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DomainErrorQuotaExceeded, app.DomainErrorInvalid:
+			c.respondWithJson(w, http.StatusUnprocessableEntity, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	if changes == nil {
+		changes = []string{}
+	}
+	status := c.serverService.GetServerStatus()
+	rs = ReloadCapacityRs{Changes: changes, ServerStatus: &status}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
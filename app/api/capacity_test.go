@@ -0,0 +1,59 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/api/apitest"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+func TestController_ReloadCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantStatus int
+	}{
+		{
+			name: "given a valid configuration, when the capacity is reloaded, then it's applied",
+			env: map[string]string{
+				config.TotalNumberOfCores:  "20",
+				config.TotalSizeOfMemoryMb: "10",
+				config.TotalSizeOfDiskMb:   "10",
+				config.RedisAddr:           "localhost:6379",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "given a configuration with problems, when the capacity is reloaded, then it's refused as unprocessable",
+			env: map[string]string{
+				config.TotalNumberOfCores:  "lots",
+				config.TotalSizeOfMemoryMb: "10",
+				config.TotalSizeOfDiskMb:   "10",
+				config.RedisAddr:           "localhost:6379",
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTenants(t)
+			t.Setenv(config.ConfigFile, "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			rq, err := http.NewRequest(http.MethodPost, tt.server.URL+"/admin/reload", nil)
+			require.NoError(t, err)
+			rq.Header.Set(api.APIKeyHeader, apitest.AdminKey)
+			r, err := http.DefaultClient.Do(rq)
+			require.NoError(t, err)
+			r.Body.Close()
+			assert.Equal(t, tc.wantStatus, r.StatusCode)
+		})
+	}
+}
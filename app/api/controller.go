@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -11,21 +12,40 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
 )
 
 type (
 	ServerService interface {
-		CreateHosting(name string, cores int, memorymb int, diskmb int) (domain.UUID, error)
-		GetHostings() ([]domain.Hosting, error)
-		RemoveHosting(uuid domain.UUID) error
-		UpdateHosting(hosting *domain.Hosting) error
-		GetServerStatus() *domain.Server
+		CreateHosting(ctx context.Context, principal *domain.Principal, name string, cores int, memorymb int, diskmb int, owner, project domain.UUID) (domain.UUID, error)
+		GetHostings(ctx context.Context, principal *domain.Principal) ([]domain.Hosting, error)
+		GetCustomerHostings(ctx context.Context, principal *domain.Principal, owner domain.UUID) ([]domain.Hosting, error)
+		RemoveHosting(ctx context.Context, principal *domain.Principal, uuid domain.UUID) error
+		UpdateHosting(ctx context.Context, principal *domain.Principal, hosting *domain.Hosting) error
+		ReloadCapacity(ctx context.Context) ([]string, error)
+		Reconcile(ctx context.Context, repair bool) ([]domain.Discrepancy, error)
+		GetServerStatus() domain.ServerStatus
+	}
+
+	CustomerService interface {
+		CreateCustomer(ctx context.Context, name string, quota domain.Quota) (domain.UUID, error)
+		GetCustomers(ctx context.Context, principal *domain.Principal) ([]domain.Customer, error)
+		GetCustomer(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.Customer, error)
+		UpdateCustomer(ctx context.Context, customer *domain.Customer) error
+		RemoveCustomer(ctx context.Context, uuid domain.UUID) error
+		GetCustomerUsage(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.CustomerUsage, error)
+		CreateProject(ctx context.Context, owner domain.UUID, name string, quota domain.Quota) (domain.UUID, error)
+		GetProjects(ctx context.Context, principal *domain.Principal, owner domain.UUID) ([]domain.Project, error)
+		GetProject(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.Project, error)
+		UpdateProject(ctx context.Context, project *domain.Project) error
+		RemoveProject(ctx context.Context, uuid domain.UUID) error
 	}
 
 	HealthRs struct {
 		RunningTime  string `json:"running_time"`
-		ServerStatus *domain.Server
+		ServerStatus domain.ServerStatus
 	}
 
 	CreateHostingRq struct {
@@ -55,17 +75,23 @@ type (
 	}
 
 	Controller struct {
-		log           *logrus.Logger
-		startTime     time.Time
-		serverService ServerService
+		log             *logrus.Logger
+		cfg             *config.Config
+		startTime       time.Time
+		serverService   ServerService
+		customerService CustomerService
+		authService     AuthService
 	}
 )
 
-func NewController(serverService ServerService, log *logrus.Logger) *Controller {
+func NewController(serverService ServerService, customerService CustomerService, authService AuthService, log *logrus.Logger, cfg *config.Config) *Controller {
 	return &Controller{
-		log:           log,
-		serverService: serverService,
-		startTime:     time.Now(),
+		log:             log,
+		cfg:             cfg,
+		serverService:   serverService,
+		customerService: customerService,
+		authService:     authService,
+		startTime:       time.Now(),
 	}
 }
 
@@ -77,7 +103,7 @@ func (c *Controller) Health(w http.ResponseWriter, r *http.Request) {
 	runningTime := time.Now().Sub(c.startTime)
 	serverStatus := c.serverService.GetServerStatus()
 	rs = HealthRs{RunningTime: durafmt.Parse(runningTime).String(), ServerStatus: serverStatus}
-	c.respondWithJson(w, http.StatusOK, &rs, r.Method)
+	c.respondWithJson(w, http.StatusOK, &rs, r)
 }
 
 func (c *Controller) CreateHosting(w http.ResponseWriter, r *http.Request) {
@@ -88,24 +114,32 @@ func (c *Controller) CreateHosting(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&rq)
 	if err != nil {
-		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r.Method)
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
 		return
 	}
 
-	uuid, err := c.serverService.CreateHosting(rq.Name, rq.Cores, rq.MemoryMb, rq.DiskMb)
+	uuid, err := c.serverService.CreateHosting(r.Context(), caller(r), rq.Name, rq.Cores, rq.MemoryMb, rq.DiskMb, rq.Owner, rq.Project)
 	if err != nil {
 		rs = CreateHostingRs{ErrMsg: err.Error()}
 		switch errors.Cause(err) {
 		case app.DbErrorAlreadyExist:
-			c.respondWithJson(w, http.StatusConflict, &rs, r.Method)
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DomainErrorQuotaExceeded:
+			c.respondWithJson(w, http.StatusUnprocessableEntity, &rs, r)
+		case app.DomainErrorInvalid:
+			c.respondWithJson(w, http.StatusBadRequest, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
 		default:
-			c.respondWithJson(w, http.StatusInternalServerError, &rs, r.Method)
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
 		}
 		return
 	}
 
 	rs = CreateHostingRs{UUID: string(uuid)}
-	c.respondWithJson(w, http.StatusOK, &rs, r.Method)
+	c.respondWithJson(w, http.StatusOK, &rs, r)
 }
 
 func (c *Controller) GetHostings(w http.ResponseWriter, r *http.Request) {
@@ -113,18 +147,48 @@ func (c *Controller) GetHostings(w http.ResponseWriter, r *http.Request) {
 		rs GetHostingsRs
 	)
 
-	hostings, err := c.serverService.GetHostings()
+	hostings, err := c.serverService.GetHostings(r.Context(), caller(r))
 	if err != nil {
 		rs = GetHostingsRs{ErrMsg: err.Error()}
-		c.respondWithJson(w, http.StatusInternalServerError, &rs, r.Method)
+		c.respondWithJson(w, serverErrorStatus(err), &rs, r)
 		return
 	}
 
 	rs = GetHostingsRs{Hostings: hostings}
 	if len(hostings) > 0 {
-		c.respondWithJson(w, http.StatusFound, &rs, r.Method)
+		c.respondWithJson(w, http.StatusFound, &rs, r)
 	} else {
-		c.respondWithJson(w, http.StatusOK, &rs, r.Method)
+		c.respondWithJson(w, http.StatusOK, &rs, r)
+	}
+}
+
+func (c *Controller) GetCustomerHostings(w http.ResponseWriter, r *http.Request) {
+	var (
+		rs GetHostingsRs
+	)
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	hostings, err := c.serverService.GetCustomerHostings(r.Context(), caller(r), domain.UUID(uuid))
+	if err != nil {
+		rs = GetHostingsRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = GetHostingsRs{Hostings: hostings}
+	if len(hostings) > 0 {
+		c.respondWithJson(w, http.StatusFound, &rs, r)
+	} else {
+		c.respondWithJson(w, http.StatusOK, &rs, r)
 	}
 }
 
@@ -134,20 +198,22 @@ func (c *Controller) RemoveHosting(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	uuid := params["uuid"]
 
-	err := c.serverService.RemoveHosting(domain.UUID(uuid))
+	err := c.serverService.RemoveHosting(r.Context(), caller(r), domain.UUID(uuid))
 	if err != nil {
 		rs = RemoveHostingRs{ErrMsg: err.Error()}
 		switch errors.Cause(err) {
 		case app.DbErrorNotFound:
-			c.respondWithJson(w, http.StatusNotFound, &rs, r.Method)
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
 		default:
-			c.respondWithJson(w, http.StatusInternalServerError, &rs, r.Method)
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
 		}
 		return
 	}
 
 	rs = RemoveHostingRs{UUID: uuid}
-	c.respondWithJson(w, http.StatusOK, rs, r.Method)
+	c.respondWithJson(w, http.StatusOK, rs, r)
 }
 
 func (c *Controller) UpdateHosting(w http.ResponseWriter, r *http.Request) {
@@ -158,32 +224,40 @@ func (c *Controller) UpdateHosting(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&rq)
 	if err != nil {
-		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r.Method)
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
 		return
 	}
 
-	err = c.serverService.UpdateHosting(&rq.Hosting)
+	err = c.serverService.UpdateHosting(r.Context(), caller(r), &rq.Hosting)
 	if err != nil {
 		rs = UpdateHostingRs{ErrMsg: err.Error()}
 		switch errors.Cause(err) {
 		case app.DbErrorNotFound:
-			c.respondWithJson(w, http.StatusNotFound, &rs, r.Method)
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorAlreadyExist:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		case app.DomainErrorQuotaExceeded:
+			c.respondWithJson(w, http.StatusUnprocessableEntity, &rs, r)
+		case app.DomainErrorInvalid:
+			c.respondWithJson(w, http.StatusBadRequest, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
 		default:
-			c.respondWithJson(w, http.StatusInternalServerError, &rs, r.Method)
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
 		}
 		return
 	}
 
 	rs = UpdateHostingRs{UUID: string(rq.UUID)}
-	c.respondWithJson(w, http.StatusOK, &rs, r.Method)
+	c.respondWithJson(w, http.StatusOK, &rs, r)
 }
 
-func (c *Controller) respondWithJson(w http.ResponseWriter, code int, payload interface{}, action string) {
+func (c *Controller) respondWithJson(w http.ResponseWriter, code int, payload interface{}, r *http.Request) {
 	response, _ := json.Marshal(payload)
 
 	if code != http.StatusOK && code != http.StatusFound && response != nil {
-		c.log.WithFields(logrus.Fields{
-			"action":      action,
+		requestid.Logger(c.log, r.Context()).WithFields(logrus.Fields{
+			"action":      r.Method,
 			"http_status": code,
 		}).Error(string(response))
 	}
@@ -192,3 +266,12 @@ func (c *Controller) respondWithJson(w http.ResponseWriter, code int, payload in
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+// serverErrorStatus returns the status of the errors which are not caused by the request.
+// An unavailable store is expected to recover, so the client is told to try again later.
+func serverErrorStatus(err error) int {
+	if errors.Cause(err) == app.DbErrorUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	CreateCustomerRq struct {
+		domain.Customer
+	}
+	CreateCustomerRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	GetCustomersRs struct {
+		Customers []domain.Customer
+		ErrMsg    string `json:"error,omitempty"`
+	}
+
+	GetCustomerRs struct {
+		Customer *domain.Customer `json:"customer,omitempty"`
+		ErrMsg   string           `json:"error,omitempty"`
+	}
+
+	UpdateCustomerRq struct {
+		domain.Customer
+	}
+	UpdateCustomerRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	RemoveCustomerRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	GetCustomerUsageRs struct {
+		Usage  *domain.CustomerUsage `json:"usage,omitempty"`
+		ErrMsg string                `json:"error,omitempty"`
+	}
+)
+
+func (c *Controller) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var (
+		rq CreateCustomerRq
+		rs CreateCustomerRs
+	)
+
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
+		return
+	}
+
+	uuid, err := c.customerService.CreateCustomer(r.Context(), rq.Name, rq.Quota)
+	if err != nil {
+		rs = CreateCustomerRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorAlreadyExist:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = CreateCustomerRs{UUID: string(uuid)}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) GetCustomers(w http.ResponseWriter, r *http.Request) {
+	var (
+		rs GetCustomersRs
+	)
+
+	customers, err := c.customerService.GetCustomers(r.Context(), caller(r))
+	if err != nil {
+		rs = GetCustomersRs{ErrMsg: err.Error()}
+		c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		return
+	}
+
+	rs = GetCustomersRs{Customers: customers}
+	if len(customers) > 0 {
+		c.respondWithJson(w, http.StatusFound, &rs, r)
+	} else {
+		c.respondWithJson(w, http.StatusOK, &rs, r)
+	}
+}
+
+func (c *Controller) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	var rs GetCustomerRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	customer, err := c.customerService.GetCustomer(r.Context(), caller(r), domain.UUID(uuid))
+	if err != nil {
+		rs = GetCustomerRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = GetCustomerRs{Customer: customer}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	var (
+		rq UpdateCustomerRq
+		rs UpdateCustomerRs
+	)
+
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
+		return
+	}
+
+	err = c.customerService.UpdateCustomer(r.Context(), &rq.Customer)
+	if err != nil {
+		rs = UpdateCustomerRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorAlreadyExist:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		case app.DomainErrorQuotaExceeded:
+			c.respondWithJson(w, http.StatusUnprocessableEntity, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = UpdateCustomerRs{UUID: string(rq.UUID)}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) RemoveCustomer(w http.ResponseWriter, r *http.Request) {
+	var rs RemoveCustomerRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	err := c.customerService.RemoveCustomer(r.Context(), domain.UUID(uuid))
+	if err != nil {
+		rs = RemoveCustomerRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorInUse:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = RemoveCustomerRs{UUID: uuid}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) GetCustomerUsage(w http.ResponseWriter, r *http.Request) {
+	var rs GetCustomerUsageRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	usage, err := c.customerService.GetCustomerUsage(r.Context(), caller(r), domain.UUID(uuid))
+	if err != nil {
+		rs = GetCustomerUsageRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = GetCustomerUsageRs{Usage: usage}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
@@ -0,0 +1,163 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/api/apitest"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type tenants struct {
+	server         *apitest.Service
+	owned, foreign domain.UUID
+	foreignProject domain.UUID
+	tenantKey      string
+}
+
+// newTenants serves the real API with two customers, a project of the foreign one and a read-only key of the owned one
+func newTenants(t *testing.T) *tenants {
+	ctx := context.Background()
+	s := apitest.New(t, 10, 10, 10)
+
+	var err error
+	tt := &tenants{server: s}
+	tt.owned, err = s.CustomerService.CreateCustomer(ctx, "owned", domain.Quota{})
+	require.NoError(t, err)
+	tt.foreign, err = s.CustomerService.CreateCustomer(ctx, "foreign", domain.Quota{})
+	require.NoError(t, err)
+	tt.foreignProject, err = s.CustomerService.CreateProject(ctx, tt.foreign, "web", domain.Quota{})
+	require.NoError(t, err)
+	_, tt.tenantKey, err = s.AuthService.CreateAPIKey(ctx, "owned", domain.RoleReadOnly, tt.owned)
+	require.NoError(t, err)
+	return tt
+}
+
+func (tt *tenants) get(t *testing.T, key, path string, rs interface{}) int {
+	rq, err := http.NewRequest(http.MethodGet, tt.server.URL+path, nil)
+	require.NoError(t, err)
+	rq.Header.Set(api.APIKeyHeader, key)
+	r, err := http.DefaultClient.Do(rq)
+	require.NoError(t, err)
+	defer r.Body.Close()
+	if rs != nil {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(rs))
+	}
+	return r.StatusCode
+}
+
+func TestController_TenantReads(t *testing.T) {
+	tt := newTenants(t)
+
+	tests := []struct {
+		name       string
+		key        string
+		path       string
+		wantStatus int
+	}{
+		{
+			name:       "given a tenant key, when the customer of another tenant is got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/customer/" + string(tt.foreign),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when a missing customer is got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/customer/missing",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when the usage of another tenant is got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/customer/" + string(tt.foreign) + "/usage",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when the projects of another tenant are got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/customer/" + string(tt.foreign) + "/project",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when a project of another tenant is got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/project/" + string(tt.foreignProject),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when a missing project is got, then it's forbidden",
+			key:        tt.tenantKey,
+			path:       "/project/missing",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a tenant key, when its own customer is got, then it's returned",
+			key:        tt.tenantKey,
+			path:       "/customer/" + string(tt.owned),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "given a tenant key, when its own usage is got, then it's returned",
+			key:        tt.tenantKey,
+			path:       "/customer/" + string(tt.owned) + "/usage",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "given the admin key, when a project of any tenant is got, then it's returned",
+			key:        apitest.AdminKey,
+			path:       "/project/" + string(tt.foreignProject),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "given the admin key, when a missing project is got, then it's not found",
+			key:        apitest.AdminKey,
+			path:       "/project/missing",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantStatus, tt.get(t, tc.key, tc.path, nil))
+		})
+	}
+}
+
+func TestController_GetCustomers(t *testing.T) {
+	tt := newTenants(t)
+
+	tests := []struct {
+		name string
+		key  string
+		want []domain.UUID
+	}{
+		{
+			name: "given a tenant key, when the customers are got, then only its own one is returned",
+			key:  tt.tenantKey,
+			want: []domain.UUID{tt.owned},
+		},
+		{
+			name: "given the admin key, when the customers are got, then all of them are returned",
+			key:  apitest.AdminKey,
+			want: []domain.UUID{tt.owned, tt.foreign},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var rs api.GetCustomersRs
+			assert.Equal(t, http.StatusFound, tt.get(t, tc.key, "/customer", &rs))
+
+			got := make([]domain.UUID, 0, len(rs.Customers))
+			for _, c := range rs.Customers {
+				got = append(got, c.UUID)
+			}
+			assert.ElementsMatch(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+type (
+	// accessEntry is what the access log can't see from outside the router, so it's filled along
+	// the request: the route matched, by Instrument, and the authenticated caller, by Authorize
+	accessEntry struct {
+		route  string
+		caller *domain.Principal
+	}
+
+	accessEntryKey struct{}
+)
+
+// accessEntryOf returns the access entry of the request, nil when it's not logged
+func accessEntryOf(r *http.Request) *accessEntry {
+	entry, _ := r.Context().Value(accessEntryKey{}).(*accessEntry)
+	return entry
+}
+
+// statusRecorder keeps the status code and the size of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// RequestID is a middleware which gives an ID to each request, so all its log lines can be correlated.
+// The ID given by the client in the X-Request-ID header is kept, and it's always returned in the response.
+func (a *Api) RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// AccessLog is a middleware which logs a line by each request, once it has been answered.
+// Besides the path, the line has the route matched and the authenticated caller, if any.
+func (a *Api) AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		entry := &accessEntry{}
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+
+		fields := logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"http_status": recorder.status,
+			"bytes":       recorder.bytes,
+			"duration_ms": float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		}
+		if len(entry.route) > 0 {
+			fields["route"] = entry.route
+		}
+		if entry.caller != nil {
+			fields["caller"] = entry.caller.Subject
+		}
+		requestid.Logger(a.log, r.Context()).WithFields(fields).Info("access")
+	})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/metrics"
+	"github.com/theskyinflames/cdmon2/app/ratelimit"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+// authStub authenticates the API key "key" as a read-only caller
+type authStub struct {
+	AuthService
+}
+
+func (authStub) AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error) {
+	if key != "key" {
+		return nil, app.AuthErrorUnauthenticated
+	}
+	return &domain.Principal{Subject: "k1", Role: domain.RoleReadOnly, Tenant: "customer1"}, nil
+}
+
+func TestApi_RequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "given a request with an ID, when it's served, then the ID is kept", header: "abc-123", wantSame: true},
+		{name: "given a request without ID, when it's served, then a new ID is given", header: "", wantSame: false},
+		{name: "given a request with a wrong ID, when it's served, then a new ID is given", header: "abc\n123", wantSame: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, hook := test.NewNullLogger()
+			a := &Api{log: log}
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				requestid.Logger(log, r.Context()).Info("handled")
+				w.WriteHeader(http.StatusTeapot)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/hosting", nil)
+			if len(tt.header) > 0 {
+				r.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			a.RequestID(a.AccessLog(http.HandlerFunc(handler))).ServeHTTP(w, r)
+
+			id := w.Header().Get(requestid.Header)
+			assert.True(t, requestid.Valid(id))
+			assert.Equal(t, tt.wantSame, id == tt.header)
+
+			// The handler line and the access line are correlated by the request ID
+			entries := hook.AllEntries()
+			assert.Equal(t, 2, len(entries))
+			for _, e := range entries {
+				assert.Equal(t, id, e.Data[requestid.Field])
+			}
+			access := hook.LastEntry()
+			assert.Equal(t, logrus.InfoLevel, access.Level)
+			assert.Equal(t, http.StatusTeapot, access.Data["http_status"])
+			assert.Equal(t, "/hosting", access.Data["path"])
+		})
+	}
+}
+
+func TestApi_AccessLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		wantStatus int
+		wantRoute  interface{}
+		wantCaller interface{}
+	}{
+		{
+			name:       "given an authenticated caller, when it's served, then its route and the caller are logged",
+			method:     http.MethodDelete,
+			path:       "/hosting/h1",
+			key:        "key",
+			wantStatus: http.StatusForbidden,
+			wantRoute:  "/hosting/{uuid}",
+			wantCaller: "k1",
+		},
+		{
+			name:       "given an unauthenticated caller, when it's served, then its route is logged without caller",
+			method:     http.MethodDelete,
+			path:       "/hosting/h1",
+			key:        "other",
+			wantStatus: http.StatusUnauthorized,
+			wantRoute:  "/hosting/{uuid}",
+		},
+		{
+			name:       "given an unknown path, when it's served, then it's logged without route",
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, hook := test.NewNullLogger()
+			cfg := &config.Config{AuthEnabled: true}
+			controller := NewController(nil, nil, authStub{}, log, cfg)
+			a := NewApi(controller, ratelimit.NewMemoryLimiter(), NewProbes(), metrics.NewRegistry(), log, cfg)
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if len(tt.key) > 0 {
+				r.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			a.Handler().ServeHTTP(w, r)
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			access := hook.LastEntry()
+			assert.Equal(t, "access", access.Message)
+			assert.Equal(t, tt.path, access.Data["path"])
+			assert.Equal(t, tt.wantRoute, access.Data["route"])
+			assert.Equal(t, tt.wantCaller, access.Data["caller"])
+		})
+	}
+}
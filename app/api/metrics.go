@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Instrument is a middleware which counts the requests and measures their latency, by route and status.
+// As it runs inside the router, it also tells the route matched to the access log.
+func (a *Api) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		path := pathOf(r)
+		if entry := accessEntryOf(r); entry != nil {
+			entry.route = path
+		}
+		status := strconv.Itoa(recorder.status)
+		a.requests.Inc(r.Method, path, status)
+		a.latency.Observe(time.Since(start).Seconds(), r.Method, path, status)
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+const (
+	probeStatusOK   = "ok"
+	probeStatusFail = "fail"
+
+	startupCheck  = "startup"
+	shutdownCheck = "shutdown"
+)
+
+type (
+	// Check returns an error if the dependency it checks is not available
+	Check func() error
+
+	namedCheck struct {
+		name  string
+		check Check
+	}
+
+	// Probes tells the orchestrator whether the process is alive, and whether it's ready to serve requests
+	Probes struct {
+		sync.RWMutex
+		checks       []namedCheck
+		started      bool
+		shuttingDown bool
+	}
+
+	ProbeRs struct {
+		Status string             `json:"status"`
+		Checks map[string]CheckRs `json:"checks,omitempty"`
+	}
+
+	CheckRs struct {
+		Status string `json:"status"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+)
+
+func NewProbes() *Probes {
+	return &Probes{}
+}
+
+// AddCheck adds a check to the readiness probe
+func (p *Probes) AddCheck(name string, check Check) {
+	p.Lock()
+	defer p.Unlock()
+	p.checks = append(p.checks, namedCheck{name: name, check: check})
+}
+
+// SetStarted marks the end of the start up tasks. The service is not ready until then.
+func (p *Probes) SetStarted() {
+	p.Lock()
+	defer p.Unlock()
+	p.started = true
+}
+
+// SetShuttingDown marks the start of the shutdown. The service is not ready since then.
+func (p *Probes) SetShuttingDown() {
+	p.Lock()
+	defer p.Unlock()
+	p.shuttingDown = true
+}
+
+// Livez answers whenever the process is able to serve requests
+func (p *Probes) Livez(w http.ResponseWriter, r *http.Request) {
+	respondProbe(w, http.StatusOK, &ProbeRs{Status: probeStatusOK})
+}
+
+// Readyz answers with the result of each readiness check. It fails if any of them fails.
+func (p *Probes) Readyz(w http.ResponseWriter, r *http.Request) {
+	p.RLock()
+	started, shuttingDown := p.started, p.shuttingDown
+	checks := p.checks
+	p.RUnlock()
+
+	rs := ProbeRs{Status: probeStatusOK, Checks: make(map[string]CheckRs, len(checks)+2)}
+	result := func(name string, ok bool, errMsg string) {
+		if ok {
+			rs.Checks[name] = CheckRs{Status: probeStatusOK}
+			return
+		}
+		rs.Status = probeStatusFail
+		rs.Checks[name] = CheckRs{Status: probeStatusFail, ErrMsg: errMsg}
+	}
+
+	result(startupCheck, started, "the start up has not finished")
+	result(shutdownCheck, !shuttingDown, "the service is shutting down")
+	for _, c := range checks {
+		err := c.check()
+		if err != nil {
+			result(c.name, false, err.Error())
+		} else {
+			result(c.name, true, "")
+		}
+	}
+
+	code := http.StatusOK
+	if rs.Status != probeStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	respondProbe(w, code, &rs)
+}
+
+// The failed probes are not logged, the orchestrator polls them
+func respondProbe(w http.ResponseWriter, code int, rs *ProbeRs) {
+	response, _ := json.Marshal(rs)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbes_Readyz(t *testing.T) {
+
+	redisDown := func() error { return errors.New("connection refused") }
+	redisUp := func() error { return nil }
+
+	tests := []struct {
+		name         string
+		started      bool
+		shuttingDown bool
+		check        Check
+		wantCode     int
+		wantFailed   []string
+	}{
+		{
+			name:     "given a started service, when its checks succeed, then it's ready",
+			started:  true,
+			check:    redisUp,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "given a service which is starting, when the readiness is requested, then it's not ready",
+			started:    false,
+			check:      redisUp,
+			wantCode:   http.StatusServiceUnavailable,
+			wantFailed: []string{startupCheck},
+		},
+		{
+			name:         "given a service which is shutting down, when the readiness is requested, then it's not ready",
+			started:      true,
+			shuttingDown: true,
+			check:        redisUp,
+			wantCode:     http.StatusServiceUnavailable,
+			wantFailed:   []string{shutdownCheck},
+		},
+		{
+			name:       "given a started service, when the store can't be reached, then it's not ready",
+			started:    true,
+			check:      redisDown,
+			wantCode:   http.StatusServiceUnavailable,
+			wantFailed: []string{"redis"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProbes()
+			p.AddCheck("redis", tt.check)
+			if tt.started {
+				p.SetStarted()
+			}
+			if tt.shuttingDown {
+				p.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			p.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			var rs ProbeRs
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rs))
+			assert.Equal(t, 3, len(rs.Checks))
+			var failed []string
+			for name, check := range rs.Checks {
+				if check.Status != probeStatusOK {
+					failed = append(failed, name)
+					assert.NotEmpty(t, check.ErrMsg)
+				}
+			}
+			assert.Equal(t, tt.wantFailed, failed)
+		})
+	}
+}
+
+func TestProbes_Livez(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewProbes().Livez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	CreateProjectRq struct {
+		domain.Project
+	}
+	CreateProjectRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	GetProjectsRs struct {
+		Projects []domain.Project
+		ErrMsg   string `json:"error,omitempty"`
+	}
+
+	GetProjectRs struct {
+		Project *domain.Project `json:"project,omitempty"`
+		ErrMsg  string          `json:"error,omitempty"`
+	}
+
+	UpdateProjectRq struct {
+		domain.Project
+	}
+	UpdateProjectRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+
+	RemoveProjectRs struct {
+		UUID   string `json:"uuid,omitempty"`
+		ErrMsg string `json:"error,omitempty"`
+	}
+)
+
+func (c *Controller) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var (
+		rq CreateProjectRq
+		rs CreateProjectRs
+	)
+
+	params := mux.Vars(r)
+	owner := params["uuid"]
+
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
+		return
+	}
+
+	uuid, err := c.customerService.CreateProject(r.Context(), domain.UUID(owner), rq.Name, rq.Quota)
+	if err != nil {
+		rs = CreateProjectRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorAlreadyExist:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = CreateProjectRs{UUID: string(uuid)}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) GetProjects(w http.ResponseWriter, r *http.Request) {
+	var (
+		rs GetProjectsRs
+	)
+
+	params := mux.Vars(r)
+	owner := params["uuid"]
+
+	projects, err := c.customerService.GetProjects(r.Context(), caller(r), domain.UUID(owner))
+	if err != nil {
+		rs = GetProjectsRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = GetProjectsRs{Projects: projects}
+	if len(projects) > 0 {
+		c.respondWithJson(w, http.StatusFound, &rs, r)
+	} else {
+		c.respondWithJson(w, http.StatusOK, &rs, r)
+	}
+}
+
+func (c *Controller) GetProject(w http.ResponseWriter, r *http.Request) {
+	var rs GetProjectRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	project, err := c.customerService.GetProject(r.Context(), caller(r), domain.UUID(uuid))
+	if err != nil {
+		rs = GetProjectRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.AuthErrorForbidden:
+			c.respondWithJson(w, http.StatusForbidden, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = GetProjectRs{Project: project}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	var (
+		rq UpdateProjectRq
+		rs UpdateProjectRs
+	)
+
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		c.respondWithJson(w, http.StatusBadRequest, err.Error(), r)
+		return
+	}
+
+	err = c.customerService.UpdateProject(r.Context(), &rq.Project)
+	if err != nil {
+		rs = UpdateProjectRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorAlreadyExist:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		case app.DomainErrorQuotaExceeded:
+			c.respondWithJson(w, http.StatusUnprocessableEntity, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = UpdateProjectRs{UUID: string(rq.UUID)}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
+
+func (c *Controller) RemoveProject(w http.ResponseWriter, r *http.Request) {
+	var rs RemoveProjectRs
+
+	params := mux.Vars(r)
+	uuid := params["uuid"]
+
+	err := c.customerService.RemoveProject(r.Context(), domain.UUID(uuid))
+	if err != nil {
+		rs = RemoveProjectRs{ErrMsg: err.Error()}
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			c.respondWithJson(w, http.StatusNotFound, &rs, r)
+		case app.DbErrorInUse:
+			c.respondWithJson(w, http.StatusConflict, &rs, r)
+		default:
+			c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		}
+		return
+	}
+
+	rs = RemoveProjectRs{UUID: uuid}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+type (
+	RateLimiter interface {
+		Allow(key string, limit config.RateLimit) (bool, time.Duration, error)
+	}
+
+	RateLimitRs struct {
+		ErrMsg string `json:"error,omitempty"`
+	}
+)
+
+// RateLimit is a middleware which limits the requests of each client to each route. The clients are identified
+// by the API key or the JWT subject they're authenticated with, or by their IP if they aren't. The outcome of the
+// authentication is kept in the request, so it's not done again to authorize it.
+func (a *Api) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeOf(r)
+		limit := a.cfg.RouteRateLimit(route)
+		if limit.Unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var principal *domain.Principal
+		if a.cfg.AuthEnabled {
+			var err error
+			principal, err = a.controller.authenticate(r)
+			r = r.WithContext(context.WithValue(r.Context(), authenticationKey{}, &authentication{principal: principal, err: err}))
+		}
+
+		allowed, retryAfter, err := a.limiter.Allow(route+"|"+clientOf(r, principal), limit)
+		if err != nil {
+			// The service keeps working if the buckets can't be reached
+			requestid.Logger(a.log, r.Context()).WithFields(logrus.Fields{"route": route}).Errorf("rate limit: %s", err.Error())
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			rs := RateLimitRs{ErrMsg: "rate limit exceeded, retry after " + strconv.Itoa(seconds) + "s"}
+			a.controller.respondWithJson(w, http.StatusTooManyRequests, &rs, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeOf returns the method and the path template of the matched route, like "DELETE /hosting/{uuid}"
+func routeOf(r *http.Request) string {
+	return r.Method + " " + pathOf(r)
+}
+
+// pathOf returns the path template of the matched route, so the paths with variables are not told apart
+func pathOf(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// clientOf returns the key of the client, authenticated by the principal if it's not nil. The unauthenticated
+// clients are told apart by their IP, so they can't get a new bucket by sending made-up credentials.
+// The API keys are hashed, so they are not kept in clear.
+func clientOf(r *http.Request, principal *domain.Principal) string {
+	if principal != nil {
+		if key := r.Header.Get(APIKeyHeader); len(key) > 0 {
+			return "apikey:" + domain.HashAPIKey(key)
+		}
+		return "jwt:" + principal.Subject
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
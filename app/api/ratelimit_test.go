@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app/api/apitest"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+func TestApi_RateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		keys       func(tt *tenants) []string
+		wantStatus []int
+	}{
+		{
+			name: "given a client which rotates invalid keys, when the bucket of its IP is empty, then it's limited",
+			keys: func(tt *tenants) []string {
+				return []string{"bogus-1", "bogus-2", "bogus-3"}
+			},
+			wantStatus: []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+		{
+			name: "given a valid key, when it runs out of tokens, then it's limited",
+			keys: func(tt *tenants) []string {
+				return []string{tt.tenantKey, tt.tenantKey, tt.tenantKey}
+			},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "given two valid keys behind the same IP, when one runs out of tokens, then the other isn't limited",
+			keys: func(tt *tenants) []string {
+				return []string{tt.tenantKey, tt.tenantKey, apitest.AdminKey, apitest.AdminKey}
+			},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name: "given a valid key, when the invalid keys of its IP are limited, then it isn't",
+			keys: func(tt *tenants) []string {
+				return []string{"bogus-1", "bogus-2", "bogus-3", tt.tenantKey}
+			},
+			wantStatus: []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusOK},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTenants(t)
+			tt.server.Config.RateLimits = map[string]config.RateLimit{"GET /customer/{uuid}": {Rate: 0.001, Burst: 2}}
+
+			for z, key := range tc.keys(tt) {
+				status := tt.get(t, key, "/customer/"+string(tt.owned), nil)
+				assert.Equal(t, tc.wantStatus[z], status, "request "+strconv.Itoa(z))
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	ReconcileRs struct {
+		Discrepancies []domain.Discrepancy `json:"discrepancies"`
+		ErrMsg        string               `json:"error,omitempty"`
+	}
+)
+
+// Reconcile checks the server resources and the names index against the stored hostings.
+// The discrepancies found are repaired on a POST, and only reported on a GET.
+func (c *Controller) Reconcile(w http.ResponseWriter, r *http.Request) {
+	var (
+		rs ReconcileRs
+	)
+
+	discrepancies, err := c.serverService.Reconcile(r.Context(), r.Method == http.MethodPost)
+	if err != nil {
+		rs = ReconcileRs{Discrepancies: discrepancies, ErrMsg: err.Error()}
+		c.respondWithJson(w, serverErrorStatus(err), &rs, r)
+		return
+	}
+
+	if discrepancies == nil {
+		discrepancies = []domain.Discrepancy{}
+	}
+	rs = ReconcileRs{Discrepancies: discrepancies}
+	c.respondWithJson(w, http.StatusOK, &rs, r)
+}
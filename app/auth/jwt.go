@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const algHS256 = "HS256"
+
+var encoding = base64.RawURLEncoding
+
+type (
+	header struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ,omitempty"`
+	}
+
+	// Claims are the JWT claims understood by the service
+	Claims struct {
+		Subject   string `json:"sub"`
+		Role      string `json:"role"`
+		Tenant    string `json:"tenant,omitempty"`
+		ExpiresAt int64  `json:"exp"`
+		NotBefore int64  `json:"nbf,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+	}
+)
+
+// Sign returns a HS256 JWT with the claims
+func Sign(claims *Claims, secret []byte) (string, error) {
+	h, err := json.Marshal(header{Alg: algHS256, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+	return signingInput + "." + encoding.EncodeToString(sign(signingInput, secret)), nil
+}
+
+// Parse verifies the HS256 signature and the time claims of the token, and returns its claims
+func Parse(token string, secret []byte, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	b, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token header")
+	}
+	var h header
+	err = json.Unmarshal(b, &h)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token header")
+	}
+	if h.Alg != algHS256 {
+		return nil, errors.Errorf("unexpected signing algorithm %q", h.Alg)
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token signature")
+	}
+	if !hmac.Equal(signature, sign(parts[0]+"."+parts[1], secret)) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	b, err = encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token claims")
+	}
+	var claims Claims
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token claims")
+	}
+
+	if claims.ExpiresAt == 0 {
+		return nil, errors.New("the token has not expiration time")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, errors.New("the token has expired")
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return nil, errors.New("the token is not valid yet")
+	}
+	return &claims, nil
+}
+
+func sign(signingInput string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+
+	secret := []byte("secret")
+	now := time.Unix(1550000000, 0)
+
+	sign := func(claims *Claims, secret []byte) string {
+		token, err := Sign(claims, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign(&Claims{Subject: "john", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}, secret)
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:    "given a valid token, when it's parsed, then its claims are returned",
+			token:   valid,
+			wantErr: false,
+		},
+		{
+			name:    "given a token signed with another secret, when it's parsed, then it fails",
+			token:   sign(&Claims{Subject: "john", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}, []byte("other")),
+			wantErr: true,
+		},
+		{
+			name:    "given a token with tampered claims, when it's parsed, then it fails",
+			token:   parts[0] + "." + encoding.EncodeToString([]byte(`{"sub":"john","role":"admin","exp":9999999999}`)) + "." + parts[2],
+			wantErr: true,
+		},
+		{
+			name:    "given a token without signing algorithm, when it's parsed, then it fails",
+			token:   encoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".",
+			wantErr: true,
+		},
+		{
+			name:    "given an expired token, when it's parsed, then it fails",
+			token:   sign(&Claims{Subject: "john", Role: "admin", ExpiresAt: now.Unix()}, secret),
+			wantErr: true,
+		},
+		{
+			name:    "given a token without expiration time, when it's parsed, then it fails",
+			token:   sign(&Claims{Subject: "john", Role: "admin"}, secret),
+			wantErr: true,
+		},
+		{
+			name:    "given a not yet valid token, when it's parsed, then it fails",
+			token:   sign(&Claims{Subject: "john", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix(), NotBefore: now.Add(time.Minute).Unix()}, secret),
+			wantErr: true,
+		},
+		{
+			name:    "given a malformed token, when it's parsed, then it fails",
+			token:   "not-a-token",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.token, secret, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				assert.Equal(t, "john", got.Subject)
+				assert.Equal(t, "admin", got.Role)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package backup defines the snapshot of the stored data, written by the backup and loaded by the restore.
+// A snapshot is a JSON document which carries its format version and the checksum of its data,
+// so a snapshot written by an unknown version, truncated or edited by hand is refused.
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+const (
+	// Version is the format of the snapshots written. The ones of other formats are refused.
+	Version = 1
+
+	checksumPrefix = "sha256:"
+)
+
+type (
+	Snapshot struct {
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+		// Checksum is the SHA-256 hash of the JSON encoding of the data
+		Checksum string `json:"checksum"`
+		Data     Data   `json:"data"`
+	}
+
+	// Data is the stored data. The server status is the one when the snapshot was taken,
+	// as the server is built again from the hostings when the service starts.
+	Data struct {
+		Server    domain.ServerStatus `json:"server"`
+		Hostings  []domain.Hosting    `json:"hostings"`
+		Customers []domain.Customer   `json:"customers"`
+		Projects  []domain.Project    `json:"projects"`
+		APIKeys   []APIKey            `json:"api_keys"`
+		Indexes   Indexes             `json:"indexes"`
+	}
+
+	// Indexes are the indexes of the stored data. The names of the customers and
+	// the projects are not kept, as they're indexed again along with their records.
+	Indexes struct {
+		HostingNames map[string]domain.UUID `json:"hosting_names"`
+	}
+
+	// APIKey is an API key along with its hash, which is not written by the API
+	APIKey struct {
+		domain.APIKey
+		Hash string `json:"hash"`
+	}
+)
+
+// New returns the snapshot of the data, taken at the given time
+func New(data Data, createdAt time.Time) (*Snapshot, error) {
+	sum, err := checksum(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Version: Version, CreatedAt: createdAt.UTC(), Checksum: sum, Data: data}, nil
+}
+
+// Write writes the snapshot as indented JSON
+func (s *Snapshot) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.Wrap(e.Encode(s), "the snapshot could not be written")
+}
+
+// Read reads a snapshot, and checks its version and its checksum
+func Read(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	err := json.NewDecoder(r).Decode(&s)
+	if err != nil {
+		return nil, errors.Wrapf(app.DomainErrorInvalid, "the snapshot could not be read: %s", err.Error())
+	}
+
+	if s.Version != Version {
+		return nil, errors.Wrapf(app.DomainErrorInvalid, "the snapshot version %d is not supported, it must be %d", s.Version, Version)
+	}
+	sum, err := checksum(s.Data)
+	if err != nil {
+		return nil, err
+	}
+	if sum != s.Checksum {
+		return nil, errors.Wrapf(app.DomainErrorInvalid, "the snapshot checksum %s doesn't match its data, whose checksum is %s", s.Checksum, sum)
+	}
+	return &s, nil
+}
+
+// Empty returns true if the data has no records
+func (d *Data) Empty() bool {
+	return len(d.Hostings) == 0 && len(d.Customers) == 0 && len(d.Projects) == 0 && len(d.APIKeys) == 0 && len(d.Indexes.HostingNames) == 0
+}
+
+// checksum hashes the JSON encoding of the data, whose maps are encoded with their keys sorted
+func checksum(data Data) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.Wrap(err, "the snapshot checksum could not be calculated")
+	}
+	sum := sha256.Sum256(b)
+	return checksumPrefix + hex.EncodeToString(sum[:]), nil
+}
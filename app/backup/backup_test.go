@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestRead(t *testing.T) {
+	data := Data{
+		Hostings: []domain.Hosting{{UUID: "u1", Name: "h1", Cores: 1, MemoryMb: 2, DiskMb: 3}},
+		APIKeys:  []APIKey{{APIKey: domain.APIKey{UUID: "k1", Name: "admin", Role: domain.RoleAdmin}, Hash: "hash1"}},
+		Indexes:  Indexes{HostingNames: map[string]domain.UUID{"h1": "u1"}},
+	}
+	snapshot, err := New(data, time.Date(2019, 2, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, snapshot.Write(&b))
+	written := b.String()
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{
+			name: "given a written snapshot, when it's read, then it's the same one",
+			json: written,
+		},
+		{
+			name:    "given a snapshot of another version, when it's read, then it's refused",
+			json:    strings.Replace(written, `"version": 1`, `"version": 2`, 1),
+			wantErr: "the snapshot version 2 is not supported, it must be 1",
+		},
+		{
+			name:    "given an edited snapshot, when it's read, then its checksum doesn't match",
+			json:    strings.Replace(written, `"cores": 1`, `"cores": 2`, 1),
+			wantErr: "doesn't match its data",
+		},
+		{
+			name:    "given a truncated snapshot, when it's read, then it's refused",
+			json:    written[:len(written)/2],
+			wantErr: "the snapshot could not be read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.json))
+			if len(tt.wantErr) > 0 {
+				assert.Equal(t, app.DomainErrorInvalid, errors.Cause(err))
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, snapshot, got)
+			assert.Equal(t, "hash1", got.Data.APIKeys[0].Hash)
+		})
+	}
+}
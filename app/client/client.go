@@ -0,0 +1,233 @@
+// Package client is a typed client of the hosting API, for the Go services which call cdmon2.
+// The requests and responses are the ones of the api package, and the failures are told apart
+// by the errors of this package, which are the cause of the returned errors:
+//
+//	_, err := c.CreateHosting(ctx, rq)
+//	if errors.Cause(err) == client.ErrInsufficientCapacity {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+// DefaultTimeout is the timeout of the requests made with the default HTTP client
+const DefaultTimeout = 30 * time.Second
+
+var (
+	ErrInvalid              = errors.New("invalid request")
+	ErrUnauthenticated      = errors.New("unauthenticated")
+	ErrForbidden            = errors.New("forbidden")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrUnavailable          = errors.New("service unavailable")
+	ErrServer               = errors.New("server error")
+)
+
+type (
+	// Credentials authenticate the client, by an API key or by a JWT. None are needed
+	// if the authentication is disabled in the service.
+	Credentials struct {
+		APIKey string
+		Token  string
+	}
+
+	Client struct {
+		baseURL     string
+		credentials Credentials
+		// HTTPClient makes the requests. It can be replaced before the client is used.
+		HTTPClient *http.Client
+	}
+
+	// errorRs is the error body of every response
+	errorRs struct {
+		ErrMsg string `json:"error"`
+	}
+)
+
+// New returns a client of the service at baseURL, like "http://localhost:8080"
+func New(baseURL string, credentials Credentials) *Client {
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		credentials: credentials,
+		HTTPClient: &http.Client{
+			Timeout: DefaultTimeout,
+			// GET /hosting answers 302 Found when there are hostings, which is not a redirection
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// CreateHosting creates the hosting. ErrInsufficientCapacity is returned if it doesn't fit in the server resources,
+// or in the quota of its project or customer, and ErrConflict if there is another hosting with the same name.
+func (c *Client) CreateHosting(ctx context.Context, rq api.CreateHostingRq) (*api.CreateHostingRs, error) {
+	var rs api.CreateHostingRs
+	err := c.do(ctx, http.MethodPost, "/hosting", &rq, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// GetHostings returns the hostings the caller can access
+func (c *Client) GetHostings(ctx context.Context) (*api.GetHostingsRs, error) {
+	var rs api.GetHostingsRs
+	err := c.do(ctx, http.MethodGet, "/hosting", nil, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// UpdateHosting updates the hosting, as CreateHosting creates it. ErrNotFound is returned if it doesn't exist.
+func (c *Client) UpdateHosting(ctx context.Context, rq api.UpdateHostingRq) (*api.UpdateHostingRs, error) {
+	var rs api.UpdateHostingRs
+	err := c.do(ctx, http.MethodPut, "/hosting", &rq, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// RemoveHosting removes the hosting. ErrNotFound is returned if it doesn't exist.
+func (c *Client) RemoveHosting(ctx context.Context, uuid domain.UUID) (*api.RemoveHostingRs, error) {
+	var rs api.RemoveHostingRs
+	err := c.do(ctx, http.MethodDelete, "/hosting/"+url.PathEscape(string(uuid)), nil, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// Health returns the running time of the service and the status of the server resources
+func (c *Client) Health(ctx context.Context) (*api.HealthRs, error) {
+	var rs api.HealthRs
+	err := c.do(ctx, http.MethodGet, "/health", nil, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// do sends the request, and decodes the response into rs. The failed responses are returned as errors.
+func (c *Client) do(ctx context.Context, method, path string, rq, rs interface{}) error {
+	var body io.Reader
+	if rq != nil {
+		b, err := json.Marshal(rq)
+		if err != nil {
+			return errors.Wrapf(err, "encoding the %s %s request", method, path)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if rq != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authenticate(req)
+
+	// The request ID of the caller, if any, is passed on, so the request can be followed across the services
+	if id := requestid.FromContext(ctx); len(id) > 0 {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading the %s %s response", method, path)
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusFound {
+		return responseError(res, b, method, path)
+	}
+	err = json.Unmarshal(b, rs)
+	if err != nil {
+		return errors.Wrapf(err, "decoding the %s %s response", method, path)
+	}
+	return nil
+}
+
+func (c *Client) authenticate(req *http.Request) {
+	if len(c.credentials.APIKey) > 0 {
+		req.Header.Set(api.APIKeyHeader, c.credentials.APIKey)
+	}
+	if len(c.credentials.Token) > 0 {
+		req.Header.Set(api.AuthorizationHeader, "Bearer "+c.credentials.Token)
+	}
+}
+
+// responseError maps the status of a failed response to the errors of the package. The message of
+// the service is kept, along with the request ID, so the failure can be found in the service logs.
+func responseError(res *http.Response, body []byte, method, path string) error {
+	msg := errorMessage(body)
+	if id := res.Header.Get(requestid.Header); len(id) > 0 {
+		msg = msg + " (request " + id + ")"
+	}
+	msg = method + " " + path + ": " + msg
+
+	switch res.StatusCode {
+	case http.StatusBadRequest:
+		return errors.Wrap(ErrInvalid, msg)
+	case http.StatusUnauthorized:
+		return errors.Wrap(ErrUnauthenticated, msg)
+	case http.StatusForbidden:
+		return errors.Wrap(ErrForbidden, msg)
+	case http.StatusNotFound:
+		return errors.Wrap(ErrNotFound, msg)
+	case http.StatusConflict:
+		return errors.Wrap(ErrConflict, msg)
+	case http.StatusUnprocessableEntity:
+		return errors.Wrap(ErrInsufficientCapacity, msg)
+	case http.StatusTooManyRequests:
+		return errors.Wrap(ErrRateLimited, msg)
+	case http.StatusServiceUnavailable:
+		return errors.Wrap(ErrUnavailable, msg)
+	}
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errors.Wrap(ErrServer, msg)
+	}
+	return errors.Errorf("%s: unexpected status %d", msg, res.StatusCode)
+}
+
+// errorMessage returns the message of an error body. Most of them carry an error field,
+// but the bad JSON requests are answered with the message alone.
+func errorMessage(body []byte) string {
+	var rs errorRs
+	if json.Unmarshal(body, &rs) == nil && len(rs.ErrMsg) > 0 {
+		return rs.ErrMsg
+	}
+	var msg string
+	if json.Unmarshal(body, &msg) == nil && len(msg) > 0 {
+		return msg
+	}
+	return strings.TrimSpace(string(body))
+}
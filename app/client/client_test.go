@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/api/apitest"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+func hostingRq(name string, cores, memorymb, diskmb int) api.CreateHostingRq {
+	return api.CreateHostingRq{Hosting: domain.Hosting{Name: name, Cores: cores, MemoryMb: memorymb, DiskMb: diskmb}}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	s := apitest.New(t, 10, 10, 10)
+	c := New(s.URL, Credentials{APIKey: apitest.AdminKey})
+
+	// Create
+	created, err := c.CreateHosting(ctx, hostingRq("h1", 2, 4, 6))
+	require.NoError(t, err)
+	require.NotEmpty(t, created.UUID)
+
+	_, err = c.CreateHosting(ctx, hostingRq("h1", 1, 1, 1))
+	assert.Equal(t, ErrConflict, errors.Cause(err))
+
+	_, err = c.CreateHosting(ctx, hostingRq("h2", 9, 1, 1))
+	assert.Equal(t, ErrInsufficientCapacity, errors.Cause(err))
+
+	// Read
+	got, err := c.GetHostings(ctx)
+	require.NoError(t, err)
+	require.Len(t, got.Hostings, 1)
+	assert.Equal(t, domain.Hosting{UUID: domain.UUID(created.UUID), Name: "h1", Cores: 2, MemoryMb: 4, DiskMb: 6}, got.Hostings[0])
+
+	health, err := c.Health(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 8, health.ServerStatus.AvailableCores)
+
+	// Update
+	updated, err := c.UpdateHosting(ctx, api.UpdateHostingRq{Hosting: domain.Hosting{UUID: domain.UUID(created.UUID), Name: "h1", Cores: 5, MemoryMb: 4, DiskMb: 6}})
+	require.NoError(t, err)
+	assert.Equal(t, created.UUID, updated.UUID)
+
+	_, err = c.UpdateHosting(ctx, api.UpdateHostingRq{Hosting: domain.Hosting{UUID: "missing", Name: "h3", Cores: 1, MemoryMb: 1, DiskMb: 1}})
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+
+	health, err = c.Health(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, health.ServerStatus.AvailableCores)
+
+	// Remove
+	removed, err := c.RemoveHosting(ctx, domain.UUID(created.UUID))
+	require.NoError(t, err)
+	assert.Equal(t, created.UUID, removed.UUID)
+
+	_, err = c.RemoveHosting(ctx, domain.UUID(created.UUID))
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+
+	got, err = c.GetHostings(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got.Hostings)
+}
+
+func TestClient_Unauthenticated(t *testing.T) {
+	s := apitest.New(t, 10, 10, 10)
+
+	for _, credentials := range []Credentials{{}, {APIKey: "wrong"}} {
+		_, err := New(s.URL, credentials).GetHostings(context.Background())
+		assert.Equal(t, ErrUnauthenticated, errors.Cause(err))
+	}
+
+	// The health is not authenticated
+	_, err := New(s.URL, Credentials{}).Health(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "given a bad request answered with a bare message, when it's returned, then the message is kept",
+			status:  http.StatusBadRequest,
+			body:    `"invalid character 'x' looking for beginning of value"`,
+			wantErr: ErrInvalid,
+			wantMsg: "GET /health: invalid character 'x' looking for beginning of value (request rq1): invalid request",
+		},
+		{
+			name:    "given a forbidden request, when it's returned, then it's told apart",
+			status:  http.StatusForbidden,
+			body:    `{"error":"the hosting h1 is not accessible"}`,
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "given a rate limited request, when it's returned, then it's told apart",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":"rate limit exceeded, retry after 1s"}`,
+			wantErr: ErrRateLimited,
+		},
+		{
+			name:    "given an unavailable store, when it's returned, then it can be retried",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"error":"store unavailable"}`,
+			wantErr: ErrUnavailable,
+		},
+		{
+			name:    "given a server failure, when it's returned, then it's a server error",
+			status:  http.StatusInternalServerError,
+			body:    `{"error":"boom"}`,
+			wantErr: ErrServer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(requestid.Header, "rq1")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer s.Close()
+
+			_, err := New(s.URL, Credentials{}).Health(context.Background())
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if len(tt.wantMsg) > 0 {
+				assert.Equal(t, tt.wantMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestClient_RequestID(t *testing.T) {
+	var got string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestid.Header)
+		w.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	_, err := New(s.URL, Credentials{}).Health(requestid.NewContext(context.Background(), "rq1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "rq1", got)
+}
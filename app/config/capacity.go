@@ -0,0 +1,72 @@
+package config
+
+import "fmt"
+
+// Capacity is the part of the configuration which can be reloaded at runtime:
+// the server resources and the minimal size of a hosting
+type Capacity struct {
+	TotalNumberOfCores    int `json:"total_number_of_cores"`
+	TotalSizeOfMemoryMb   int `json:"total_size_of_memory"`
+	TotalSizeOfDiskMb     int `json:"total_size_of_disk"`
+	MinimalNumberOfCores  int `json:"minimal_number_of_cores"`
+	MinimalSizeOfMemoryMb int `json:"minimal_size_of_memory"`
+	MinimalSizeOfDiskMb   int `json:"minimal_size_of_disk"`
+}
+
+// Capacity returns the capacity settings of the configuration
+func (c *Config) Capacity() Capacity {
+	return Capacity{
+		TotalNumberOfCores:    c.TotalNumberOfCores,
+		TotalSizeOfMemoryMb:   c.TotalSizeOfMemoryMb,
+		TotalSizeOfDiskMb:     c.TotalSizeOfDiskMb,
+		MinimalNumberOfCores:  c.MinimalNumberOfCores,
+		MinimalSizeOfMemoryMb: c.MinimalSizeOfMemoryMb,
+		MinimalSizeOfDiskMb:   c.MinimalSizeOfDiskMb,
+	}
+}
+
+// SetCapacity replaces the capacity settings. The configuration is shared, so the caller
+// must keep the readers of these settings out while they are replaced.
+func (c *Config) SetCapacity(capacity Capacity) {
+	c.TotalNumberOfCores = capacity.TotalNumberOfCores
+	c.TotalSizeOfMemoryMb = capacity.TotalSizeOfMemoryMb
+	c.TotalSizeOfDiskMb = capacity.TotalSizeOfDiskMb
+	c.MinimalNumberOfCores = capacity.MinimalNumberOfCores
+	c.MinimalSizeOfMemoryMb = capacity.MinimalSizeOfMemoryMb
+	c.MinimalSizeOfDiskMb = capacity.MinimalSizeOfDiskMb
+}
+
+// Diff describes the settings changed from c to next, one by line, as "CDMON2_TOTAL_NUMBER_OF_CORES: 8 -> 16"
+func (c Capacity) Diff(next Capacity) []string {
+	var diff []string
+	for _, s := range []struct {
+		name      string
+		old, next int
+	}{
+		{TotalNumberOfCores, c.TotalNumberOfCores, next.TotalNumberOfCores},
+		{TotalSizeOfMemoryMb, c.TotalSizeOfMemoryMb, next.TotalSizeOfMemoryMb},
+		{TotalSizeOfDiskMb, c.TotalSizeOfDiskMb, next.TotalSizeOfDiskMb},
+		{MinimalNumberOfCores, c.MinimalNumberOfCores, next.MinimalNumberOfCores},
+		{MinimalSizeOfMemoryMb, c.MinimalSizeOfMemoryMb, next.MinimalSizeOfMemoryMb},
+		{MinimalSizeOfDiskMb, c.MinimalSizeOfDiskMb, next.MinimalSizeOfDiskMb},
+	} {
+		if s.old != s.next {
+			diff = append(diff, fmt.Sprintf("%s: %d -> %d", s.name, s.old, s.next))
+		}
+	}
+	return diff
+}
+
+// LoadCapacity loads the configuration again, as at start up, and returns its capacity settings.
+// The whole configuration must be valid, so a broken change is not half applied.
+func LoadCapacity() (Capacity, error) {
+	c := &Config{}
+	err := c.Load()
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		return Capacity{}, err
+	}
+	return c.Capacity(), nil
+}
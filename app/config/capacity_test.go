@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapacity_Diff(t *testing.T) {
+	c := validConfig()
+	old := c.Capacity()
+
+	next := old
+	next.TotalSizeOfMemoryMb = 200
+	next.MinimalSizeOfDiskMb = 2
+
+	assert.Empty(t, old.Diff(old))
+	assert.Equal(t, []string{
+		TotalSizeOfMemoryMb + ": 100 -> 200",
+		MinimalSizeOfDiskMb + ": 1 -> 2",
+	}, old.Diff(next))
+
+	c.SetCapacity(next)
+	assert.Equal(t, next, c.Capacity())
+}
+
+func TestLoadCapacity(t *testing.T) {
+	t.Setenv(ConfigFile, writeConfigFile(t, `{
+		"total_number_of_cores": 8,
+		"total_size_of_memory": 2048,
+		"total_size_of_disk": 4096,
+		"minimal_size_of_memory": 64,
+		"redis_addr": "localhost:6379"
+	}`))
+
+	capacity, err := LoadCapacity()
+	assert.NoError(t, err)
+	assert.Equal(t, Capacity{
+		TotalNumberOfCores:    8,
+		TotalSizeOfMemoryMb:   2048,
+		TotalSizeOfDiskMb:     4096,
+		MinimalNumberOfCores:  1,
+		MinimalSizeOfMemoryMb: 64,
+		MinimalSizeOfDiskMb:   1,
+	}, capacity)
+
+	// A change which makes the configuration invalid is refused as a whole
+	t.Setenv(MinimalNumberOfCores, "16")
+	_, err = LoadCapacity()
+	assert.Error(t, err)
+}
@@ -2,63 +2,169 @@ package config
 
 import (
 	"os"
-	"strconv"
+	"time"
 )
 
 const (
+	ConfigFile            = "CDMON2_CONFIG_FILE"
 	APIPort               = "CDMON2_API_PORT"
 	TotalNumberOfCores    = "CDMON2_TOTAL_NUMBER_OF_CORES"
 	TotalSizeOfMemoryMb   = "CDMON2_TOTAL_SIZE_OF_MEMORY"
 	TotalSizeOfDiskMb     = "CDMON2_TOTAL_SIZE_OF_DISK"
 	MinimalNumberOfCores  = "CDMON2_MINIMAL_NUMBER_OF_CORES"
 	MinimalSizeOfMemoryMb = "CDMON2_MINIMAL_SIZE_OF_MEMORY"
-	MininalSizeOfDiskMb   = "CDMON2_MININAML_SIZE_OF_DISK"
+	MinimalSizeOfDiskMb   = "CDMON2_MINIMAL_SIZE_OF_DISK"
 	RedisAddr             = "CDMON2_REDIS_ADDR"
+	AuthEnabled           = "CDMON2_AUTH_ENABLED"
+	JWTSecret             = "CDMON2_JWT_SECRET"
+	BootstrapAPIKey       = "CDMON2_BOOTSTRAP_API_KEY"
+	RateLimitDefault      = "CDMON2_RATE_LIMIT"
+	RateLimitRoutes       = "CDMON2_RATE_LIMIT_ROUTES"
+	RateLimitStore        = "CDMON2_RATE_LIMIT_STORE"
+	HTTPReadTimeout       = "CDMON2_HTTP_READ_TIMEOUT"
+	HTTPWriteTimeout      = "CDMON2_HTTP_WRITE_TIMEOUT"
+	HTTPIdleTimeout       = "CDMON2_HTTP_IDLE_TIMEOUT"
+	ShutdownTimeout       = "CDMON2_SHUTDOWN_TIMEOUT"
+	LogLevel              = "CDMON2_LOG_LEVEL"
+	LogFormat             = "CDMON2_LOG_FORMAT"
+	StoreTimeout          = "CDMON2_STORE_TIMEOUT"
+	StoreOpTimeouts       = "CDMON2_STORE_OP_TIMEOUTS"
+	RedisConnectAttempts  = "CDMON2_REDIS_CONNECT_ATTEMPTS"
+	RedisConnectBackoff   = "CDMON2_REDIS_CONNECT_BACKOFF"
+	StoreRetries          = "CDMON2_STORE_RETRIES"
+	StoreRetryBackoff     = "CDMON2_STORE_RETRY_BACKOFF"
+	StoreBreakerFailures  = "CDMON2_STORE_BREAKER_FAILURES"
+	StoreBreakerCooldown  = "CDMON2_STORE_BREAKER_COOLDOWN"
+	StoreCodec            = "CDMON2_STORE_CODEC"
+	StoreBackend          = "CDMON2_STORE_BACKEND"
+	StoreFile             = "CDMON2_STORE_FILE"
+	StoreFsync            = "CDMON2_STORE_FSYNC"
+	StoreFsyncInterval    = "CDMON2_STORE_FSYNC_INTERVAL"
+	StoreCompactInterval  = "CDMON2_STORE_COMPACT_INTERVAL"
+	RepairOnStart         = "CDMON2_REPAIR_ON_START"
+	FlushOnStart          = "CDMON2_FLUSH_ON_START"
+
+	// Deprecated: MininalSizeOfDiskMb is the misspelled name MinimalSizeOfDiskMb was first given.
+	// It's still read, from the environment or the config file, when MinimalSizeOfDiskMb is not set.
+	MininalSizeOfDiskMb = "CDMON2_MININAML_SIZE_OF_DISK"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
 )
 
 type (
 	EmptyRecordFunc func() interface{}
 
 	Config struct {
-		APIPort               string
-		TotalNumberOfCores    int
-		TotalSizeOfMemoryMb   int
-		TotalSizeOfDiskMb     int
-		MinimalNumberOfCores  int
-		MinimalSizeOfMemoryMb int
-		MinimalSizeOfDiskMb   int
-		RedisAddr             string
+		APIPort                 string
+		TotalNumberOfCores      int
+		TotalSizeOfMemoryMb     int
+		TotalSizeOfDiskMb       int
+		MinimalNumberOfCores    int
+		MinimalSizeOfMemoryMb   int
+		MinimalSizeOfDiskMb     int
+		RedisAddr               string
+		RedisPassword           Secret
+		RedisDB                 int
+		RedisTLS                bool
+		RedisTLSCAFile          string // CA to verify Redis, the system ones if it's empty
+		RedisTLSCertFile        string // Client certificate, if Redis requires it
+		RedisTLSKeyFile         string
+		RedisTLSServerName      string // Name verified in the Redis certificate, the host of RedisAddr if it's empty
+		RedisTLSSkipVerify      bool
+		RedisPoolSize           int
+		RedisMinIdleConns       int
+		RedisPoolTimeout        time.Duration
+		RedisIdleTimeout        time.Duration
+		RedisMaxConnAge         time.Duration
+		RedisDialTimeout        time.Duration
+		RedisSentinelAddrs      []string
+		RedisSentinelMasterName string
+		AuthEnabled             bool
+		JWTSecret               Secret               // JWT authentication is disabled if it's empty
+		BootstrapAPIKey         Secret               // Admin API key to be registered at start up, if it's not empty
+		RateLimit               RateLimit            // Limit of the routes without their own one
+		RateLimits              map[string]RateLimit // Limits by route, like "POST /hosting"
+		RateLimitStore          string               // Where the buckets are kept, memory or redis
+		HTTPReadTimeout         time.Duration
+		HTTPWriteTimeout        time.Duration
+		HTTPIdleTimeout         time.Duration
+		ShutdownTimeout         time.Duration // Deadline to drain the in-flight requests
+		LogLevel                string
+		LogFormat               string                   // json or text
+		StoreTimeout            time.Duration            // Timeout of the store operations without their own one
+		StoreOpTimeouts         map[string]time.Duration // Timeouts by store operation, like "get_all"
+		RedisConnectAttempts    int                      // Attempts to connect to Redis at start up
+		RedisConnectBackoff     time.Duration            // Base delay between the connection attempts
+		StoreRetries            int                      // Retries of the store operations failed by Redis
+		StoreRetryBackoff       time.Duration            // Base delay between the retries
+		StoreBreakerFailures    int                      // Consecutive failures which open the circuit breaker, zero to disable it
+		StoreBreakerCooldown    time.Duration            // Time the circuit breaker stays open
+		StoreCodec              string                   // Encoding of the records written, gob or json
+		StoreBackend            string                   // Where the data is kept, redis or file
+		StoreFile               string                   // Log file of the file backend
+		StoreFsync              string                   // When the log is flushed to disk, always, interval or never
+		StoreFsyncInterval      time.Duration            // Time between the flushes of the interval policy
+		StoreCompactInterval    time.Duration            // Time between the log compactions, zero to disable them
+		RepairOnStart           bool                     // Whether the discrepancies found at start up are repaired, or only reported
+		FlushOnStart            bool                     // Whether all the stored data is removed at start up
 	}
 )
 
-func (c *Config) Load() (err error) {
-	c.APIPort = getEnv(APIPort)
-	c.TotalNumberOfCores, err = strconv.Atoi(getEnv(TotalNumberOfCores))
-	if err == nil {
-		c.TotalSizeOfMemoryMb, err = strconv.Atoi(getEnv(TotalSizeOfMemoryMb))
-	}
-	if err == nil {
-		c.TotalSizeOfDiskMb, err = strconv.Atoi(getEnv(TotalSizeOfDiskMb))
-	}
-	if err == nil {
-		c.MinimalNumberOfCores, err = strconv.Atoi(getEnv(MinimalNumberOfCores))
-	}
-	if err == nil {
-		c.MinimalSizeOfMemoryMb, err = strconv.Atoi(getEnv(MinimalSizeOfMemoryMb))
+// Load loads the configuration from the environment variables. If CDMON2_CONFIG_FILE is set, the settings
+// missing in the environment are taken from that file, and the ones missing in both take their defaults.
+// All the problems found are returned at once, as Problems.
+func (c *Config) Load() error {
+	l, err := newLoader(os.Getenv(ConfigFile))
+	if err != nil {
+		return err
 	}
-	if err == nil {
-		c.MinimalSizeOfDiskMb, err = strconv.Atoi(getEnv(MininalSizeOfDiskMb))
-	}
-	if err == nil {
-		c.RedisAddr = getEnv(RedisAddr)
-	}
-	return
+
+	c.APIPort = l.string(APIPort, "8080")
+	c.TotalNumberOfCores = l.int(TotalNumberOfCores, "")
+	c.TotalSizeOfMemoryMb = l.int(TotalSizeOfMemoryMb, "")
+	c.TotalSizeOfDiskMb = l.int(TotalSizeOfDiskMb, "")
+	c.MinimalNumberOfCores = l.int(MinimalNumberOfCores, "1")
+	c.MinimalSizeOfMemoryMb = l.int(MinimalSizeOfMemoryMb, "1")
+	c.MinimalSizeOfDiskMb = l.int(MinimalSizeOfDiskMb, "1")
+	c.RedisAddr = l.string(RedisAddr, "")
+	c.loadRedis(l)
+	c.AuthEnabled = l.bool(AuthEnabled, "true")
+	c.JWTSecret = Secret(l.string(JWTSecret, ""))
+	c.BootstrapAPIKey = Secret(l.string(BootstrapAPIKey, ""))
+	c.RateLimit = l.rateLimit(RateLimitDefault, "")
+	c.RateLimits = l.rateLimits(RateLimitRoutes, "")
+	c.RateLimitStore = l.string(RateLimitStore, RateLimitStoreMemory)
+	c.HTTPReadTimeout = l.duration(HTTPReadTimeout, "10s")
+	c.HTTPWriteTimeout = l.duration(HTTPWriteTimeout, "30s")
+	c.HTTPIdleTimeout = l.duration(HTTPIdleTimeout, "2m")
+	c.ShutdownTimeout = l.duration(ShutdownTimeout, "30s")
+	c.LogLevel = l.string(LogLevel, "info")
+	c.LogFormat = l.string(LogFormat, LogFormatJSON)
+	c.StoreTimeout = l.duration(StoreTimeout, "2s")
+	c.StoreOpTimeouts = l.storeTimeouts(StoreOpTimeouts, "")
+	c.RedisConnectAttempts = l.int(RedisConnectAttempts, "10")
+	c.RedisConnectBackoff = l.duration(RedisConnectBackoff, "500ms")
+	c.StoreRetries = l.int(StoreRetries, "2")
+	c.StoreRetryBackoff = l.duration(StoreRetryBackoff, "50ms")
+	c.StoreBreakerFailures = l.int(StoreBreakerFailures, "5")
+	c.StoreBreakerCooldown = l.duration(StoreBreakerCooldown, "10s")
+	c.StoreCodec = l.string(StoreCodec, StoreCodecGob)
+	c.StoreBackend = l.string(StoreBackend, StoreBackendRedis)
+	c.StoreFile = l.string(StoreFile, "cdmon2.store")
+	c.StoreFsync = l.string(StoreFsync, StoreFsyncAlways)
+	c.StoreFsyncInterval = l.duration(StoreFsyncInterval, "1s")
+	c.StoreCompactInterval = l.duration(StoreCompactInterval, "10m")
+	c.RepairOnStart = l.bool(RepairOnStart, "false")
+	c.FlushOnStart = l.bool(FlushOnStart, "false")
+
+	return l.err()
 }
 
-func getEnv(env string) (value string) {
-	value = os.Getenv(env)
-	if len(value) == 0 {
-		panic("environment variable " + env + " does not exist")
+// RouteRateLimit returns the limit of the route, like "POST /hosting"
+func (c *Config) RouteRateLimit(route string) RateLimit {
+	if limit, ok := c.RateLimits[route]; ok {
+		return limit
 	}
-	return
+	return c.RateLimit
 }
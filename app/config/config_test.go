@@ -0,0 +1,295 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "cdmon2.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		check   func(t *testing.T, c *Config)
+		wantErr []string
+	}{
+		{
+			name: "given only the required variables, when the config is loaded, then the rest take their defaults",
+			env: map[string]string{
+				TotalNumberOfCores:  "100",
+				TotalSizeOfMemoryMb: "200",
+				TotalSizeOfDiskMb:   "300",
+				RedisAddr:           "localhost:6379",
+			},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, "8080", c.APIPort)
+				assert.Equal(t, 100, c.TotalNumberOfCores)
+				assert.Equal(t, 1, c.MinimalNumberOfCores)
+				assert.Equal(t, 30*time.Second, c.ShutdownTimeout)
+				assert.Equal(t, LogFormatJSON, c.LogFormat)
+				assert.True(t, c.AuthEnabled)
+			},
+		},
+		{
+			name: "given a config file, when the config is loaded, then the environment overrides it",
+			file: `{
+				"total_number_of_cores": 10,
+				"total_size_of_memory": "20",
+				"total_size_of_disk": 30,
+				"redis_addr": "redis:6379",
+				"auth_enabled": false,
+				"redis_sentinel_addrs": ["s1:26379", "s2:26379"],
+				"redis_sentinel_master": "mymaster",
+				"rate_limit_routes": {"POST /hosting": "1:5"},
+				"store_op_timeouts": {"get_all": "5s"}
+			}`,
+			env: map[string]string{
+				TotalNumberOfCores: "50",
+			},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, 50, c.TotalNumberOfCores)
+				assert.Equal(t, 20, c.TotalSizeOfMemoryMb)
+				assert.Equal(t, 30, c.TotalSizeOfDiskMb)
+				assert.Equal(t, "redis:6379", c.RedisAddr)
+				assert.False(t, c.AuthEnabled)
+				assert.Equal(t, []string{"s1:26379", "s2:26379"}, c.RedisSentinelAddrs)
+				assert.Equal(t, RateLimit{Rate: 1, Burst: 5}, c.RouteRateLimit("POST /hosting"))
+				assert.Equal(t, 5*time.Second, c.StoreOpTimeout(StoreOpGetAll))
+			},
+		},
+		{
+			name: "given several wrong settings, when the config is loaded, then all of them are reported",
+			file: `{"total_size_of_memory": "lots", "total_size_of_disk": 30, "unknown_setting": 1}`,
+			env: map[string]string{
+				HTTPReadTimeout: "10",
+			},
+			wantErr: []string{
+				`unknown setting "unknown_setting" in the config file`,
+				TotalNumberOfCores + " is required",
+				TotalSizeOfMemoryMb + `: "lots" is not an integer`,
+				HTTPReadTimeout + `: "10" is not a duration, like 500ms or 2s`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ConfigFile, "")
+			if len(tt.file) > 0 {
+				t.Setenv(ConfigFile, writeConfigFile(t, tt.file))
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			c := &Config{}
+			err := c.Load()
+			if tt.wantErr != nil {
+				assert.Equal(t, Problems(tt.wantErr), err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestConfig_LoadMinimalSizeOfDisk(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want int
+	}{
+		{
+			name: "given the setting in the config file, when the config is loaded, then it's read",
+			file: `{"minimal_size_of_disk": 2}`,
+			want: 2,
+		},
+		{
+			name: "given the setting in the environment, when the config is loaded, then it's read",
+			env:  map[string]string{MinimalSizeOfDiskMb: "3"},
+			want: 3,
+		},
+		{
+			name: "given the deprecated key in the config file, when the config is loaded, then it's still read",
+			file: `{"mininaml_size_of_disk": 4}`,
+			want: 4,
+		},
+		{
+			name: "given the deprecated variable, when the config is loaded, then it's still read",
+			env:  map[string]string{MininalSizeOfDiskMb: "5"},
+			want: 5,
+		},
+		{
+			name: "given both names, when the config is loaded, then the right one wins",
+			file: `{"minimal_size_of_disk": 6}`,
+			env:  map[string]string{MininalSizeOfDiskMb: "7"},
+			want: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ConfigFile, "")
+			if len(tt.file) > 0 {
+				t.Setenv(ConfigFile, writeConfigFile(t, tt.file))
+			}
+			t.Setenv(TotalNumberOfCores, "100")
+			t.Setenv(TotalSizeOfMemoryMb, "200")
+			t.Setenv(TotalSizeOfDiskMb, "300")
+			t.Setenv(RedisAddr, "localhost:6379")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			c := &Config{}
+			assert.NoError(t, c.Load())
+			assert.Equal(t, tt.want, c.MinimalSizeOfDiskMb)
+		})
+	}
+}
+
+func TestConfig_LoadWrongFile(t *testing.T) {
+	t.Setenv(ConfigFile, writeConfigFile(t, `{"api_port": `))
+	assert.Error(t, (&Config{}).Load())
+
+	t.Setenv(ConfigFile, filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, (&Config{}).Load())
+}
+
+func validConfig() *Config {
+	return &Config{
+		APIPort:               "8080",
+		TotalNumberOfCores:    100,
+		TotalSizeOfMemoryMb:   100,
+		TotalSizeOfDiskMb:     100,
+		MinimalNumberOfCores:  1,
+		MinimalSizeOfMemoryMb: 1,
+		MinimalSizeOfDiskMb:   1,
+		RedisAddr:             "localhost:6379",
+		RedisDialTimeout:      time.Second,
+		RateLimitStore:        RateLimitStoreMemory,
+		LogLevel:              "info",
+		LogFormat:             LogFormatJSON,
+		HTTPReadTimeout:       time.Second,
+		HTTPWriteTimeout:      time.Second,
+		HTTPIdleTimeout:       time.Second,
+		ShutdownTimeout:       time.Second,
+		StoreTimeout:          time.Second,
+		RedisConnectAttempts:  1,
+		StoreBreakerFailures:  5,
+		StoreBreakerCooldown:  time.Second,
+		StoreCodec:            StoreCodecGob,
+		StoreBackend:          StoreBackendRedis,
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []string
+	}{
+		{
+			name:   "given a valid config, when it's validated, then there are no problems",
+			modify: func(c *Config) {},
+		},
+		{
+			name:    "given a negative minimum, when it's validated, then it's reported",
+			modify:  func(c *Config) { c.MinimalNumberOfCores = -1 },
+			wantErr: []string{MinimalNumberOfCores + " must be positive, it's -1"},
+		},
+		{
+			name:    "given a minimum above its total, when it's validated, then it's reported",
+			modify:  func(c *Config) { c.MinimalSizeOfDiskMb = 200 },
+			wantErr: []string{MinimalSizeOfDiskMb + " can't be above " + TotalSizeOfDiskMb + ", it's 200 and the total is 100"},
+		},
+		{
+			name:    "given an unknown store codec, when it's validated, then it's reported",
+			modify:  func(c *Config) { c.StoreCodec = "xml" },
+			wantErr: []string{StoreCodec + `: unknown store codec "xml", it must be gob or json`},
+		},
+		{
+			name: "given the file backend, when it's validated, then Redis is not required",
+			modify: func(c *Config) {
+				c.StoreBackend, c.StoreFile, c.StoreFsync = StoreBackendFile, "cdmon2.store", StoreFsyncAlways
+				c.RedisAddr = ""
+			},
+		},
+		{
+			name: "given the file backend with wrong settings, when it's validated, then they're reported",
+			modify: func(c *Config) {
+				c.StoreBackend, c.StoreFile, c.StoreFsync = StoreBackendFile, "", "sometimes"
+				c.RateLimitStore = RateLimitStoreRedis
+			},
+			wantErr: []string{
+				StoreFile + " is required along with the file store backend",
+				StoreFsync + `: unknown fsync policy "sometimes", it must be always, interval or never`,
+				RateLimitStore + " can't be redis along with the file store backend",
+			},
+		},
+		{
+			name:    "given an unknown store backend, when it's validated, then it's reported",
+			modify:  func(c *Config) { c.StoreBackend = "etcd" },
+			wantErr: []string{StoreBackend + `: unknown store backend "etcd", it must be redis or file`},
+		},
+		{
+			name: "given several problems, when it's validated, then all of them are reported",
+			modify: func(c *Config) {
+				c.APIPort = "80800"
+				c.TotalNumberOfCores = 0
+				c.LogFormat = "xml"
+			},
+			wantErr: []string{
+				APIPort + `: "80800" is not a valid port, it must be between 1 and 65535`,
+				TotalNumberOfCores + " must be positive, it's 0",
+				LogFormat + `: unknown log format "xml", it must be json or text`,
+			},
+		},
+		{
+			name: "given redis without address, when it's validated, then it's reported unless it's reached through sentinel",
+			modify: func(c *Config) {
+				c.RedisAddr = ""
+				c.RedisSentinelAddrs = []string{"s1:26379"}
+			},
+			wantErr: []string{RedisSentinelMasterName + " is required along with " + RedisSentinelAddrs},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, Problems(tt.wantErr), err)
+		})
+	}
+}
+
+func TestConfig_DumpMasksSecrets(t *testing.T) {
+	c := validConfig()
+	c.RedisPassword, c.JWTSecret, c.BootstrapAPIKey = "redis-s3cr3t", "jwt-s3cr3t", "key-s3cr3t"
+
+	// The configuration is dumped to the log at start up
+	dump := spew.Sdump(c)
+	assert.False(t, strings.Contains(dump, "s3cr3t"), dump)
+	assert.Contains(t, dump, "JWTSecret: (config.Secret) (len=10) ********")
+}
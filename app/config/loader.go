@@ -0,0 +1,245 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const envPrefix = "CDMON2_"
+
+type (
+	// Problems are the problems found in the configuration. They're reported all at once,
+	// so they can be fixed all at once too.
+	Problems []string
+
+	// loader reads the settings from the environment, or from the config file if they're not in the environment,
+	// and collects the problems found on the way
+	loader struct {
+		file     map[string]string
+		problems Problems
+	}
+)
+
+func (p Problems) Error() string {
+	return "invalid configuration: " + strings.Join(p, "; ")
+}
+
+func (p *Problems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// FileKey returns the key of a setting in the config file. It's the name of its variable, lower case and without the prefix,
+// like "total_number_of_cores" for CDMON2_TOTAL_NUMBER_OF_CORES.
+func FileKey(env string) string {
+	return strings.ToLower(strings.TrimPrefix(env, envPrefix))
+}
+
+// newLoader returns a loader over the config file, if any. The file is a JSON object with the settings by their key.
+// Their values can be given as strings, numbers or booleans, and the list settings as arrays, or objects for the ones
+// by route or operation, like {"rate_limit_routes": {"POST /hosting": "1:5"}}.
+func newLoader(path string) (*loader, error) {
+	l := &loader{file: make(map[string]string)}
+	if len(path) == 0 {
+		return l, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the config file")
+	}
+	var settings map[string]interface{}
+	err = json.Unmarshal(b, &settings)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing the config file %s", path)
+	}
+
+	for key, value := range settings {
+		if !knownKeys[key] {
+			l.problems.add("unknown setting %q in the config file", key)
+			continue
+		}
+		s, err := fileValue(value)
+		if err != nil {
+			l.problems.add("%s in the config file: %s", key, err.Error())
+			continue
+		}
+		l.file[key] = s
+	}
+	sort.Strings(l.problems)
+	return l, nil
+}
+
+// fileValue turns a value of the config file into its environment variable form
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, k := range keys {
+			s, err := fileValue(v[k])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, k+"="+s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", errors.Errorf("unsupported value %v", value)
+	}
+}
+
+// lookup returns the setting from the environment, or from the config file.
+// If it's not set, its deprecated name is looked up the same way.
+func (l *loader) lookup(env string) (string, bool) {
+	value, ok := l.lookupName(env)
+	if alias, deprecated := aliases[env]; !ok && deprecated {
+		return l.lookupName(alias)
+	}
+	return value, ok
+}
+
+func (l *loader) lookupName(env string) (string, bool) {
+	if value, ok := os.LookupEnv(env); ok {
+		return value, true
+	}
+	value, ok := l.file[FileKey(env)]
+	return value, ok
+}
+
+// string returns the setting, or its default if it's not set
+func (l *loader) string(env, def string) string {
+	value, ok := l.lookup(env)
+	if !ok {
+		return def
+	}
+	return value
+}
+
+// required returns the setting, and reports it if it's not set. An empty default makes a setting required.
+func (l *loader) required(env, def string) (string, bool) {
+	value := l.string(env, def)
+	if len(value) == 0 {
+		l.problems.add("%s is required", env)
+		return "", false
+	}
+	return value, true
+}
+
+func (l *loader) int(env, def string) int {
+	value, ok := l.required(env, def)
+	if !ok {
+		return 0
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		l.problems.add("%s: %q is not an integer", env, value)
+	}
+	return i
+}
+
+func (l *loader) bool(env, def string) bool {
+	value, ok := l.required(env, def)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.problems.add("%s: %q is not a boolean", env, value)
+	}
+	return b
+}
+
+func (l *loader) duration(env, def string) time.Duration {
+	value, ok := l.required(env, def)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.problems.add("%s: %q is not a duration, like 500ms or 2s", env, value)
+	}
+	return d
+}
+
+func (l *loader) rateLimit(env, def string) RateLimit {
+	limit, err := ParseRateLimit(l.string(env, def))
+	l.check(env, err)
+	return limit
+}
+
+func (l *loader) rateLimits(env, def string) map[string]RateLimit {
+	limits, err := ParseRateLimits(l.string(env, def))
+	l.check(env, err)
+	return limits
+}
+
+func (l *loader) storeTimeouts(env, def string) map[string]time.Duration {
+	timeouts, err := ParseStoreTimeouts(l.string(env, def))
+	l.check(env, err)
+	return timeouts
+}
+
+// check reports the error of the setting, if any
+func (l *loader) check(env string, err error) {
+	if err != nil {
+		l.problems.add("%s: %s", env, err.Error())
+	}
+}
+
+// err returns the problems found, if any
+func (l *loader) err() error {
+	if len(l.problems) > 0 {
+		return l.problems
+	}
+	return nil
+}
+
+// aliases are the deprecated names of the settings which were renamed
+var aliases = map[string]string{
+	MinimalSizeOfDiskMb: MininalSizeOfDiskMb,
+}
+
+// knownKeys are the keys of the config file, to catch the misspelled ones
+var knownKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	for _, env := range []string{
+		APIPort, TotalNumberOfCores, TotalSizeOfMemoryMb, TotalSizeOfDiskMb, MinimalNumberOfCores, MinimalSizeOfMemoryMb, MinimalSizeOfDiskMb, MininalSizeOfDiskMb,
+		RedisAddr, AuthEnabled, JWTSecret, BootstrapAPIKey, RateLimitDefault, RateLimitRoutes, RateLimitStore,
+		HTTPReadTimeout, HTTPWriteTimeout, HTTPIdleTimeout, ShutdownTimeout, LogLevel, LogFormat,
+		StoreTimeout, StoreOpTimeouts, RedisConnectAttempts, RedisConnectBackoff, StoreRetries, StoreRetryBackoff, StoreBreakerFailures, StoreBreakerCooldown, StoreCodec,
+		StoreBackend, StoreFile, StoreFsync, StoreFsyncInterval, StoreCompactInterval, RepairOnStart, FlushOnStart,
+		RedisPassword, RedisPasswordFile, RedisDB, RedisTLS, RedisTLSCAFile, RedisTLSCertFile, RedisTLSKeyFile, RedisTLSServerName, RedisTLSSkipVerify,
+		RedisPoolSize, RedisMinIdleConns, RedisPoolTimeout, RedisIdleTimeout, RedisMaxConnAge, RedisDialTimeout, RedisSentinelAddrs, RedisSentinelMasterName,
+	} {
+		keys[FileKey(env)] = true
+	}
+	return keys
+}()
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// RateLimit is a token bucket which is refilled with Rate tokens per second, up to Burst tokens.
+// A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// ParseRateLimit parses a limit with the format "rate[:burst]". The burst defaults to the rate, rounded up.
+func ParseRateLimit(s string) (RateLimit, error) {
+	var limit RateLimit
+	if len(s) == 0 {
+		return limit, nil
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	rate, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || rate < 0 {
+		return limit, errors.Errorf("invalid rate limit %q, the rate must be a non negative number", s)
+	}
+	limit.Rate = rate
+	limit.Burst = int(math.Ceil(rate))
+
+	if len(parts) == 2 {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit.Burst < 1 {
+			return limit, errors.Errorf("invalid rate limit %q, the burst must be a positive integer", s)
+		}
+	}
+	return limit, nil
+}
+
+// ParseRateLimits parses a list of limits by route, with the format "METHOD /path=rate[:burst],...".
+// The path is the route template, like "/hosting/{uuid}".
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid route rate limit %q, it must be like \"POST /hosting=1:5\"", item)
+		}
+		limit, err := ParseRateLimit(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.Join(strings.Fields(parts[0]), " ")] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]RateLimit
+		wantErr bool
+	}{
+		{
+			name: "given an empty list, when it's parsed, then there are not limits",
+			s:    "",
+			want: map[string]RateLimit{},
+		},
+		{
+			name: "given a list of limits, when it's parsed, then the limits by route are returned",
+			s:    "POST /hosting=0.5:5, GET  /hosting=10",
+			want: map[string]RateLimit{
+				"POST /hosting": RateLimit{Rate: 0.5, Burst: 5},
+				"GET /hosting":  RateLimit{Rate: 10, Burst: 10},
+			},
+		},
+		{
+			name:    "given a limit without route, when it's parsed, then it fails",
+			s:       "10:5",
+			wantErr: true,
+		},
+		{
+			name:    "given a limit with a wrong burst, when it's parsed, then it fails",
+			s:       "POST /hosting=1:0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRateLimits(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRateLimits() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
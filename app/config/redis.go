@@ -0,0 +1,115 @@
+package config
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RedisPassword           = "CDMON2_REDIS_PASSWORD"
+	RedisPasswordFile       = "CDMON2_REDIS_PASSWORD_FILE"
+	RedisDB                 = "CDMON2_REDIS_DB"
+	RedisTLS                = "CDMON2_REDIS_TLS"
+	RedisTLSCAFile          = "CDMON2_REDIS_TLS_CA_FILE"
+	RedisTLSCertFile        = "CDMON2_REDIS_TLS_CERT_FILE"
+	RedisTLSKeyFile         = "CDMON2_REDIS_TLS_KEY_FILE"
+	RedisTLSServerName      = "CDMON2_REDIS_TLS_SERVER_NAME"
+	RedisTLSSkipVerify      = "CDMON2_REDIS_TLS_INSECURE_SKIP_VERIFY"
+	RedisPoolSize           = "CDMON2_REDIS_POOL_SIZE"
+	RedisMinIdleConns       = "CDMON2_REDIS_MIN_IDLE_CONNS"
+	RedisPoolTimeout        = "CDMON2_REDIS_POOL_TIMEOUT"
+	RedisIdleTimeout        = "CDMON2_REDIS_IDLE_TIMEOUT"
+	RedisMaxConnAge         = "CDMON2_REDIS_MAX_CONN_AGE"
+	RedisDialTimeout        = "CDMON2_REDIS_DIAL_TIMEOUT"
+	RedisSentinelAddrs      = "CDMON2_REDIS_SENTINEL_ADDRS"
+	RedisSentinelMasterName = "CDMON2_REDIS_SENTINEL_MASTER"
+)
+
+// Secret is a configuration value which must not be disclosed, as when the configuration is logged
+type Secret string
+
+func (s Secret) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "********"
+}
+
+// loadRedis loads the Redis connection settings. The zero pool settings keep the Redis client defaults.
+func (c *Config) loadRedis(l *loader) {
+	password, err := loadSecret(l.string(RedisPassword, ""), l.string(RedisPasswordFile, ""))
+	l.check(RedisPasswordFile, err)
+	c.RedisPassword = password
+	c.RedisDB = l.int(RedisDB, "0")
+	c.RedisTLS = l.bool(RedisTLS, "false")
+	c.RedisTLSCAFile = l.string(RedisTLSCAFile, "")
+	c.RedisTLSCertFile = l.string(RedisTLSCertFile, "")
+	c.RedisTLSKeyFile = l.string(RedisTLSKeyFile, "")
+	c.RedisTLSServerName = l.string(RedisTLSServerName, "")
+	c.RedisTLSSkipVerify = l.bool(RedisTLSSkipVerify, "false")
+	c.RedisPoolSize = l.int(RedisPoolSize, "0")
+	c.RedisMinIdleConns = l.int(RedisMinIdleConns, "0")
+	c.RedisPoolTimeout = l.duration(RedisPoolTimeout, "0s")
+	c.RedisIdleTimeout = l.duration(RedisIdleTimeout, "0s")
+	c.RedisMaxConnAge = l.duration(RedisMaxConnAge, "0s")
+	c.RedisDialTimeout = l.duration(RedisDialTimeout, "5s")
+	c.RedisSentinelAddrs = splitList(l.string(RedisSentinelAddrs, ""))
+	c.RedisSentinelMasterName = l.string(RedisSentinelMasterName, "")
+}
+
+// validateRedis checks the Redis connection settings
+func (c *Config) validateRedis(p *Problems) {
+	if len(c.RedisAddr) == 0 && !c.RedisSentinel() {
+		p.add("%s is required, unless Redis is reached through Sentinel", RedisAddr)
+	}
+	if c.RedisSentinel() && len(c.RedisSentinelMasterName) == 0 {
+		p.add("%s is required along with %s", RedisSentinelMasterName, RedisSentinelAddrs)
+	}
+	if (len(c.RedisTLSCertFile) == 0) != (len(c.RedisTLSKeyFile) == 0) {
+		p.add("%s and %s must be set together", RedisTLSCertFile, RedisTLSKeyFile)
+	}
+	if c.RedisDB < 0 {
+		p.add("%s can't be negative", RedisDB)
+	}
+	if c.RedisPoolSize < 0 || c.RedisMinIdleConns < 0 {
+		p.add("%s and %s can't be negative", RedisPoolSize, RedisMinIdleConns)
+	}
+	if c.RedisDialTimeout <= 0 {
+		p.add("%s must be positive", RedisDialTimeout)
+	}
+}
+
+// loadSecret returns the secret given as is, or read from a file, as the secrets mounted by Docker or Kubernetes.
+// The trailing new line of the file is not part of the secret.
+func loadSecret(value, file string) (Secret, error) {
+	if len(file) == 0 {
+		return Secret(value), nil
+	}
+	if len(value) > 0 {
+		return "", errors.New("a secret can't be given both as is and from a file")
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrap(err, "reading the secret file")
+	}
+	return Secret(strings.TrimRight(string(b), "\r\n")), nil
+}
+
+// splitList splits a comma separated list, skipping the empty items
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// RedisSentinel tells if Redis is reached through Sentinel, instead of at RedisAddr
+func (c *Config) RedisSentinel() bool {
+	return len(c.RedisSentinelAddrs) > 0
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSecret(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(file, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		want    Secret
+		wantErr bool
+	}{
+		{
+			name:  "given a secret, when it's loaded, then it's taken as is",
+			value: "s3cr3t",
+			want:  "s3cr3t",
+		},
+		{
+			name: "given a secret file, when it's loaded, then it's read without its trailing new line",
+			file: file,
+			want: "s3cr3t",
+		},
+		{
+			name:    "given a secret and a secret file, when it's loaded, then it fails",
+			value:   "other",
+			file:    file,
+			wantErr: true,
+		},
+		{
+			name:    "given a missing secret file, when it's loaded, then it fails",
+			file:    filepath.Join(t.TempDir(), "missing"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadSecret(tt.value, tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadSecret() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecret_String(t *testing.T) {
+	assert.Equal(t, "********", Secret("s3cr3t").String())
+	assert.Equal(t, "", Secret("").String())
+}
+
+func TestConfig_LoadRedis(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(t *testing.T, c *Config)
+		wantErr bool
+	}{
+		{
+			name: "given no redis settings, when they're loaded, then the defaults are taken",
+			env:  map[string]string{},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, 0, c.RedisDB)
+				assert.False(t, c.RedisTLS)
+				assert.False(t, c.RedisSentinel())
+			},
+		},
+		{
+			name: "given the sentinel settings, when they're loaded, then redis is reached through sentinel",
+			env: map[string]string{
+				RedisSentinelAddrs:      "s1:26379, s2:26379,",
+				RedisSentinelMasterName: "mymaster",
+				RedisDB:                 "3",
+			},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, []string{"s1:26379", "s2:26379"}, c.RedisSentinelAddrs)
+				assert.True(t, c.RedisSentinel())
+				assert.Equal(t, 3, c.RedisDB)
+			},
+		},
+		{
+			name:    "given sentinel addresses without master, when they're loaded, then it fails",
+			env:     map[string]string{RedisSentinelAddrs: "s1:26379"},
+			wantErr: true,
+		},
+		{
+			name:    "given a client certificate without key, when it's loaded, then it fails",
+			env:     map[string]string{RedisTLSCertFile: "cert.pem"},
+			wantErr: true,
+		},
+		{
+			name:    "given a wrong db, when it's loaded, then it fails",
+			env:     map[string]string{RedisDB: "one"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			l, err := newLoader("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Config{RedisAddr: "localhost:6379"}
+			c.loadRedis(l)
+			problems := l.problems
+			c.validateRedis(&problems)
+			if (len(problems) > 0) != tt.wantErr {
+				t.Errorf("Config.loadRedis() problems = %v, wantErr %v", problems, tt.wantErr)
+				return
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Store operations, to set their timeouts
+const (
+	StoreOpGet    = "get"
+	StoreOpGetAll = "get_all"
+	StoreOpSet    = "set"
+	StoreOpRemove = "remove"
+	StoreOpKeys   = "keys"
+	StoreOpLoad   = "load"
+)
+
+// Store codecs, the encodings of the stored records
+const (
+	StoreCodecGob  = "gob"
+	StoreCodecJSON = "json"
+)
+
+// Store backends, where the data is kept
+const (
+	StoreBackendRedis = "redis"
+	StoreBackendFile  = "file"
+)
+
+// Flush policies of the file backend log
+const (
+	StoreFsyncAlways     = "always"
+	StoreFsyncOnInterval = "interval"
+	StoreFsyncNever      = "never"
+)
+
+var storeOps = map[string]bool{
+	StoreOpGet:    true,
+	StoreOpGetAll: true,
+	StoreOpSet:    true,
+	StoreOpRemove: true,
+	StoreOpKeys:   true,
+	StoreOpLoad:   true,
+}
+
+// ParseStoreTimeouts parses a list of timeouts by store operation, with the format "op=duration,...", like "get_all=5s"
+func ParseStoreTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid store timeout %q, it must be like \"get_all=5s\"", item)
+		}
+		op := strings.TrimSpace(parts[0])
+		if !storeOps[op] {
+			return nil, errors.Errorf("unknown store operation %q", op)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || timeout <= 0 {
+			return nil, errors.Errorf("invalid store timeout %q, it must be a positive duration", item)
+		}
+		timeouts[op] = timeout
+	}
+	return timeouts, nil
+}
+
+// StoreOpTimeout returns the timeout of the store operation
+func (c *Config) StoreOpTimeout(op string) time.Duration {
+	if timeout, ok := c.StoreOpTimeouts[op]; ok {
+		return timeout
+	}
+	return c.StoreTimeout
+}
+
+// StoreMaxTimeout returns the longest timeout of the store operations, so the socket timeouts don't cut them short
+func (c *Config) StoreMaxTimeout() time.Duration {
+	max := c.StoreTimeout
+	for _, timeout := range c.StoreOpTimeouts {
+		if timeout > max {
+			max = timeout
+		}
+	}
+	return max
+}
+
+// validateStore checks the settings of the store backend. The Redis ones are only checked when Redis is used.
+func (c *Config) validateStore(p *Problems) {
+	switch c.StoreBackend {
+	case StoreBackendRedis:
+		c.validateRedis(p)
+	case StoreBackendFile:
+		if len(c.StoreFile) == 0 {
+			p.add("%s is required along with the %s store backend", StoreFile, StoreBackendFile)
+		}
+		switch c.StoreFsync {
+		case StoreFsyncAlways, StoreFsyncNever:
+		case StoreFsyncOnInterval:
+			if c.StoreFsyncInterval <= 0 {
+				p.add("%s must be positive", StoreFsyncInterval)
+			}
+		default:
+			p.add("%s: unknown fsync policy %q, it must be %s, %s or %s", StoreFsync, c.StoreFsync, StoreFsyncAlways, StoreFsyncOnInterval, StoreFsyncNever)
+		}
+		if c.StoreCompactInterval < 0 {
+			p.add("%s can't be negative", StoreCompactInterval)
+		}
+	default:
+		p.add("%s: unknown store backend %q, it must be %s or %s", StoreBackend, c.StoreBackend, StoreBackendRedis, StoreBackendFile)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+var logLevels = map[string]bool{
+	"panic": true, "fatal": true, "error": true, "warn": true, "warning": true, "info": true, "debug": true, "trace": true,
+}
+
+// Validate checks the loaded configuration. All the problems found are returned at once, as Problems.
+func (c *Config) Validate() error {
+	var p Problems
+
+	port, err := strconv.Atoi(c.APIPort)
+	if err != nil || port < 1 || port > 65535 {
+		p.add("%s: %q is not a valid port, it must be between 1 and 65535", APIPort, c.APIPort)
+	}
+
+	for _, r := range []struct {
+		total, minimal       int
+		totalEnv, minimalEnv string
+	}{
+		{c.TotalNumberOfCores, c.MinimalNumberOfCores, TotalNumberOfCores, MinimalNumberOfCores},
+		{c.TotalSizeOfMemoryMb, c.MinimalSizeOfMemoryMb, TotalSizeOfMemoryMb, MinimalSizeOfMemoryMb},
+		{c.TotalSizeOfDiskMb, c.MinimalSizeOfDiskMb, TotalSizeOfDiskMb, MinimalSizeOfDiskMb},
+	} {
+		if r.total <= 0 {
+			p.add("%s must be positive, it's %d", r.totalEnv, r.total)
+		}
+		if r.minimal <= 0 {
+			p.add("%s must be positive, it's %d", r.minimalEnv, r.minimal)
+		}
+		if r.total > 0 && r.minimal > r.total {
+			p.add("%s can't be above %s, it's %d and the total is %d", r.minimalEnv, r.totalEnv, r.minimal, r.total)
+		}
+	}
+
+	c.validateStore(&p)
+
+	if c.RateLimitStore != RateLimitStoreMemory && c.RateLimitStore != RateLimitStoreRedis {
+		p.add("%s: unknown rate limit store %q, it must be %s or %s", RateLimitStore, c.RateLimitStore, RateLimitStoreMemory, RateLimitStoreRedis)
+	}
+	if c.RateLimitStore == RateLimitStoreRedis && c.StoreBackend == StoreBackendFile {
+		p.add("%s can't be %s along with the %s store backend", RateLimitStore, RateLimitStoreRedis, StoreBackendFile)
+	}
+	if c.StoreCodec != StoreCodecGob && c.StoreCodec != StoreCodecJSON {
+		p.add("%s: unknown store codec %q, it must be %s or %s", StoreCodec, c.StoreCodec, StoreCodecGob, StoreCodecJSON)
+	}
+	if !logLevels[c.LogLevel] {
+		p.add("%s: unknown log level %q", LogLevel, c.LogLevel)
+	}
+	if c.LogFormat != LogFormatJSON && c.LogFormat != LogFormatText {
+		p.add("%s: unknown log format %q, it must be %s or %s", LogFormat, c.LogFormat, LogFormatJSON, LogFormatText)
+	}
+
+	for _, t := range []struct {
+		timeout time.Duration
+		env     string
+	}{
+		{c.HTTPReadTimeout, HTTPReadTimeout},
+		{c.HTTPWriteTimeout, HTTPWriteTimeout},
+		{c.HTTPIdleTimeout, HTTPIdleTimeout},
+		{c.ShutdownTimeout, ShutdownTimeout},
+		{c.StoreTimeout, StoreTimeout},
+	} {
+		if t.timeout <= 0 {
+			p.add("%s must be positive", t.env)
+		}
+	}
+
+	if c.RedisConnectAttempts < 1 {
+		p.add("%s must be at least 1, it's %d", RedisConnectAttempts, c.RedisConnectAttempts)
+	}
+	if c.StoreRetries < 0 {
+		p.add("%s can't be negative, it's %d", StoreRetries, c.StoreRetries)
+	}
+	if c.RedisConnectBackoff < 0 || c.StoreRetryBackoff < 0 {
+		p.add("%s and %s can't be negative", RedisConnectBackoff, StoreRetryBackoff)
+	}
+	if c.StoreBreakerFailures < 0 {
+		p.add("%s can't be negative, it's %d", StoreBreakerFailures, c.StoreBreakerFailures)
+	}
+	if c.StoreBreakerFailures > 0 && c.StoreBreakerCooldown <= 0 {
+		p.add("%s must be positive while the breaker is enabled", StoreBreakerCooldown)
+	}
+
+	if len(p) > 0 {
+		return p
+	}
+	return nil
+}
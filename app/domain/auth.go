@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	gouuid "github.com/satori/go.uuid"
+)
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleReadOnly Role = "read-only"
+
+	apiKeySize = 32
+)
+
+// Each role can do whatever the roles with a lower rank can
+var rolesRank = map[Role]int{
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+type (
+	Role string
+
+	// Principal is the authenticated caller of an operation.
+	// Unless it's an admin, it can only act on the hostings of its tenant, the customer it belongs to.
+	Principal struct {
+		Subject string `json:"subject"`
+		Role    Role   `json:"role"`
+		Tenant  UUID   `json:"tenant,omitempty"`
+	}
+
+	// APIKey is a static credential. Only the hash of the key is persisted.
+	APIKey struct {
+		UUID   UUID   `json:"uuid"`
+		Name   string `json:"name"`
+		Hash   string `json:"-"`
+		Role   Role   `json:"role"`
+		Tenant UUID   `json:"tenant,omitempty"`
+	}
+)
+
+func (r Role) Validate() error {
+	if _, ok := rolesRank[r]; !ok {
+		return errors.Errorf("unknown role %q", string(r))
+	}
+	return nil
+}
+
+// Allows returns true if the role is granted with the required one
+func (r Role) Allows(required Role) bool {
+	rank, ok := rolesRank[r]
+	return ok && rank >= rolesRank[required]
+}
+
+// Validate checks the principal has a known role and, unless it's an admin, a tenant
+func (p *Principal) Validate() error {
+	err := p.Role.Validate()
+	if err != nil {
+		return err
+	}
+
+	if p.Role != RoleAdmin && len(p.Tenant) == 0 {
+		return errors.Errorf("Tenant can't be empty for the role %q", string(p.Role))
+	}
+	return nil
+}
+
+// CanAccessAll returns true if the principal can act across tenants.
+// A nil principal stands for an internal caller, which is not restricted.
+func (p *Principal) CanAccessAll() bool {
+	return p == nil || p.Role == RoleAdmin
+}
+
+// CanAccess returns true if the principal can act on the resources owned by the tenant.
+// A principal without tenant can't, so the resources without owner are left to the admins.
+func (p *Principal) CanAccess(tenant UUID) bool {
+	return p.CanAccessAll() || (len(p.Tenant) > 0 && p.Tenant == tenant)
+}
+
+// NewAPIKey returns a new API key, and its plain value. The plain value can't be recovered later.
+func NewAPIKey(name string, role Role, tenant UUID) (*APIKey, string, error) {
+	b := make([]byte, apiKeySize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, "", err
+	}
+	key := hex.EncodeToString(b)
+
+	uuid := gouuid.NewV1()
+	return &APIKey{
+		UUID:   UUID(uuid.String()),
+		Name:   name,
+		Hash:   HashAPIKey(key),
+		Role:   role,
+		Tenant: tenant,
+	}, key, nil
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKey) Validate() error {
+
+	err := k.UUID.Validate()
+	if err != nil {
+		return err
+	}
+
+	if len(k.Name) == 0 {
+		return errors.New("Name can't be empty")
+	}
+
+	if len(k.Hash) == 0 {
+		return errors.New("Hash can't be empty")
+	}
+
+	return k.Principal().Validate()
+}
+
+func (k *APIKey) Principal() *Principal {
+	return &Principal{Subject: k.Name, Role: k.Role, Tenant: k.Tenant}
+}
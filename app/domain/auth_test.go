@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Allows(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     Role
+		required Role
+		want     bool
+	}{
+		{name: "given an admin, when an admin operation is called, then it's allowed", role: RoleAdmin, required: RoleAdmin, want: true},
+		{name: "given an admin, when a read-only operation is called, then it's allowed", role: RoleAdmin, required: RoleReadOnly, want: true},
+		{name: "given an operator, when an operator operation is called, then it's allowed", role: RoleOperator, required: RoleOperator, want: true},
+		{name: "given an operator, when an admin operation is called, then it's denied", role: RoleOperator, required: RoleAdmin, want: false},
+		{name: "given a read-only, when an operator operation is called, then it's denied", role: RoleReadOnly, required: RoleOperator, want: false},
+		{name: "given an unknown role, when a read-only operation is called, then it's denied", role: Role("guest"), required: RoleReadOnly, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.Allows(tt.required))
+		})
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	apiKey, key, err := NewAPIKey("k1", RoleOperator, UUID("customer1"))
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "k1", Role: RoleOperator, Tenant: UUID("customer1")}, apiKey.Principal())
+	assert.NoError(t, apiKey.Validate())
+	assert.NotEqual(t, key, apiKey.Hash)
+	assert.Equal(t, HashAPIKey(key), apiKey.Hash)
+}
+
+func TestPrincipal_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		wantErr   bool
+	}{
+		{name: "given an admin without tenant, when it's validated, then it's valid", principal: &Principal{Role: RoleAdmin}, wantErr: false},
+		{name: "given an operator with tenant, when it's validated, then it's valid", principal: &Principal{Role: RoleOperator, Tenant: "customer1"}, wantErr: false},
+		{name: "given an operator without tenant, when it's validated, then it's invalid", principal: &Principal{Role: RoleOperator}, wantErr: true},
+		{name: "given a read-only without tenant, when it's validated, then it's invalid", principal: &Principal{Role: RoleReadOnly}, wantErr: true},
+		{name: "given an unknown role, when it's validated, then it's invalid", principal: &Principal{Role: Role("guest"), Tenant: "customer1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.principal.Validate() != nil)
+		})
+	}
+}
+
+func TestPrincipal_CanAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		tenant    UUID
+		want      bool
+	}{
+		{name: "given an internal caller, when it accesses a tenant, then it's allowed", principal: nil, tenant: "customer1", want: true},
+		{name: "given an admin, when it accesses another tenant, then it's allowed", principal: &Principal{Role: RoleAdmin, Tenant: "customer2"}, tenant: "customer1", want: true},
+		{name: "given an operator, when it accesses its tenant, then it's allowed", principal: &Principal{Role: RoleOperator, Tenant: "customer1"}, tenant: "customer1", want: true},
+		{name: "given an operator, when it accesses another tenant, then it's denied", principal: &Principal{Role: RoleOperator, Tenant: "customer2"}, tenant: "customer1", want: false},
+		{name: "given a read-only without tenant, when it accesses a tenant, then it's denied", principal: &Principal{Role: RoleReadOnly}, tenant: "customer1", want: false},
+		{name: "given a read-only without tenant, when it accesses the hostings without owner, then it's denied", principal: &Principal{Role: RoleReadOnly}, tenant: "", want: false},
+		{name: "given an admin without tenant, when it accesses the hostings without owner, then it's allowed", principal: &Principal{Role: RoleAdmin}, tenant: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.principal.CanAccess(tt.tenant))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	gouuid "github.com/satori/go.uuid"
+)
+
+type (
+	// Customer is the organization which owns hostings. Its quota limits
+	// the resources taken among all its hostings, whatever project they belong to.
+	Customer struct {
+		UUID  UUID   `json:"uuid"`
+		Name  string `json:"name"`
+		Quota Quota  `json:"quota"`
+	}
+)
+
+func NewCustomer(name string, quota Quota) (*Customer, error) {
+
+	uuid := gouuid.NewV1()
+	return &Customer{
+		UUID:  UUID(uuid.String()),
+		Name:  name,
+		Quota: quota,
+	}, nil
+}
+
+func (c *Customer) Validate() error {
+
+	err := c.UUID.Validate()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Name) == 0 {
+		return errors.New("Name can't be empty")
+	}
+
+	err = c.Quota.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Customer) ScopeName() string {
+	return "customer " + string(c.UUID)
+}
+
+func (c *Customer) Contains(hosting *Hosting) bool {
+	return hosting.Owner == c.UUID
+}
+
+func (c *Customer) Limit() Quota {
+	return c.Quota
+}
+
+// CheckQuota verifies that the customer quota is not exceeded when the hosting is allocated.
+// If the hosting is being updated, old is its current version, and its resources are not counted twice.
+func (c *Customer) CheckQuota(hosting, old *Hosting, hostings []Hosting) error {
+	return CheckQuotas(hosting, old, hostings, c)
+}
+
+// CheckUsage verifies that the resources already taken by the customer hostings fit in its quota
+func (c *Customer) CheckUsage(hostings []Hosting) error {
+	return CheckUsage(c, hostings)
+}
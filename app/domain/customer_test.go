@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+func populateCustomer(cores, memorymb, diskmb int) *Customer {
+	return &Customer{UUID: UUID("customer1"), Name: "c1", Quota: Quota{Cores: cores, MemoryMb: memorymb, DiskMb: diskmb}}
+}
+
+func populateOwnedHostings() []Hosting {
+	return []Hosting{
+		Hosting{UUID: UUID("uuid1"), Owner: UUID("customer1"), Name: "h1", Cores: 2, MemoryMb: 2, DiskMb: 2},
+		Hosting{UUID: UUID("uuid2"), Owner: UUID("customer1"), Name: "h2", Cores: 2, MemoryMb: 2, DiskMb: 2},
+		Hosting{UUID: UUID("uuid3"), Owner: UUID("customer2"), Name: "h3", Cores: 50, MemoryMb: 50, DiskMb: 50},
+	}
+}
+
+func populateCustomerHosting(cores, memorymb, diskmb int) *Hosting {
+	hosting := populateHosting(cores, memorymb, diskmb)
+	hosting.Owner = UUID("customer1")
+	return hosting
+}
+
+func TestCustomer_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer *Customer
+		wantErr  bool
+	}{
+		{
+			name:     "given a valid customer, when it's validated, then all works fine",
+			customer: populateCustomer(1, 1, 1),
+			wantErr:  false,
+		},
+		{
+			name:     "given a customer without a name, when it's validated, then it fails",
+			customer: &Customer{UUID: UUID("customer1")},
+			wantErr:  true,
+		},
+		{
+			name:     "given a customer with a negative quota, when it's validated, then it fails",
+			customer: populateCustomer(-1, 1, 1),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.customer.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Customer.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomer_CheckQuota(t *testing.T) {
+
+	hostings := populateOwnedHostings()
+
+	type args struct {
+		hosting *Hosting
+		old     *Hosting
+	}
+	tests := []struct {
+		name     string
+		customer *Customer
+		args     args
+		wantErr  bool
+	}{
+		{
+			name:     "given a customer, when a hosting fits in its quota, then all works fine",
+			customer: populateCustomer(5, 5, 5),
+			args:     args{hosting: populateCustomerHosting(1, 1, 1)},
+			wantErr:  false,
+		},
+		{
+			name:     "given a customer without limits, when a hosting is added, then all works fine",
+			customer: populateCustomer(0, 0, 0),
+			args:     args{hosting: populateCustomerHosting(100, 100, 100)},
+			wantErr:  false,
+		},
+		{
+			name:     "given a customer, when a hosting exceeds its cores quota, then it fails",
+			customer: populateCustomer(4, 5, 5),
+			args:     args{hosting: populateCustomerHosting(1, 1, 1)},
+			wantErr:  true,
+		},
+		{
+			name:     "given a customer, when a hosting exceeds its memory quota, then it fails",
+			customer: populateCustomer(5, 4, 5),
+			args:     args{hosting: populateCustomerHosting(1, 1, 1)},
+			wantErr:  true,
+		},
+		{
+			name:     "given a customer, when a hosting exceeds its disk quota, then it fails",
+			customer: populateCustomer(5, 5, 4),
+			args:     args{hosting: populateCustomerHosting(1, 1, 1)},
+			wantErr:  true,
+		},
+		{
+			name:     "given a customer, when one of its hostings is resized inside its quota, then all works fine",
+			customer: populateCustomer(5, 5, 5),
+			args:     args{hosting: &Hosting{UUID: UUID("uuid1"), Owner: UUID("customer1"), Cores: 3, MemoryMb: 3, DiskMb: 3}, old: &hostings[0]},
+			wantErr:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.customer.CheckQuota(tt.args.hosting, tt.args.old, hostings)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Customer.CheckQuota() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestConsumedBy(t *testing.T) {
+	customer := populateCustomer(0, 0, 0)
+	assert.Equal(t, Quota{Cores: 4, MemoryMb: 4, DiskMb: 4}, ConsumedBy(customer, populateOwnedHostings()))
+}
@@ -11,6 +11,8 @@ const ()
 type (
 	Hosting struct {
 		UUID     UUID   `json:"uuid"`
+		Owner    UUID   `json:"owner,omitempty"`
+		Project  UUID   `json:"project,omitempty"`
 		Name     string `json:"name"`
 		Cores    int    `json:"cores"`
 		MemoryMb int    `json:"memorymb"`
@@ -25,11 +27,13 @@ func (u UUID) Validate() error {
 	return nil
 }
 
-func NewHosting(name string, cores, memorymb, diskmb int) (*Hosting, error) {
+func NewHosting(name string, cores, memorymb, diskmb int, owner, project UUID) (*Hosting, error) {
 
 	uuid := gouuid.NewV1()
 	return &Hosting{
 		UUID:     UUID(uuid.String()),
+		Owner:    owner,
+		Project:  project,
 		Name:     name,
 		Cores:    cores,
 		MemoryMb: memorymb,
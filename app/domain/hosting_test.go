@@ -113,6 +113,8 @@ func TestNewHosting(t *testing.T) {
 		cores    int
 		memorymb int
 		diskmb   int
+		owner    UUID
+		project  UUID
 	}
 	tests := []struct {
 		name    string
@@ -124,7 +126,7 @@ func TestNewHosting(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewHosting(tt.args.name, tt.args.cores, tt.args.memorymb, tt.args.diskmb)
+			got, err := NewHosting(tt.args.name, tt.args.cores, tt.args.memorymb, tt.args.diskmb, tt.args.owner, tt.args.project)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewHosting() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	gouuid "github.com/satori/go.uuid"
+)
+
+type (
+	// Project groups hostings of a customer. Its quota limits the resources taken
+	// among the hostings of the project, which also count against the customer quota.
+	Project struct {
+		UUID  UUID   `json:"uuid"`
+		Owner UUID   `json:"owner"`
+		Name  string `json:"name"`
+		Quota Quota  `json:"quota"`
+	}
+)
+
+func NewProject(owner UUID, name string, quota Quota) (*Project, error) {
+
+	uuid := gouuid.NewV1()
+	return &Project{
+		UUID:  UUID(uuid.String()),
+		Owner: owner,
+		Name:  name,
+		Quota: quota,
+	}, nil
+}
+
+func (p *Project) Validate() error {
+
+	err := p.UUID.Validate()
+	if err != nil {
+		return err
+	}
+
+	if len(p.Owner) == 0 {
+		return errors.New("Owner can't be empty")
+	}
+
+	if len(p.Name) == 0 {
+		return errors.New("Name can't be empty")
+	}
+
+	return p.Quota.Validate()
+}
+
+func (p *Project) ScopeName() string {
+	return "project " + string(p.UUID)
+}
+
+func (p *Project) Contains(hosting *Hosting) bool {
+	return hosting.Project == p.UUID
+}
+
+func (p *Project) Limit() Quota {
+	return p.Quota
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+type (
+	// Quota limits the resources that can be allocated among the hostings of a scope.
+	// A zero limit means there isn't a limit for that resource.
+	Quota struct {
+		Cores    int `json:"cores"`
+		MemoryMb int `json:"memorymb"`
+		DiskMb   int `json:"diskmb"`
+	}
+
+	// QuotaScope is a level of the quotas hierarchy. A hosting is allocated against
+	// the limits of every scope which contains it: its project and its customer.
+	QuotaScope interface {
+		ScopeName() string
+		Contains(hosting *Hosting) bool
+		Limit() Quota
+	}
+
+	// Usage shows the resources consumed in a scope, and its limit
+	Usage struct {
+		UUID     UUID   `json:"uuid"`
+		Name     string `json:"name"`
+		Consumed Quota  `json:"consumed"`
+		Limit    Quota  `json:"limit"`
+	}
+
+	ProjectUsage struct {
+		Usage
+		Hostings []Usage `json:"hostings"`
+	}
+
+	CustomerUsage struct {
+		Usage
+		Projects []ProjectUsage `json:"projects"`
+		Hostings []Usage        `json:"hostings"` // Hostings out of any project
+	}
+)
+
+func (q Quota) Validate() error {
+	if q.Cores < 0 || q.MemoryMb < 0 || q.DiskMb < 0 {
+		return errors.New("Quota limits can't be negative")
+	}
+	return nil
+}
+
+func (q Quota) add(hosting *Hosting) Quota {
+	q.Cores += hosting.Cores
+	q.MemoryMb += hosting.MemoryMb
+	q.DiskMb += hosting.DiskMb
+	return q
+}
+
+func (q Quota) sub(hosting *Hosting) Quota {
+	q.Cores -= hosting.Cores
+	q.MemoryMb -= hosting.MemoryMb
+	q.DiskMb -= hosting.DiskMb
+	return q
+}
+
+// fits checks the usage against the quota limits
+func (q Quota) fits(usage Quota) error {
+	if q.Cores > 0 && usage.Cores > q.Cores {
+		return errors.New("there is not cores enough")
+	}
+	if q.MemoryMb > 0 && usage.MemoryMb > q.MemoryMb {
+		return errors.New("there is not memory mb enough")
+	}
+	if q.DiskMb > 0 && usage.DiskMb > q.DiskMb {
+		return errors.New("there is not disk space mb enough")
+	}
+	return nil
+}
+
+// ConsumedBy returns the resources taken by the hostings of the scope
+func ConsumedBy(scope QuotaScope, hostings []Hosting) Quota {
+	var consumed Quota
+	for z := range hostings {
+		if scope.Contains(&hostings[z]) {
+			consumed = consumed.add(&hostings[z])
+		}
+	}
+	return consumed
+}
+
+// CheckQuotas walks the scopes, from the innermost to the outermost one, verifying
+// that allocating the hosting doesn't exceed any of their limits. If the hosting
+// is being updated, old is its current version, and its resources are not counted twice.
+func CheckQuotas(hosting, old *Hosting, hostings []Hosting, scopes ...QuotaScope) error {
+	for _, scope := range scopes {
+		if !scope.Contains(hosting) {
+			continue
+		}
+		consumed := ConsumedBy(scope, hostings)
+		if old != nil && scope.Contains(old) {
+			consumed = consumed.sub(old)
+		}
+		err := scope.Limit().fits(consumed.add(hosting))
+		if err != nil {
+			return errors.Wrapf(app.DomainErrorQuotaExceeded, "%s: %s", scope.ScopeName(), err.Error())
+		}
+	}
+	return nil
+}
+
+// CheckUsage verifies that the resources already taken by the hostings of the scope fit in its limits
+func CheckUsage(scope QuotaScope, hostings []Hosting) error {
+	err := scope.Limit().fits(ConsumedBy(scope, hostings))
+	if err != nil {
+		return errors.Wrapf(app.DomainErrorQuotaExceeded, "%s: %s", scope.ScopeName(), err.Error())
+	}
+	return nil
+}
+
+// NewCustomerUsage breaks down the resources consumed by the customer by project and hosting
+func NewCustomerUsage(customer *Customer, projects []Project, hostings []Hosting) *CustomerUsage {
+	usage := &CustomerUsage{
+		Usage: Usage{
+			UUID:     customer.UUID,
+			Name:     customer.Name,
+			Consumed: ConsumedBy(customer, hostings),
+			Limit:    customer.Limit(),
+		},
+		Projects: []ProjectUsage{},
+		Hostings: []Usage{},
+	}
+
+	for z := range projects {
+		project := &projects[z]
+		if project.Owner != customer.UUID {
+			continue
+		}
+		projectUsage := ProjectUsage{
+			Usage: Usage{
+				UUID:     project.UUID,
+				Name:     project.Name,
+				Consumed: ConsumedBy(project, hostings),
+				Limit:    project.Limit(),
+			},
+			Hostings: []Usage{},
+		}
+		for _, h := range hostings {
+			if project.Contains(&h) {
+				projectUsage.Hostings = append(projectUsage.Hostings, hostingUsage(&h))
+			}
+		}
+		usage.Projects = append(usage.Projects, projectUsage)
+	}
+
+	for _, h := range hostings {
+		if customer.Contains(&h) && len(h.Project) == 0 {
+			usage.Hostings = append(usage.Hostings, hostingUsage(&h))
+		}
+	}
+	return usage
+}
+
+// hostingUsage is the leaf of the usage tree. A hosting has no limit of its own.
+func hostingUsage(hosting *Hosting) Usage {
+	return Usage{
+		UUID:     hosting.UUID,
+		Name:     hosting.Name,
+		Consumed: Quota{}.add(hosting),
+	}
+}
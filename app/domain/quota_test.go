@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+func populateProject(cores, memorymb, diskmb int) *Project {
+	return &Project{UUID: UUID("project1"), Owner: UUID("customer1"), Name: "p1", Quota: Quota{Cores: cores, MemoryMb: memorymb, DiskMb: diskmb}}
+}
+
+func TestCheckQuotas(t *testing.T) {
+
+	// customer1 takes 4 cores, 2 of them in the project1
+	hostings := populateOwnedHostings()
+	hostings[0].Project = UUID("project1")
+
+	inProject := &Hosting{UUID: UUID("uuid4"), Owner: UUID("customer1"), Project: UUID("project1"), Name: "h4", Cores: 1, MemoryMb: 1, DiskMb: 1}
+	outOfProject := &Hosting{UUID: UUID("uuid4"), Owner: UUID("customer1"), Name: "h4", Cores: 1, MemoryMb: 1, DiskMb: 1}
+
+	tests := []struct {
+		name     string
+		hosting  *Hosting
+		project  *Project
+		customer *Customer
+		wantErr  bool
+	}{
+		{
+			name:     "given a hierarchy of quotas, when a hosting fits in all of them, then all works fine",
+			hosting:  inProject,
+			project:  populateProject(3, 3, 3),
+			customer: populateCustomer(5, 5, 5),
+			wantErr:  false,
+		},
+		{
+			name:     "given a hierarchy of quotas, when a hosting exceeds its project quota, then it fails",
+			hosting:  inProject,
+			project:  populateProject(2, 3, 3),
+			customer: populateCustomer(5, 5, 5),
+			wantErr:  true,
+		},
+		{
+			name:     "given a hierarchy of quotas, when a hosting fits in its project but exceeds its customer quota, then it fails",
+			hosting:  inProject,
+			project:  populateProject(3, 3, 3),
+			customer: populateCustomer(4, 5, 5),
+			wantErr:  true,
+		},
+		{
+			name:     "given a hierarchy of quotas, when a hosting out of the project is added, then the project quota is not taken into account",
+			hosting:  outOfProject,
+			project:  populateProject(1, 1, 1),
+			customer: populateCustomer(5, 5, 5),
+			wantErr:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckQuotas(tt.hosting, nil, hostings, tt.project, tt.customer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckQuotas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestNewCustomerUsage(t *testing.T) {
+
+	hostings := populateOwnedHostings()
+	hostings[0].Project = UUID("project1")
+	projects := []Project{*populateProject(3, 0, 0)}
+
+	usage := NewCustomerUsage(populateCustomer(10, 0, 0), projects, hostings)
+
+	assert.Equal(t, Quota{Cores: 4, MemoryMb: 4, DiskMb: 4}, usage.Consumed)
+	assert.Equal(t, Quota{Cores: 10}, usage.Limit)
+
+	assert.Equal(t, 1, len(usage.Projects))
+	assert.Equal(t, Quota{Cores: 2, MemoryMb: 2, DiskMb: 2}, usage.Projects[0].Consumed)
+	assert.Equal(t, Quota{Cores: 3}, usage.Projects[0].Limit)
+	assert.Equal(t, 1, len(usage.Projects[0].Hostings))
+	assert.Equal(t, UUID("uuid1"), usage.Projects[0].Hostings[0].UUID)
+
+	assert.Equal(t, 1, len(usage.Hostings))
+	assert.Equal(t, UUID("uuid2"), usage.Hostings[0].UUID)
+}
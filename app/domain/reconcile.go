@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of discrepancy between the server resources, the names index and the stored hostings
+const (
+	// The available resource is not the total one minus the one taken by the hostings
+	DiscrepancyAvailability = "availability"
+	// The hostings take more than the total resource. It can't be repaired, but by removing hostings.
+	DiscrepancyOverCapacity = "over_capacity"
+	// A hosting has no entry in the names index
+	DiscrepancyMissingName = "missing_name"
+	// The entry of the hosting name points to another hosting
+	DiscrepancyWrongName = "wrong_name"
+	// Several hostings have the same name. It can't be repaired, but by renaming them.
+	DiscrepancyDuplicateName = "duplicate_name"
+	// An entry of the names index has no hosting with that name
+	DiscrepancyDanglingName = "dangling_name"
+)
+
+type (
+	// Discrepancy is an inconsistency found by CheckConsistency
+	Discrepancy struct {
+		Kind string `json:"kind"`
+		// Subject is the resource, or the name of the index entry
+		Subject string `json:"subject"`
+		// UUID is the hosting the index entry must point to, if any
+		UUID       UUID   `json:"uuid,omitempty"`
+		Detail     string `json:"detail"`
+		Repairable bool   `json:"repairable"`
+		Repaired   bool   `json:"repaired"`
+	}
+)
+
+// Used returns the resources taken by the hostings
+func Used(hostings []Hosting) Quota {
+	var used Quota
+	for z := range hostings {
+		used = used.add(&hostings[z])
+	}
+	return used
+}
+
+// CheckConsistency checks the server resources and the names index against the hostings:
+// the available resources must be the total ones minus the ones taken by the hostings,
+// every hosting must have one entry in the index, and every entry must point to its hosting.
+func CheckConsistency(status ServerStatus, hostings []Hosting, names map[string]UUID) []Discrepancy {
+	discrepancies := checkAvailability(status, Used(hostings))
+
+	byName := make(map[string][]UUID)
+	for _, h := range hostings {
+		byName[h.Name] = append(byName[h.Name], h.UUID)
+	}
+	for _, name := range sortedNames(byName, names) {
+		uuids, entry := byName[name], names[name]
+		switch {
+		case len(uuids) == 0:
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind: DiscrepancyDanglingName, Subject: name, Repairable: true,
+				Detail: fmt.Sprintf("the name %s is indexed, but there is no hosting with it", name),
+			})
+		case len(uuids) > 1:
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind: DiscrepancyDuplicateName, Subject: name,
+				Detail: fmt.Sprintf("the name %s is taken by %d hostings: %v", name, len(uuids), uuids),
+			})
+		case len(entry) == 0:
+			if _, ok := names[name]; !ok {
+				discrepancies = append(discrepancies, Discrepancy{
+					Kind: DiscrepancyMissingName, Subject: name, UUID: uuids[0], Repairable: true,
+					Detail: fmt.Sprintf("the name of the hosting %s is not indexed", uuids[0]),
+				})
+				break
+			}
+			fallthrough
+		case entry != uuids[0]:
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind: DiscrepancyWrongName, Subject: name, UUID: uuids[0], Repairable: true,
+				Detail: fmt.Sprintf("the name %s points to %q instead of the hosting %s", name, string(entry), uuids[0]),
+			})
+		}
+	}
+	return discrepancies
+}
+
+func checkAvailability(status ServerStatus, used Quota) []Discrepancy {
+	resources := []struct {
+		name                  string
+		total, available, use int
+	}{
+		{"cores", status.TotalCores, status.AvailableCores, used.Cores},
+		{"memory_mb", status.TotalSizeOfMemoryMb, status.AvailableSizeOfMemoryMb, used.MemoryMb},
+		{"disk_mb", status.TotalSizeOfDiskMb, status.AvailableSizeOfDiskMb, used.DiskMb},
+	}
+
+	// The availability is recalculated as a whole, so it can't be repaired if any resource is over capacity
+	repairable := true
+	for _, r := range resources {
+		if r.use > r.total {
+			repairable = false
+		}
+	}
+
+	var discrepancies []Discrepancy
+	for _, r := range resources {
+		switch {
+		case r.use > r.total:
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind: DiscrepancyOverCapacity, Subject: r.name,
+				Detail: fmt.Sprintf("the hostings take %d %s, more than the %d of the server", r.use, r.name, r.total),
+			})
+		case r.available != r.total-r.use:
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind: DiscrepancyAvailability, Subject: r.name, Repairable: repairable,
+				Detail: fmt.Sprintf("%d %s are available, but the hostings leave %d of %d", r.available, r.name, r.total-r.use, r.total),
+			})
+		}
+	}
+	return discrepancies
+}
+
+// sortedNames returns the names of the hostings and of the index entries, sorted and without repetitions
+func sortedNames(byName map[string][]UUID, names map[string]UUID) []string {
+	all := make([]string, 0, len(byName)+len(names))
+	for name := range byName {
+		all = append(all, name)
+	}
+	for name := range names {
+		if _, ok := byName[name]; !ok {
+			all = append(all, name)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
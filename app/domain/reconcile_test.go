@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	hostings := []Hosting{
+		{UUID: "u1", Name: "h1", Cores: 10, MemoryMb: 20, DiskMb: 30},
+		{UUID: "u2", Name: "h2", Cores: 10, MemoryMb: 20, DiskMb: 30},
+	}
+	consistent := ServerStatus{
+		TotalCores: 100, TotalSizeOfMemoryMb: 100, TotalSizeOfDiskMb: 100,
+		AvailableCores: 80, AvailableSizeOfMemoryMb: 60, AvailableSizeOfDiskMb: 40,
+	}
+	names := map[string]UUID{"h1": "u1", "h2": "u2"}
+
+	tests := []struct {
+		name     string
+		status   ServerStatus
+		hostings []Hosting
+		names    map[string]UUID
+		want     []Discrepancy
+	}{
+		{
+			name:     "given a consistent server and index, when they're checked, then nothing is found",
+			status:   consistent,
+			hostings: hostings,
+			names:    names,
+		},
+		{
+			name:   "given no hostings and a full server, when it's checked, then the resources are not available",
+			status: ServerStatus{TotalCores: 10, TotalSizeOfMemoryMb: 10, TotalSizeOfDiskMb: 10, AvailableCores: 10, AvailableSizeOfMemoryMb: 10},
+			want: []Discrepancy{
+				{Kind: DiscrepancyAvailability, Subject: "disk_mb", Repairable: true, Detail: "0 disk_mb are available, but the hostings leave 10 of 10"},
+			},
+		},
+		{
+			name: "given hostings over the server capacity, when they're checked, then nothing of the availability can be repaired",
+			status: ServerStatus{
+				TotalCores: 100, TotalSizeOfMemoryMb: 30, TotalSizeOfDiskMb: 100,
+				AvailableCores: 100, AvailableSizeOfMemoryMb: 0, AvailableSizeOfDiskMb: 40,
+			},
+			hostings: hostings,
+			names:    names,
+			want: []Discrepancy{
+				{Kind: DiscrepancyAvailability, Subject: "cores", Detail: "100 cores are available, but the hostings leave 80 of 100"},
+				{Kind: DiscrepancyOverCapacity, Subject: "memory_mb", Detail: "the hostings take 40 memory_mb, more than the 30 of the server"},
+			},
+		},
+		{
+			name:     "given a broken names index, when it's checked, then the missing, wrong and dangling entries are found",
+			status:   consistent,
+			hostings: hostings,
+			names:    map[string]UUID{"h2": "0", "h3": "u3"},
+			want: []Discrepancy{
+				{Kind: DiscrepancyMissingName, Subject: "h1", UUID: "u1", Repairable: true, Detail: "the name of the hosting u1 is not indexed"},
+				{Kind: DiscrepancyWrongName, Subject: "h2", UUID: "u2", Repairable: true, Detail: `the name h2 points to "0" instead of the hosting u2`},
+				{Kind: DiscrepancyDanglingName, Subject: "h3", Repairable: true, Detail: "the name h3 is indexed, but there is no hosting with it"},
+			},
+		},
+		{
+			name:     "given two hostings with the same name, when they're checked, then it can't be repaired",
+			status:   consistent,
+			hostings: []Hosting{hostings[0], {UUID: "u2", Name: "h1", Cores: 10, MemoryMb: 20, DiskMb: 30}},
+			names:    map[string]UUID{"h1": "u1"},
+			want: []Discrepancy{
+				{Kind: DiscrepancyDuplicateName, Subject: "h1", Detail: "the name h1 is taken by 2 hostings: [u1 u2]"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CheckConsistency(tt.status, tt.hostings, tt.names))
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package domain
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pkg/errors"
 	gouuid "github.com/satori/go.uuid"
+	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 )
 
@@ -19,7 +21,26 @@ type (
 		TotalSizeOfDiskMb       int  `json:"total_disk_mb"`
 		AvailableCores          int  `json:"available_cores"`
 		AvailableSizeOfMemoryMb int  `json:"available_memory_mb"`
-		AvailableSizeOfDiskMb   int  `json:"available_disk_mb`
+		AvailableSizeOfDiskMb   int  `json:"available_disk_mb"`
+	}
+
+	// ServerStatus is a consistent copy of the server resources, taken at once
+	ServerStatus struct {
+		UUID                    UUID        `json:"uuid"`
+		TotalCores              int         `json:"total_cores"`
+		TotalSizeOfMemoryMb     int         `json:"total_memory_mb"`
+		TotalSizeOfDiskMb       int         `json:"total_disk_mb"`
+		AvailableCores          int         `json:"available_cores"`
+		AvailableSizeOfMemoryMb int         `json:"available_memory_mb"`
+		AvailableSizeOfDiskMb   int         `json:"available_disk_mb"`
+		Utilization             Utilization `json:"utilization"`
+	}
+
+	// Utilization is the percentage of each resource which is taken by the hostings
+	Utilization struct {
+		Cores    float64 `json:"cores"`
+		MemoryMb float64 `json:"memory_mb"`
+		DiskMb   float64 `json:"disk_mb"`
 	}
 )
 
@@ -37,7 +58,10 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	}, nil
 }
 
-func (s *Server) AddHosting(hosting *Hosting, cfg *config.Config) error {
+func (s *Server) AddHosting(ctx context.Context, hosting *Hosting, cfg *config.Config) error {
+	s.Lock()
+	defer s.Unlock()
+
 	err := s.checkForResourcesAvailability(hosting)
 	if err != nil {
 		return errors.Wrap(err, "there aren't resources enough to create the hosting")
@@ -49,13 +73,13 @@ func (s *Server) AddHosting(hosting *Hosting, cfg *config.Config) error {
 
 func (s *Server) checkForResourcesAvailability(hosting *Hosting) error {
 	if (s.AvailableCores - hosting.Cores) < 0 {
-		return errors.New("there is not cores enough")
+		return errors.Wrap(app.DomainErrorQuotaExceeded, "there is not cores enough")
 	}
 	if (s.AvailableSizeOfMemoryMb - hosting.MemoryMb) < 0 {
-		return errors.New("there is not memory mb enough")
+		return errors.Wrap(app.DomainErrorQuotaExceeded, "there is not memory mb enough")
 	}
 	if (s.AvailableSizeOfDiskMb - hosting.DiskMb) < 0 {
-		return errors.New("there is not disk space mb enough")
+		return errors.Wrap(app.DomainErrorQuotaExceeded, "there is not disk space mb enough")
 	}
 	return nil
 }
@@ -67,7 +91,10 @@ func (s *Server) assignResources(hosting *Hosting) {
 	return
 }
 
-func (s *Server) RemoveHosting(hosting *Hosting) error {
+func (s *Server) RemoveHosting(ctx context.Context, hosting *Hosting) error {
+	s.Lock()
+	defer s.Unlock()
+
 	s.restoreResources(hosting)
 	return nil
 }
@@ -78,7 +105,10 @@ func (s *Server) restoreResources(hosting *Hosting) {
 	s.AvailableSizeOfDiskMb += hosting.DiskMb
 }
 
-func (s *Server) UpdateHosting(hosting, old *Hosting, cfg *config.Config) error {
+func (s *Server) UpdateHosting(ctx context.Context, hosting, old *Hosting, cfg *config.Config) error {
+	s.Lock()
+	defer s.Unlock()
+
 	s.restoreResources(old)
 
 	err := s.checkForResourcesAvailability(hosting)
@@ -90,3 +120,96 @@ func (s *Server) UpdateHosting(hosting, old *Hosting, cfg *config.Config) error
 	s.assignResources(hosting)
 	return nil
 }
+
+// Resize changes the server resources, keeping the ones taken by the hostings. It's refused if
+// the hostings take more than the new resources, so the server is never over its capacity.
+func (s *Server) Resize(ctx context.Context, cores, memorymb, diskmb int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	usedCores := s.TotalCores - s.AvailableCores
+	usedMemoryMb := s.TotalSizeOfMemoryMb - s.AvailableSizeOfMemoryMb
+	usedDiskMb := s.TotalSizeOfDiskMb - s.AvailableSizeOfDiskMb
+
+	if usedCores > cores {
+		return errors.Wrapf(app.DomainErrorQuotaExceeded, "the hostings take %d cores, more than the %d of the server", usedCores, cores)
+	}
+	if usedMemoryMb > memorymb {
+		return errors.Wrapf(app.DomainErrorQuotaExceeded, "the hostings take %d memory mb, more than the %d of the server", usedMemoryMb, memorymb)
+	}
+	if usedDiskMb > diskmb {
+		return errors.Wrapf(app.DomainErrorQuotaExceeded, "the hostings take %d disk mb, more than the %d of the server", usedDiskMb, diskmb)
+	}
+
+	s.TotalCores, s.AvailableCores = cores, cores-usedCores
+	s.TotalSizeOfMemoryMb, s.AvailableSizeOfMemoryMb = memorymb, memorymb-usedMemoryMb
+	s.TotalSizeOfDiskMb, s.AvailableSizeOfDiskMb = diskmb, diskmb-usedDiskMb
+	return nil
+}
+
+// SetUsage sets the resources taken by the hostings, so the available ones are recalculated from them,
+// as when they're loaded from the store. It's refused if they take more than the server resources.
+func (s *Server) SetUsage(ctx context.Context, used Quota) error {
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.checkUsage(used)
+	if err != nil {
+		return err
+	}
+	s.setUsage(used)
+	return nil
+}
+
+// LoadUsage sets the resources taken by the stored hostings as SetUsage, but even if they take more than the server
+// resources, as the hostings are there anyway. Then the availability is negative, so no hosting fits until enough
+// of them are removed or the server is resized, and app.DomainErrorQuotaExceeded is returned to tell it.
+func (s *Server) LoadUsage(ctx context.Context, used Quota) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.setUsage(used)
+	return s.checkUsage(used)
+}
+
+func (s *Server) checkUsage(used Quota) error {
+	if used.Cores > s.TotalCores || used.MemoryMb > s.TotalSizeOfMemoryMb || used.DiskMb > s.TotalSizeOfDiskMb {
+		return errors.Wrapf(app.DomainErrorQuotaExceeded, "the hostings take %d cores, %d memory mb and %d disk mb, more than the %d, %d and %d of the server",
+			used.Cores, used.MemoryMb, used.DiskMb, s.TotalCores, s.TotalSizeOfMemoryMb, s.TotalSizeOfDiskMb)
+	}
+	return nil
+}
+
+func (s *Server) setUsage(used Quota) {
+	s.AvailableCores = s.TotalCores - used.Cores
+	s.AvailableSizeOfMemoryMb = s.TotalSizeOfMemoryMb - used.MemoryMb
+	s.AvailableSizeOfDiskMb = s.TotalSizeOfDiskMb - used.DiskMb
+}
+
+// Snapshot returns the status of the server resources. It's safe to be called while the hostings are changed.
+func (s *Server) Snapshot() ServerStatus {
+	s.Lock()
+	defer s.Unlock()
+
+	return ServerStatus{
+		UUID:                    s.UUID,
+		TotalCores:              s.TotalCores,
+		TotalSizeOfMemoryMb:     s.TotalSizeOfMemoryMb,
+		TotalSizeOfDiskMb:       s.TotalSizeOfDiskMb,
+		AvailableCores:          s.AvailableCores,
+		AvailableSizeOfMemoryMb: s.AvailableSizeOfMemoryMb,
+		AvailableSizeOfDiskMb:   s.AvailableSizeOfDiskMb,
+		Utilization: Utilization{
+			Cores:    utilization(s.TotalCores, s.AvailableCores),
+			MemoryMb: utilization(s.TotalSizeOfMemoryMb, s.AvailableSizeOfMemoryMb),
+			DiskMb:   utilization(s.TotalSizeOfDiskMb, s.AvailableSizeOfDiskMb),
+		},
+	}
+}
+
+func utilization(total, available int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(total-available) * 100 / float64(total)
+}
@@ -1,16 +1,18 @@
 package domain
 
 import (
+	"context"
 	"sync"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 )
 
 func populateServer() *Server {
 	return &Server{
-		Mutex:                   sync.Mutex{},
 		UUID:                    UUID("uuid1"),
 		TotalCores:              100,
 		TotalSizeOfMemoryMb:     100,
@@ -96,7 +98,7 @@ func TestServer_AddHosting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := tt.fields.server
-			if err := s.AddHosting(tt.args.hosting, tt.args.cfg); (err != nil) != tt.wantErr {
+			if err := s.AddHosting(context.Background(), tt.args.hosting, tt.args.cfg); (err != nil) != tt.wantErr {
 				t.Errorf("Server.AddHosting() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -114,7 +116,6 @@ func TestServer_RemoveHosting(t *testing.T) {
 	notExistHosting.UUID = UUID("uuid89")
 
 	type fields struct {
-		Mutex                   sync.Mutex
 		UUID                    UUID
 		TotalCores              int
 		TotalSizeOfMemoryMb     int
@@ -135,7 +136,6 @@ func TestServer_RemoveHosting(t *testing.T) {
 		{
 			name: "given a server, when an existing hosting is removed, all works fine",
 			fields: fields{
-				Mutex:                   sync.Mutex{},
 				UUID:                    UUID("uuid1"),
 				TotalCores:              100,
 				TotalSizeOfMemoryMb:     100,
@@ -153,7 +153,6 @@ func TestServer_RemoveHosting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
-				Mutex:                   tt.fields.Mutex,
 				UUID:                    tt.fields.UUID,
 				TotalCores:              tt.fields.TotalCores,
 				TotalSizeOfMemoryMb:     tt.fields.TotalSizeOfMemoryMb,
@@ -162,7 +161,7 @@ func TestServer_RemoveHosting(t *testing.T) {
 				AvailableSizeOfMemoryMb: tt.fields.AvailableSizeOfMemoryMb,
 				AvailableSizeOfDiskMb:   tt.fields.AvailableSizeOfDiskMb,
 			}
-			if err := s.RemoveHosting(tt.args.hosting); (err != nil) != tt.wantErr {
+			if err := s.RemoveHosting(context.Background(), tt.args.hosting); (err != nil) != tt.wantErr {
 				t.Errorf("Server.RemoveHosting() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -181,7 +180,6 @@ func TestServer_UpdateHosting(t *testing.T) {
 	hostingNewOverSized := populateHosting(101, 1, 1)
 
 	type fields struct {
-		Mutex                   sync.Mutex
 		UUID                    UUID
 		TotalCores              int
 		TotalSizeOfMemoryMb     int
@@ -204,7 +202,6 @@ func TestServer_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is updated and the new configuration fits in the server, then all works fine",
 			fields: fields{
-				Mutex:                   sync.Mutex{},
 				UUID:                    UUID("uuid1"),
 				TotalCores:              100,
 				TotalSizeOfMemoryMb:     100,
@@ -223,7 +220,6 @@ func TestServer_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is updated and the new configuration doesn't fit in the server, then it fails",
 			fields: fields{
-				Mutex:                   sync.Mutex{},
 				UUID:                    UUID("uuid1"),
 				TotalCores:              100,
 				TotalSizeOfMemoryMb:     100,
@@ -243,7 +239,6 @@ func TestServer_UpdateHosting(t *testing.T) {
 	for z, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
-				Mutex:                   tt.fields.Mutex,
 				UUID:                    tt.fields.UUID,
 				TotalCores:              tt.fields.TotalCores,
 				TotalSizeOfMemoryMb:     tt.fields.TotalSizeOfMemoryMb,
@@ -252,7 +247,7 @@ func TestServer_UpdateHosting(t *testing.T) {
 				AvailableSizeOfMemoryMb: tt.fields.AvailableSizeOfMemoryMb,
 				AvailableSizeOfDiskMb:   tt.fields.AvailableSizeOfDiskMb,
 			}
-			if err := s.UpdateHosting(tt.args.hosting, tt.args.old, tt.args.cfg); (err != nil) != tt.wantErr {
+			if err := s.UpdateHosting(context.Background(), tt.args.hosting, tt.args.old, tt.args.cfg); (err != nil) != tt.wantErr {
 				t.Errorf("Server.UpdateHosting() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -269,3 +264,161 @@ func TestServer_UpdateHosting(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_Snapshot(t *testing.T) {
+	server := populateServer()
+	assert.NoError(t, server.AddHosting(context.Background(), populateHosting(25, 50, 10), populateConfig()))
+
+	assert.Equal(t, ServerStatus{
+		UUID:                    UUID("uuid1"),
+		TotalCores:              100,
+		TotalSizeOfMemoryMb:     100,
+		TotalSizeOfDiskMb:       100,
+		AvailableCores:          75,
+		AvailableSizeOfMemoryMb: 50,
+		AvailableSizeOfDiskMb:   90,
+		Utilization:             Utilization{Cores: 25, MemoryMb: 50, DiskMb: 10},
+	}, server.Snapshot())
+}
+
+func TestServer_Resize(t *testing.T) {
+	tests := []struct {
+		name                    string
+		cores, memorymb, diskmb int
+		wantErr                 bool
+		want                    [3]int // Available cores, memory and disk after resizing
+	}{
+		{
+			name:  "given a server with hostings, when it grows, then the new resources become available",
+			cores: 200, memorymb: 150, diskmb: 100,
+			want: [3]int{175, 100, 90},
+		},
+		{
+			name:  "given a server with hostings, when it shrinks down to the hostings, then nothing is available",
+			cores: 25, memorymb: 50, diskmb: 10,
+			want: [3]int{0, 0, 0},
+		},
+		{
+			name:  "given a server with hostings, when it shrinks below them, then it's refused and the server is kept",
+			cores: 100, memorymb: 49, diskmb: 100,
+			wantErr: true,
+			want:    [3]int{75, 50, 90},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := populateServer()
+			assert.NoError(t, server.AddHosting(context.Background(), populateHosting(25, 50, 10), populateConfig()))
+
+			err := server.Resize(context.Background(), tt.cores, tt.memorymb, tt.diskmb)
+			if tt.wantErr {
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, [3]int{tt.cores, tt.memorymb, tt.diskmb}, [3]int{server.TotalCores, server.TotalSizeOfMemoryMb, server.TotalSizeOfDiskMb})
+			}
+
+			status := server.Snapshot()
+			assert.Equal(t, tt.want, [3]int{status.AvailableCores, status.AvailableSizeOfMemoryMb, status.AvailableSizeOfDiskMb})
+		})
+	}
+}
+
+func TestServer_SetUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		used    Quota
+		wantErr bool
+		want    [3]int // Available cores, memory and disk
+	}{
+		{
+			name: "given a server, when the usage is set, then the available resources are recalculated",
+			used: Quota{Cores: 25, MemoryMb: 50, DiskMb: 100},
+			want: [3]int{75, 50, 0},
+		},
+		{
+			name:    "given a server, when the usage exceeds its resources, then it's refused and the server is kept",
+			used:    Quota{Cores: 25, MemoryMb: 50, DiskMb: 101},
+			wantErr: true,
+			want:    [3]int{99, 99, 99},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := populateServer()
+			assert.NoError(t, server.AddHosting(context.Background(), populateHosting(1, 1, 1), populateConfig()))
+
+			err := server.SetUsage(context.Background(), tt.used)
+			if tt.wantErr {
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			status := server.Snapshot()
+			assert.Equal(t, tt.want, [3]int{status.AvailableCores, status.AvailableSizeOfMemoryMb, status.AvailableSizeOfDiskMb})
+		})
+	}
+}
+
+func TestServer_LoadUsage(t *testing.T) {
+	server := populateServer()
+
+	// The hostings over the capacity are taken all the same, and reported
+	err := server.LoadUsage(context.Background(), Quota{Cores: 25, MemoryMb: 50, DiskMb: 101})
+	assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+	status := server.Snapshot()
+	assert.Equal(t, [3]int{75, 50, -1}, [3]int{status.AvailableCores, status.AvailableSizeOfMemoryMb, status.AvailableSizeOfDiskMb})
+
+	// So no hosting fits, until enough of them are removed
+	assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(server.AddHosting(context.Background(), populateHosting(1, 1, 1), populateConfig())))
+	assert.NoError(t, server.RemoveHosting(context.Background(), populateHosting(1, 1, 2)))
+	assert.NoError(t, server.AddHosting(context.Background(), populateHosting(1, 1, 1), populateConfig()))
+}
+
+// TestServer_SnapshotRace takes snapshots while the hostings are changed. All the hostings take the same
+// amount of each resource, so a snapshot taken in the middle of a change would have different availabilities.
+// Run it with -race to check the accesses too.
+func TestServer_SnapshotRace(t *testing.T) {
+	server := populateServer()
+	cfg := populateConfig()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hosting, bigger := populateHosting(1, 1, 1), populateHosting(2, 2, 2)
+			for j := 0; j < 500; j++ {
+				if server.AddHosting(ctx, hosting, cfg) != nil {
+					continue
+				}
+				if server.UpdateHosting(ctx, bigger, hosting, cfg) == nil {
+					server.RemoveHosting(ctx, bigger)
+				} else {
+					server.RemoveHosting(ctx, hosting)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, 100, server.Snapshot().AvailableCores)
+			return
+		default:
+			status := server.Snapshot()
+			assert.Equal(t, status.AvailableCores, status.AvailableSizeOfMemoryMb)
+			assert.Equal(t, status.AvailableCores, status.AvailableSizeOfDiskMb)
+			assert.Equal(t, status.Utilization.Cores, status.Utilization.DiskMb)
+		}
+	}
+}
@@ -5,4 +5,14 @@ import "errors"
 var (
 	DbErrorAlreadyExist error = errors.New("already exist")
 	DbErrorNotFound     error = errors.New("not found")
+	DbErrorInUse        error = errors.New("in use")
+	DbErrorUnavailable  error = errors.New("store unavailable")
+
+	DbErrorUnknownOutcome error = errors.New("unknown outcome")
+
+	DomainErrorQuotaExceeded error = errors.New("quota exceeded")
+	DomainErrorInvalid       error = errors.New("invalid")
+
+	AuthErrorUnauthenticated error = errors.New("unauthenticated")
+	AuthErrorForbidden       error = errors.New("forbidden")
 )
@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	// Registry keeps the metrics of the service, and writes them in the Prometheus text exposition format
+	Registry struct {
+		sync.Mutex
+		metrics []metric
+	}
+
+	metric interface {
+		name() string
+		write(w *bufio.Writer)
+	}
+
+	desc struct {
+		metricName string
+		help       string
+		labels     []string
+	}
+
+	// CounterVec is a set of counters, one by each combination of label values
+	CounterVec struct {
+		desc
+		sync.Mutex
+		values map[string]*counterValue
+	}
+
+	counterValue struct {
+		labels []string
+		value  float64
+	}
+
+	// HistogramVec is a set of histograms, one by each combination of label values
+	HistogramVec struct {
+		desc
+		sync.Mutex
+		buckets []float64
+		values  map[string]*histogramValue
+	}
+
+	histogramValue struct {
+		labels []string
+		counts []uint64 // By bucket, not cumulative
+		count  uint64
+		sum    float64
+	}
+
+	// Sample is a value of a gauge, with its label values
+	Sample struct {
+		Labels []string
+		Value  float64
+	}
+
+	// GaugeFunc is a gauge whose samples are collected on each scrape
+	GaugeFunc struct {
+		desc
+		collect func() []Sample
+	}
+)
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.Lock()
+	defer r.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{metricName: name, help: help, labels: labels},
+		values: make(map[string]*counterValue),
+	}
+	r.register(c)
+	return c
+}
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) GaugeFunc(name, help string, collect func() []Sample, labels ...string) *GaugeFunc {
+	g := &GaugeFunc{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		collect: collect,
+	}
+	r.register(g)
+	return g
+}
+
+// Write writes all the metrics, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.Unlock()
+
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP publishes the metrics to be scraped
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.Write(w)
+}
+
+func (c *CounterVec) Add(v float64, labels ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := seriesKey(labels)
+	value, ok := c.values[key]
+	if !ok {
+		value = &counterValue{labels: labels}
+		c.values[key] = value
+	}
+	value.value += v
+}
+
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.Lock()
+	defer c.Unlock()
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c.writeHeader(w, typeCounter)
+	for _, key := range keys {
+		value := c.values[key]
+		c.writeSample(w, "", value.labels, "", value.value)
+	}
+}
+
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	h.Lock()
+	defer h.Unlock()
+
+	key := seriesKey(labels)
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogramValue{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = value
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			value.counts[i]++
+			break
+		}
+	}
+	value.count++
+	value.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.Lock()
+	defer h.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h.writeHeader(w, typeHistogram)
+	for _, key := range keys {
+		value := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += value.counts[i]
+			h.writeSample(w, "_bucket", value.labels, formatFloat(upper), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", value.labels, formatFloat(math.Inf(1)), float64(value.count))
+		h.writeSample(w, "_sum", value.labels, "", value.sum)
+		h.writeSample(w, "_count", value.labels, "", float64(value.count))
+	}
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w, typeGauge)
+	for _, sample := range g.collect() {
+		g.writeSample(w, "", sample.Labels, "", sample.Value)
+	}
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+func (d *desc) writeHeader(w *bufio.Writer, metricType string) {
+	w.WriteString("# HELP " + d.metricName + " " + escapeHelp(d.help) + "\n")
+	w.WriteString("# TYPE " + d.metricName + " " + metricType + "\n")
+}
+
+// writeSample writes a line of the metric. The le label is only given for the histogram buckets.
+func (d *desc) writeSample(w *bufio.Writer, suffix string, labels []string, le string, value float64) {
+	w.WriteString(d.metricName + suffix)
+
+	pairs := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		var v string
+		if i < len(labels) {
+			v = labels[i]
+		}
+		pairs = append(pairs, label+`="`+escapeLabelValue(v)+`"`)
+	}
+	if len(le) > 0 {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func seriesKey(labels []string) string {
+	return strings.Join(labels, "\x00")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Write(t *testing.T) {
+
+	r := NewRegistry()
+
+	requests := r.Counter("requests_total", "Number of requests.", "route", "status")
+	requests.Inc("/hosting", "200")
+	requests.Inc("/hosting", "200")
+	requests.Inc("/hosting/{uuid}", "404")
+
+	latency := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/hosting")
+	latency.Observe(0.5, "/hosting")
+	latency.Observe(2, "/hosting")
+
+	r.GaugeFunc("available", "Available \"resources\"\nby name.", func() []Sample {
+		return []Sample{{Labels: []string{`di"sk`}, Value: 2.5}}
+	}, "resource")
+
+	b := bytes.Buffer{}
+	assert.NoError(t, r.Write(&b))
+	assert.Equal(t, `# HELP available Available "resources"\nby name.
+# TYPE available gauge
+available{resource="di\"sk"} 2.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/hosting",le="0.1"} 1
+latency_seconds_bucket{route="/hosting",le="1"} 2
+latency_seconds_bucket{route="/hosting",le="+Inf"} 3
+latency_seconds_sum{route="/hosting"} 2.55
+latency_seconds_count{route="/hosting"} 3
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="/hosting",status="200"} 2
+requests_total{route="/hosting/{uuid}",status="404"} 1
+`, b.String())
+}
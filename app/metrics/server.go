@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+const (
+	resourceCores    = "cores"
+	resourceMemoryMb = "memory_mb"
+	resourceDiskMb   = "disk_mb"
+)
+
+type ServerStatus interface {
+	GetServerStatus() domain.ServerStatus
+	GetHostings(ctx context.Context, principal *domain.Principal) ([]domain.Hosting, error)
+}
+
+// RegisterServer registers the gauges of the hostings and the server resources, which are collected on each scrape
+func RegisterServer(registry *Registry, server ServerStatus) {
+	registry.GaugeFunc("cdmon2_hostings", "Number of hostings.", func() []Sample {
+		hostings, err := server.GetHostings(context.Background(), nil)
+		if err != nil {
+			return nil
+		}
+		return []Sample{{Value: float64(len(hostings))}}
+	})
+
+	registry.GaugeFunc("cdmon2_server_resource_total", "Total amount of each server resource.", func() []Sample {
+		status := server.GetServerStatus()
+		return []Sample{
+			{Labels: []string{resourceCores}, Value: float64(status.TotalCores)},
+			{Labels: []string{resourceDiskMb}, Value: float64(status.TotalSizeOfDiskMb)},
+			{Labels: []string{resourceMemoryMb}, Value: float64(status.TotalSizeOfMemoryMb)},
+		}
+	}, "resource")
+
+	registry.GaugeFunc("cdmon2_server_resource_available", "Available amount of each server resource.", func() []Sample {
+		status := server.GetServerStatus()
+		return []Sample{
+			{Labels: []string{resourceCores}, Value: float64(status.AvailableCores)},
+			{Labels: []string{resourceDiskMb}, Value: float64(status.AvailableSizeOfDiskMb)},
+			{Labels: []string{resourceMemoryMb}, Value: float64(status.AvailableSizeOfMemoryMb)},
+		}
+	}, "resource")
+
+	registry.GaugeFunc("cdmon2_server_resource_utilization_percent", "Percentage of each server resource taken by the hostings.", func() []Sample {
+		status := server.GetServerStatus()
+		return []Sample{
+			{Labels: []string{resourceCores}, Value: status.Utilization.Cores},
+			{Labels: []string{resourceDiskMb}, Value: status.Utilization.DiskMb},
+			{Labels: []string{resourceMemoryMb}, Value: status.Utilization.MemoryMb},
+		}
+	}, "resource")
+}
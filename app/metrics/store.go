@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+const (
+	opStatusOK       = "ok"
+	opStatusNotFound = "not_found"
+	// Rejected by the circuit breaker, without reaching the store
+	opStatusUnavailable = "unavailable"
+	opStatusError       = "error"
+)
+
+type (
+	Store interface {
+		Connect() error
+		Close() error
+		Get(ctx context.Context, key string, item interface{}) (interface{}, error)
+		GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+		Set(ctx context.Context, key string, item interface{}) error
+		Remove(ctx context.Context, key string) error
+		Keys(ctx context.Context, pattern string) ([]string, error)
+	}
+
+	// CircuitBreaker tells if the store operations are failing fast
+	CircuitBreaker interface {
+		Open() bool
+	}
+
+	// InstrumentedStore measures the latency of the store operations
+	InstrumentedStore struct {
+		Store
+		latency *HistogramVec
+	}
+)
+
+func NewInstrumentedStore(store Store, registry *Registry) *InstrumentedStore {
+	return &InstrumentedStore{
+		Store:   store,
+		latency: registry.Histogram("cdmon2_store_operation_duration_seconds", "Latency of the store operations.", DefaultBuckets, "op", "status"),
+	}
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
+	start := time.Now()
+	item, err := s.Store.Get(ctx, key, item)
+	s.observe(config.StoreOpGet, start, err)
+	return item, err
+}
+
+func (s *InstrumentedStore) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+	start := time.Now()
+	items, err := s.Store.GetAll(ctx, pattern, emptyRecordFunc)
+	s.observe(config.StoreOpGetAll, start, err)
+	return items, err
+}
+
+func (s *InstrumentedStore) Set(ctx context.Context, key string, item interface{}) error {
+	start := time.Now()
+	err := s.Store.Set(ctx, key, item)
+	s.observe(config.StoreOpSet, start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Remove(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.Store.Remove(ctx, key)
+	s.observe(config.StoreOpRemove, start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	start := time.Now()
+	keys, err := s.Store.Keys(ctx, pattern)
+	s.observe(config.StoreOpKeys, start, err)
+	return keys, err
+}
+
+func (s *InstrumentedStore) observe(op string, start time.Time, err error) {
+	status := opStatusOK
+	switch {
+	case err == nil:
+	case errors.Cause(err) == app.DbErrorNotFound:
+		status = opStatusNotFound
+	case errors.Cause(err) == app.DbErrorUnavailable:
+		status = opStatusUnavailable
+	default:
+		status = opStatusError
+	}
+	s.latency.Observe(time.Since(start).Seconds(), op, status)
+}
+
+// RegisterBreaker registers the gauge of the store circuit breaker state
+func RegisterBreaker(registry *Registry, breaker CircuitBreaker) {
+	registry.GaugeFunc("cdmon2_store_circuit_open", "1 while the circuit breaker of the store is open, 0 otherwise.", func() []Sample {
+		if breaker.Open() {
+			return []Sample{{Value: 1}}
+		}
+		return []Sample{{Value: 0}}
+	})
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+// Idle buckets are dropped once they are full again, at most once by sweepInterval
+const sweepInterval = time.Minute
+
+type (
+	// bucket keeps the limit it was last taken with, as the limits change from a route to another
+	bucket struct {
+		tokens float64
+		last   time.Time
+		limit  config.RateLimit
+	}
+
+	// MemoryLimiter keeps the token buckets in the process memory,
+	// so the limits are enforced by each replica on its own.
+	MemoryLimiter struct {
+		sync.Mutex
+		buckets   map[string]*bucket
+		lastSweep time.Time
+		now       func() time.Time
+	}
+)
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key. If there is not any, it returns how long to wait for the next one.
+func (l *MemoryLimiter) Allow(key string, limit config.RateLimit) (bool, time.Duration, error) {
+	if limit.Unlimited() {
+		return true, 0, nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.last), limit)
+	b.last = now
+	b.limit = limit
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, wait(b.tokens, limit), nil
+}
+
+// sweep drops the full buckets, each one judged by its own limit
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if refill(b.tokens, now.Sub(b.last), b.limit) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func refill(tokens float64, elapsed time.Duration, limit config.RateLimit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+}
+
+// wait returns the time until the bucket has a whole token
+func wait(tokens float64, limit config.RateLimit) time.Duration {
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
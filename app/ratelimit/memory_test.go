@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+
+	now := time.Now()
+	limit := config.RateLimit{Rate: 2, Burst: 3}
+
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+
+	// The burst is allowed at once
+	for i := 0; i < limit.Burst; i++ {
+		allowed, _, err := l.Allow("k1", limit)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	// Then the bucket is empty, and the next token comes in 1/rate seconds
+	allowed, retryAfter, err := l.Allow("k1", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Other keys have their own bucket
+	allowed, _, err = l.Allow("k2", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	// The bucket is refilled over time
+	now = now.Add(500 * time.Millisecond)
+	allowed, _, err = l.Allow("k1", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, err = l.Allow("k1", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	// The idle buckets are dropped once they're full
+	now = now.Add(sweepInterval)
+	allowed, _, err = l.Allow("k1", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 1, len(l.buckets))
+}
+
+func TestMemoryLimiter_AllowUnlimited(t *testing.T) {
+	l := NewMemoryLimiter()
+	for i := 0; i < 100; i++ {
+		allowed, _, err := l.Allow("k1", config.RateLimit{})
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	assert.Equal(t, 0, len(l.buckets))
+}
+
+func TestMemoryLimiter_SweepByBucketLimit(t *testing.T) {
+
+	now := time.Now()
+	slow := config.RateLimit{Rate: 0.001, Burst: 2}
+	fast := config.RateLimit{Rate: 100, Burst: 2}
+
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	// The bucket of the slow route is emptied, and it takes far longer than a sweep to fill again
+	for i := 0; i < slow.Burst; i++ {
+		allowed, _, err := l.Allow("slow", slow)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	// A request of the fast route sweeps the buckets, but the slow one isn't full by its own limit, so it's kept
+	now = now.Add(sweepInterval)
+	allowed, _, err := l.Allow("fast", fast)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 2, len(l.buckets))
+
+	allowed, _, err = l.Allow("slow", slow)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+const keyPrefix = "ratelimit:"
+
+// The bucket is refilled and taken in a single script, so the replicas can't race on it.
+// The times are in milliseconds, and the tokens are stored as strings to keep their fraction.
+var takeToken = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter keeps the token buckets in Redis, so the limits hold across replicas.
+// The replicas clocks are expected to be in sync.
+type RedisLimiter struct {
+	client redis.Cmdable
+	now    func() time.Time
+}
+
+func NewRedisLimiter(client redis.Cmdable) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key. If there is not any, it returns how long to wait for the next one.
+func (l *RedisLimiter) Allow(key string, limit config.RateLimit) (bool, time.Duration, error) {
+	if limit.Unlimited() {
+		return true, 0, nil
+	}
+
+	now := l.now().UnixNano() / int64(time.Millisecond)
+	res, err := takeToken.Run(l.client, []string{keyPrefix + key}, limit.Rate, limit.Burst, now).Result()
+	if err != nil {
+		return false, 0, errors.Wrap(err, "rate limit bucket")
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, errors.Errorf("unexpected rate limit script result %v", res)
+	}
+	allowed, _ := values[0].(int64)
+	if allowed == 1 {
+		return true, 0, nil
+	}
+
+	s, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false, 0, errors.Wrap(err, "rate limit bucket")
+	}
+	return false, wait(math.Max(0, tokens), limit), nil
+}
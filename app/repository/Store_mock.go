@@ -4,6 +4,7 @@
 package repository
 
 import (
+	"context"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"sync"
 )
@@ -13,6 +14,7 @@ var (
 	lockStoreMockConnect sync.RWMutex
 	lockStoreMockGet     sync.RWMutex
 	lockStoreMockGetAll  sync.RWMutex
+	lockStoreMockKeys    sync.RWMutex
 	lockStoreMockRemove  sync.RWMutex
 	lockStoreMockSet     sync.RWMutex
 )
@@ -33,16 +35,19 @@ var _ Store = &StoreMock{}
 //             ConnectFunc: func() error {
 // 	               panic("mock out the Connect method")
 //             },
-//             GetFunc: func(key string, item interface{}) (interface{}, error) {
+//             GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 // 	               panic("mock out the Get method")
 //             },
-//             GetAllFunc: func(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+//             GetAllFunc: func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
 // 	               panic("mock out the GetAll method")
 //             },
-//             RemoveFunc: func(key string) error {
+//             KeysFunc: func(ctx context.Context, pattern string) ([]string, error) {
+// 	               panic("mock out the Keys method")
+//             },
+//             RemoveFunc: func(ctx context.Context, key string) error {
 // 	               panic("mock out the Remove method")
 //             },
-//             SetFunc: func(key string, item interface{}) error {
+//             SetFunc: func(ctx context.Context, key string, item interface{}) error {
 // 	               panic("mock out the Set method")
 //             },
 //         }
@@ -59,16 +64,19 @@ type StoreMock struct {
 	ConnectFunc func() error
 
 	// GetFunc mocks the Get method.
-	GetFunc func(key string, item interface{}) (interface{}, error)
+	GetFunc func(ctx context.Context, key string, item interface{}) (interface{}, error)
 
 	// GetAllFunc mocks the GetAll method.
-	GetAllFunc func(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+	GetAllFunc func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+
+	// KeysFunc mocks the Keys method.
+	KeysFunc func(ctx context.Context, pattern string) ([]string, error)
 
 	// RemoveFunc mocks the Remove method.
-	RemoveFunc func(key string) error
+	RemoveFunc func(ctx context.Context, key string) error
 
 	// SetFunc mocks the Set method.
-	SetFunc func(key string, item interface{}) error
+	SetFunc func(ctx context.Context, key string, item interface{}) error
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -80,6 +88,8 @@ type StoreMock struct {
 		}
 		// Get holds details about calls to the Get method.
 		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 			// Item is the item argument value.
@@ -87,18 +97,31 @@ type StoreMock struct {
 		}
 		// GetAll holds details about calls to the GetAll method.
 		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Pattern is the pattern argument value.
 			Pattern string
 			// EmptyRecordFunc is the emptyRecordFunc argument value.
 			EmptyRecordFunc config.EmptyRecordFunc
 		}
+		// Keys holds details about calls to the Keys method.
+		Keys []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Pattern is the pattern argument value.
+			Pattern string
+		}
 		// Remove holds details about calls to the Remove method.
 		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 		}
 		// Set holds details about calls to the Set method.
 		Set []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Key is the key argument value.
 			Key string
 			// Item is the item argument value.
@@ -160,31 +183,35 @@ func (mock *StoreMock) ConnectCalls() []struct {
 }
 
 // Get calls GetFunc.
-func (mock *StoreMock) Get(key string, item interface{}) (interface{}, error) {
+func (mock *StoreMock) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
 	if mock.GetFunc == nil {
 		panic("StoreMock.GetFunc: method is nil but Store.Get was just called")
 	}
 	callInfo := struct {
+		Ctx  context.Context
 		Key  string
 		Item interface{}
 	}{
+		Ctx:  ctx,
 		Key:  key,
 		Item: item,
 	}
 	lockStoreMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockStoreMockGet.Unlock()
-	return mock.GetFunc(key, item)
+	return mock.GetFunc(ctx, key, item)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
 //     len(mockedStore.GetCalls())
 func (mock *StoreMock) GetCalls() []struct {
+	Ctx  context.Context
 	Key  string
 	Item interface{}
 } {
 	var calls []struct {
+		Ctx  context.Context
 		Key  string
 		Item interface{}
 	}
@@ -195,31 +222,35 @@ func (mock *StoreMock) GetCalls() []struct {
 }
 
 // GetAll calls GetAllFunc.
-func (mock *StoreMock) GetAll(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+func (mock *StoreMock) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
 	if mock.GetAllFunc == nil {
 		panic("StoreMock.GetAllFunc: method is nil but Store.GetAll was just called")
 	}
 	callInfo := struct {
+		Ctx             context.Context
 		Pattern         string
 		EmptyRecordFunc config.EmptyRecordFunc
 	}{
+		Ctx:             ctx,
 		Pattern:         pattern,
 		EmptyRecordFunc: emptyRecordFunc,
 	}
 	lockStoreMockGetAll.Lock()
 	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
 	lockStoreMockGetAll.Unlock()
-	return mock.GetAllFunc(pattern, emptyRecordFunc)
+	return mock.GetAllFunc(ctx, pattern, emptyRecordFunc)
 }
 
 // GetAllCalls gets all the calls that were made to GetAll.
 // Check the length with:
 //     len(mockedStore.GetAllCalls())
 func (mock *StoreMock) GetAllCalls() []struct {
+	Ctx             context.Context
 	Pattern         string
 	EmptyRecordFunc config.EmptyRecordFunc
 } {
 	var calls []struct {
+		Ctx             context.Context
 		Pattern         string
 		EmptyRecordFunc config.EmptyRecordFunc
 	}
@@ -229,29 +260,68 @@ func (mock *StoreMock) GetAllCalls() []struct {
 	return calls
 }
 
+// Keys calls KeysFunc.
+func (mock *StoreMock) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if mock.KeysFunc == nil {
+		panic("StoreMock.KeysFunc: method is nil but Store.Keys was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Pattern string
+	}{
+		Ctx:     ctx,
+		Pattern: pattern,
+	}
+	lockStoreMockKeys.Lock()
+	mock.calls.Keys = append(mock.calls.Keys, callInfo)
+	lockStoreMockKeys.Unlock()
+	return mock.KeysFunc(ctx, pattern)
+}
+
+// KeysCalls gets all the calls that were made to Keys.
+// Check the length with:
+//     len(mockedStore.KeysCalls())
+func (mock *StoreMock) KeysCalls() []struct {
+	Ctx     context.Context
+	Pattern string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Pattern string
+	}
+	lockStoreMockKeys.RLock()
+	calls = mock.calls.Keys
+	lockStoreMockKeys.RUnlock()
+	return calls
+}
+
 // Remove calls RemoveFunc.
-func (mock *StoreMock) Remove(key string) error {
+func (mock *StoreMock) Remove(ctx context.Context, key string) error {
 	if mock.RemoveFunc == nil {
 		panic("StoreMock.RemoveFunc: method is nil but Store.Remove was just called")
 	}
 	callInfo := struct {
+		Ctx context.Context
 		Key string
 	}{
+		Ctx: ctx,
 		Key: key,
 	}
 	lockStoreMockRemove.Lock()
 	mock.calls.Remove = append(mock.calls.Remove, callInfo)
 	lockStoreMockRemove.Unlock()
-	return mock.RemoveFunc(key)
+	return mock.RemoveFunc(ctx, key)
 }
 
 // RemoveCalls gets all the calls that were made to Remove.
 // Check the length with:
 //     len(mockedStore.RemoveCalls())
 func (mock *StoreMock) RemoveCalls() []struct {
+	Ctx context.Context
 	Key string
 } {
 	var calls []struct {
+		Ctx context.Context
 		Key string
 	}
 	lockStoreMockRemove.RLock()
@@ -261,31 +331,35 @@ func (mock *StoreMock) RemoveCalls() []struct {
 }
 
 // Set calls SetFunc.
-func (mock *StoreMock) Set(key string, item interface{}) error {
+func (mock *StoreMock) Set(ctx context.Context, key string, item interface{}) error {
 	if mock.SetFunc == nil {
 		panic("StoreMock.SetFunc: method is nil but Store.Set was just called")
 	}
 	callInfo := struct {
+		Ctx  context.Context
 		Key  string
 		Item interface{}
 	}{
+		Ctx:  ctx,
 		Key:  key,
 		Item: item,
 	}
 	lockStoreMockSet.Lock()
 	mock.calls.Set = append(mock.calls.Set, callInfo)
 	lockStoreMockSet.Unlock()
-	return mock.SetFunc(key, item)
+	return mock.SetFunc(ctx, key, item)
 }
 
 // SetCalls gets all the calls that were made to Set.
 // Check the length with:
 //     len(mockedStore.SetCalls())
 func (mock *StoreMock) SetCalls() []struct {
+	Ctx  context.Context
 	Key  string
 	Item interface{}
 } {
 	var calls []struct {
+		Ctx  context.Context
 		Key  string
 		Item interface{}
 	}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+// API keys are stored by their hash, which is what a request brings to be authenticated
+const apiKeyKeyPrefix = "apikey:"
+
+type (
+	APIKeyRepositoryMap struct {
+		sync.Mutex
+		cfg   *config.Config
+		store Store
+	}
+)
+
+func NewAPIKeyRepositoryMap(cfg *config.Config, store Store) *APIKeyRepositoryMap {
+	return &APIKeyRepositoryMap{
+		Mutex: sync.Mutex{},
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+func (a *APIKeyRepositoryMap) Get(ctx context.Context, hash string) (*domain.APIKey, error) {
+	item, err := a.store.Get(ctx, apiKeyKeyPrefix+hash, &domain.APIKey{})
+	if err != nil {
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			return nil, errors.Wrap(err, "api key")
+		default:
+			return nil, err
+		}
+	}
+	apiKey := item.(*domain.APIKey)
+	apiKey.Hash = hash
+	return apiKey, nil
+}
+
+// GetAll returns the API keys. Their hash is taken from their key, as the records
+// written with the JSON codec don't have it.
+func (a *APIKeyRepositoryMap) GetAll(ctx context.Context) ([]domain.APIKey, error) {
+	keys, err := a.store.Keys(ctx, apiKeyKeyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]domain.APIKey, 0, len(keys))
+	for _, k := range keys {
+		apiKey, err := a.Get(ctx, strings.TrimPrefix(k, apiKeyKeyPrefix))
+		switch errors.Cause(err) {
+		case nil:
+		case app.DbErrorNotFound:
+			// Removed since the keys were listed
+			continue
+		default:
+			return nil, err
+		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
+	return apiKeys, nil
+}
+
+func (a *APIKeyRepositoryMap) Insert(ctx context.Context, apiKey *domain.APIKey) error {
+	_, err := a.store.Get(ctx, apiKeyKeyPrefix+apiKey.Hash, &domain.APIKey{})
+	switch errors.Cause(err) {
+	case nil:
+		return errors.Wrapf(app.DbErrorAlreadyExist, "api key: %s", apiKey.Name)
+	case app.DbErrorNotFound:
+	default:
+		return err
+	}
+
+	err = apiKey.Validate()
+	if err != nil {
+		return err
+	}
+
+	return a.store.Set(ctx, apiKeyKeyPrefix+apiKey.Hash, *apiKey)
+}
+
+func (a *APIKeyRepositoryMap) Remove(ctx context.Context, uuid domain.UUID) (*domain.APIKey, error) {
+	apiKeys, err := a.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range apiKeys {
+		if k.UUID == uuid {
+			return &k, a.store.Remove(ctx, apiKeyKeyPrefix+k.Hash)
+		}
+	}
+	return nil, errors.Wrapf(app.DbErrorNotFound, "api key uuid: %s", string(uuid))
+}
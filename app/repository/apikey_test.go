@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/store"
+)
+
+func TestAPIKeyRepositoryMap_Hash(t *testing.T) {
+	for _, codec := range []store.Codec{store.GobCodec{}, store.JSONCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx := context.Background()
+			a := NewAPIKeyRepositoryMap(populateConfig(), store.NewMemoryStoreWithCodec(codec))
+			apiKey, _, err := domain.NewAPIKey("k1", domain.RoleOperator, "customer1")
+			require.NoError(t, err)
+			require.NoError(t, a.Insert(ctx, apiKey))
+
+			// The hash is kept whatever the codec, as it's taken from the key of the record
+			got, err := a.Get(ctx, apiKey.Hash)
+			require.NoError(t, err)
+			assert.Equal(t, apiKey, got)
+			all, err := a.GetAll(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []domain.APIKey{*apiKey}, all)
+
+			removed, err := a.Remove(ctx, apiKey.UUID)
+			require.NoError(t, err)
+			assert.Equal(t, apiKey, removed)
+			all, err = a.GetAll(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, all)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/uow"
+)
+
+const (
+	customerKeyPrefix     = "customer:"
+	customerNameKeyPrefix = "customer-name:"
+)
+
+type (
+	CustomerRepositoryMap struct {
+		sync.Mutex
+		cfg   *config.Config
+		store Store
+	}
+)
+
+func NewCustomerRepositoryMap(cfg *config.Config, store Store) *CustomerRepositoryMap {
+	return &CustomerRepositoryMap{
+		Mutex: sync.Mutex{},
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+func (c *CustomerRepositoryMap) Get(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+	item, err := c.store.Get(ctx, customerKeyPrefix+string(uuid), &domain.Customer{})
+	if err != nil {
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			return nil, errors.Wrapf(err, "customer uuid: %s", string(uuid))
+		default:
+			return nil, err
+		}
+	}
+	return item.(*domain.Customer), nil
+}
+
+func (c *CustomerRepositoryMap) GetAll(ctx context.Context) ([]domain.Customer, error) {
+
+	var emptyRecordFunc config.EmptyRecordFunc = func() interface{} {
+		return &domain.Customer{}
+	}
+	slice, err := c.store.GetAll(ctx, customerKeyPrefix+"*", emptyRecordFunc)
+	if err != nil {
+		return nil, err
+	}
+	customers := make([]domain.Customer, len(slice))
+	for z, v := range slice {
+		customers[z] = *v.(*domain.Customer)
+	}
+	return customers, nil
+}
+
+func (c *CustomerRepositoryMap) Insert(ctx context.Context, customer *domain.Customer) error {
+	// Check if already exists a customer with the same UUID
+	_, err := c.store.Get(ctx, customerKeyPrefix+string(customer.UUID), &domain.Customer{})
+	switch errors.Cause(err) {
+	case nil:
+		return errors.Wrapf(app.DbErrorAlreadyExist, "customer uuid: %s", string(customer.UUID))
+	case app.DbErrorNotFound:
+	default:
+		return err
+	}
+
+	// Check if already exists a customer with the same name
+	err = c.checkName(ctx, customer.Name)
+	if err != nil {
+		return err
+	}
+
+	err = customer.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Persist the new customer
+	work := uow.New(ctx)
+	err = work.Try(ctx, c.set(customer), c.remove(customer))
+	if err == nil {
+		err = work.Try(ctx, c.setName(customer.Name), c.removeName(customer.Name))
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
+	return nil
+}
+
+func (c *CustomerRepositoryMap) Update(ctx context.Context, customer *domain.Customer) error {
+	old, err := c.Get(ctx, customer.UUID)
+	if err != nil {
+		return err
+	}
+	if old.Name != customer.Name {
+		err = c.checkName(ctx, customer.Name)
+		if err != nil {
+			return err
+		}
+	}
+	err = customer.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Persist the new customer status
+	work := uow.New(ctx)
+	err = work.Try(ctx, c.set(customer), c.set(old))
+	if err == nil && old.Name != customer.Name {
+		err = work.Try(ctx, c.setName(customer.Name), c.removeName(customer.Name))
+		if err == nil {
+			err = work.Try(ctx, c.removeName(old.Name), c.setName(old.Name))
+		}
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
+	return nil
+}
+
+func (c *CustomerRepositoryMap) Remove(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+	customer, err := c.Get(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	work := uow.New(ctx)
+	err = work.Try(ctx, c.remove(customer), c.set(customer))
+	if err == nil {
+		err = work.Try(ctx, c.removeName(customer.Name), c.setName(customer.Name))
+	}
+	if err != nil {
+		return nil, work.Abort(err)
+	}
+	work.Commit()
+	return customer, nil
+}
+
+func (c *CustomerRepositoryMap) checkName(ctx context.Context, name string) error {
+	var s string
+	_, err := c.store.Get(ctx, customerNameKeyPrefix+name, &s)
+	switch errors.Cause(err) {
+	case nil:
+		return errors.Wrapf(app.DbErrorAlreadyExist, "customer name: %s", name)
+	case app.DbErrorNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+func (c *CustomerRepositoryMap) set(customer *domain.Customer) uow.Action {
+	item := *customer
+	return func(ctx context.Context) error {
+		return c.store.Set(ctx, customerKeyPrefix+string(item.UUID), item)
+	}
+}
+
+func (c *CustomerRepositoryMap) remove(customer *domain.Customer) uow.Action {
+	return func(ctx context.Context) error {
+		return c.store.Remove(ctx, customerKeyPrefix+string(customer.UUID))
+	}
+}
+
+func (c *CustomerRepositoryMap) setName(name string) uow.Action {
+	return func(ctx context.Context) error {
+		return c.store.Set(ctx, customerNameKeyPrefix+name, "0")
+	}
+}
+
+func (c *CustomerRepositoryMap) removeName(name string) uow.Action {
+	return func(ctx context.Context) error {
+		return c.store.Remove(ctx, customerNameKeyPrefix+name)
+	}
+}
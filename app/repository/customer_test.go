@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestCustomerRepositoryMap_Get(t *testing.T) {
+
+	type args struct {
+		uuid domain.UUID
+	}
+	tests := []struct {
+		name    string
+		store   *StoreMock
+		args    args
+		want    *domain.Customer
+		wantErr bool
+	}{
+		{
+			name: "given repository, when an existing customer is required, then it's returned",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					return &domain.Customer{UUID: "customer1", Name: "c1", Quota: domain.Quota{Cores: 1}}, nil
+				},
+			},
+			args:    args{uuid: "customer1"},
+			want:    &domain.Customer{UUID: "customer1", Name: "c1", Quota: domain.Quota{Cores: 1}},
+			wantErr: false,
+		},
+		{
+			name: "given repository, when a not existing customer is required, then it fails",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					return nil, app.DbErrorNotFound
+				},
+			},
+			args:    args{uuid: "customer111"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CustomerRepositoryMap{
+				store: tt.store,
+			}
+			got, err := c.Get(context.Background(), tt.args.uuid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CustomerRepositoryMap.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CustomerRepositoryMap.Get() = %v, want %v", got, tt.want)
+			}
+			assert.Equal(t, customerKeyPrefix+string(tt.args.uuid), tt.store.GetCalls()[0].Key)
+		})
+	}
+}
+
+func TestCustomerRepositoryMap_Insert(t *testing.T) {
+
+	cfg := populateConfig()
+
+	tests := []struct {
+		name     string
+		store    *StoreMock
+		customer *domain.Customer
+		wantErr  bool
+	}{
+		{
+			name: "given a repository, when a new customer is inserted, then all works fine",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					return nil, app.DbErrorNotFound
+				},
+				SetFunc: func(ctx context.Context, key string, item interface{}) error {
+					return nil
+				},
+			},
+			customer: &domain.Customer{UUID: "customer1", Name: "c1"},
+			wantErr:  false,
+		},
+		{
+			name: "given a repository, when a customer with an existing name is inserted, then it fails",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					if key == customerNameKeyPrefix+"c1" {
+						return "0", nil
+					}
+					return nil, app.DbErrorNotFound
+				},
+			},
+			customer: &domain.Customer{UUID: "customer1", Name: "c1"},
+			wantErr:  true,
+		},
+	}
+	for z, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CustomerRepositoryMap{
+				cfg:   cfg,
+				store: tt.store,
+			}
+			err := c.Insert(context.Background(), tt.customer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CustomerRepositoryMap.Insert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			switch z {
+			case 0:
+				assert.Equal(t, 2, len(tt.store.SetCalls()))
+			case 1:
+				assert.Equal(t, app.DbErrorAlreadyExist, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestCustomerRepositoryMap_GetAll(t *testing.T) {
+	store := &StoreMock{
+		GetAllFunc: func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+			return []interface{}{
+				&domain.Customer{UUID: "customer1", Name: "c1"},
+				&domain.Customer{UUID: "customer2", Name: "c2"},
+			}, nil
+		},
+	}
+	c := CustomerRepositoryMap{store: store}
+
+	got, err := c.GetAll(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(got))
+	assert.Equal(t, customerKeyPrefix+"*", store.GetAllCalls()[0].Pattern)
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -8,16 +10,23 @@ import (
 	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/uow"
+)
+
+const (
+	hostingKeyPrefix     = "hosting:"
+	hostingNameKeyPrefix = "hosting-name:"
 )
 
 type (
 	Store interface {
 		Connect() error
 		Close() error
-		Get(key string, item interface{}) (interface{}, error)
-		GetAll(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
-		Set(key string, item interface{}) error
-		Remove(key string) error
+		Get(ctx context.Context, key string, item interface{}) (interface{}, error)
+		GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+		Set(ctx context.Context, key string, item interface{}) error
+		Remove(ctx context.Context, key string) error
+		Keys(ctx context.Context, pattern string) ([]string, error)
 	}
 
 	HostingRepostitoryMap struct {
@@ -35,13 +44,13 @@ func NewHostingReposytoryMap(cfg *config.Config, store Store) *HostingRepostitor
 	}
 }
 
-func (h HostingRepostitoryMap) Get(uuid domain.UUID) (*domain.Hosting, error) {
+func (h *HostingRepostitoryMap) Get(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 	var (
 		err  error
 		item interface{}
 	)
 
-	item, err = h.store.Get(string(uuid), &domain.Hosting{})
+	item, err = h.store.Get(ctx, hostingKeyPrefix+string(uuid), &domain.Hosting{})
 	if err != nil {
 		switch errors.Cause(err) {
 		case app.DbErrorNotFound:
@@ -53,12 +62,12 @@ func (h HostingRepostitoryMap) Get(uuid domain.UUID) (*domain.Hosting, error) {
 	return item.(*domain.Hosting), nil
 }
 
-func (h HostingRepostitoryMap) GetAll() ([]domain.Hosting, error) {
+func (h *HostingRepostitoryMap) GetAll(ctx context.Context) ([]domain.Hosting, error) {
 
 	var emptyRecordFunc config.EmptyRecordFunc = func() interface{} {
 		return &domain.Hosting{}
 	}
-	slice, err := h.store.GetAll("*-*", emptyRecordFunc)
+	slice, err := h.store.GetAll(ctx, hostingKeyPrefix+"*", emptyRecordFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +78,9 @@ func (h HostingRepostitoryMap) GetAll() ([]domain.Hosting, error) {
 	return hostings, nil
 }
 
-func (h HostingRepostitoryMap) Insert(hosting *domain.Hosting) error {
+func (h *HostingRepostitoryMap) Insert(ctx context.Context, hosting *domain.Hosting) error {
 	// Check if already exists an hosting with the same UUID
-	_, err := h.store.Get(string(hosting.UUID), hosting)
+	_, err := h.store.Get(ctx, hostingKeyPrefix+string(hosting.UUID), hosting)
 	switch errors.Cause(err) {
 	case nil:
 		return errors.Wrapf(app.DbErrorAlreadyExist, "uuid: %s", string(hosting.UUID))
@@ -82,7 +91,7 @@ func (h HostingRepostitoryMap) Insert(hosting *domain.Hosting) error {
 
 	// Check if already exists an hosting with the same name
 	var s string
-	_, err = h.store.Get(hosting.Name, &s)
+	_, err = h.store.Get(ctx, hostingNameKeyPrefix+hosting.Name, &s)
 	switch errors.Cause(err) {
 	case nil:
 		return errors.Wrapf(app.DbErrorAlreadyExist, "name: %s", hosting.Name)
@@ -97,20 +106,27 @@ func (h HostingRepostitoryMap) Insert(hosting *domain.Hosting) error {
 	}
 
 	// Persist the new hosting
-	h.store.Set(string(hosting.UUID), *hosting)
-	h.store.Set(hosting.Name, "0")
+	work := uow.New(ctx)
+	err = work.Try(ctx, h.set(hosting), h.remove(hosting))
+	if err == nil {
+		err = work.Try(ctx, h.setName(hosting.Name, hosting.UUID), h.removeName(hosting.Name))
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
 	return nil
 }
 
-func (h HostingRepostitoryMap) Update(hosting *domain.Hosting) error {
-	old, err := h.Get(hosting.UUID)
+func (h *HostingRepostitoryMap) Update(ctx context.Context, hosting *domain.Hosting) error {
+	old, err := h.Get(ctx, hosting.UUID)
 	if err != nil {
 		return err
 	}
 	if old.Name != hosting.Name {
 		// Check for a already existing name
 		var s string
-		_, err = h.store.Get(hosting.Name, &s)
+		_, err = h.store.Get(ctx, hostingNameKeyPrefix+hosting.Name, &s)
 		switch errors.Cause(err) {
 		case nil:
 			return errors.Wrapf(app.DbErrorAlreadyExist, "name: %s", hosting.Name)
@@ -125,17 +141,95 @@ func (h HostingRepostitoryMap) Update(hosting *domain.Hosting) error {
 	}
 
 	// Persist the new hosting status
-	h.store.Set(string(hosting.UUID), *hosting)
-	h.store.Set(hosting.Name, "0")
+	work := uow.New(ctx)
+	err = work.Try(ctx, h.set(hosting), h.set(old))
+	if err == nil && old.Name != hosting.Name {
+		err = work.Try(ctx, h.setName(hosting.Name, hosting.UUID), h.removeName(hosting.Name))
+		if err == nil {
+			err = work.Try(ctx, h.removeName(old.Name), h.setName(old.Name, old.UUID))
+		}
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
 	return nil
 }
 
-func (h HostingRepostitoryMap) Remove(uuid domain.UUID) (*domain.Hosting, error) {
-	hosting, err := h.Get(uuid)
+func (h *HostingRepostitoryMap) Remove(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
+	hosting, err := h.Get(ctx, uuid)
 	if err != nil {
 		return nil, err
 	}
-	h.store.Remove(string(uuid))
-	h.store.Remove(hosting.Name)
+
+	work := uow.New(ctx)
+	err = work.Try(ctx, h.remove(hosting), h.set(hosting))
+	if err == nil {
+		err = work.Try(ctx, h.removeName(hosting.Name), h.setName(hosting.Name, hosting.UUID))
+	}
+	if err != nil {
+		return nil, work.Abort(err)
+	}
+	work.Commit()
 	return hosting, nil
 }
+
+func (h *HostingRepostitoryMap) set(hosting *domain.Hosting) uow.Action {
+	item := *hosting
+	return func(ctx context.Context) error {
+		return h.store.Set(ctx, hostingKeyPrefix+string(item.UUID), item)
+	}
+}
+
+func (h *HostingRepostitoryMap) remove(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return h.store.Remove(ctx, hostingKeyPrefix+string(hosting.UUID))
+	}
+}
+
+// NameIndex returns the entries of the names index, with the UUID of the hosting each name points to.
+// The entries written before they pointed to their hosting hold "0" instead.
+func (h *HostingRepostitoryMap) NameIndex(ctx context.Context) (map[string]domain.UUID, error) {
+	keys, err := h.store.Keys(ctx, hostingNameKeyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]domain.UUID, len(keys))
+	for _, k := range keys {
+		var uuid string
+		_, err := h.store.Get(ctx, k, &uuid)
+		switch errors.Cause(err) {
+		case nil:
+		case app.DbErrorNotFound:
+			// Removed since the keys were listed
+			continue
+		default:
+			return nil, err
+		}
+		index[strings.TrimPrefix(k, hostingNameKeyPrefix)] = domain.UUID(uuid)
+	}
+	return index, nil
+}
+
+// SetName points the name to the hosting in the names index
+func (h *HostingRepostitoryMap) SetName(ctx context.Context, name string, uuid domain.UUID) error {
+	return h.setName(name, uuid)(ctx)
+}
+
+// RemoveName removes the name from the names index
+func (h *HostingRepostitoryMap) RemoveName(ctx context.Context, name string) error {
+	return h.removeName(name)(ctx)
+}
+
+func (h *HostingRepostitoryMap) setName(name string, uuid domain.UUID) uow.Action {
+	return func(ctx context.Context) error {
+		return h.store.Set(ctx, hostingNameKeyPrefix+name, string(uuid))
+	}
+}
+
+func (h *HostingRepostitoryMap) removeName(name string) uow.Action {
+	return func(ctx context.Context) error {
+		return h.store.Remove(ctx, hostingNameKeyPrefix+name)
+	}
+}
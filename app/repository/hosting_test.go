@@ -1,9 +1,9 @@
 package repository
 
 import (
+	"context"
 	"reflect"
 	"sort"
-	"sync"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -33,7 +33,6 @@ func populateConfig() *config.Config {
 func TestHostingRepostitoryMap_Get(t *testing.T) {
 
 	type fields struct {
-		Mutex sync.Mutex
 		store *StoreMock
 	}
 	type args struct {
@@ -50,7 +49,7 @@ func TestHostingRepostitoryMap_Get(t *testing.T) {
 			name: "given repository, when an existing hosting is required, then it's returned",
 			fields: fields{
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return &domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1}, nil
 					},
 				},
@@ -63,7 +62,7 @@ func TestHostingRepostitoryMap_Get(t *testing.T) {
 			name: "given repository, when a not existing hosting is required, then it fails",
 			fields: fields{
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorNotFound
 					},
 				},
@@ -78,7 +77,7 @@ func TestHostingRepostitoryMap_Get(t *testing.T) {
 			h := HostingRepostitoryMap{
 				store: tt.fields.store,
 			}
-			got, err := h.Get(tt.args.uuid)
+			got, err := h.Get(context.Background(), tt.args.uuid)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("HostingRepostitoryMap.Get() error = %v, wantErr %v", err, tt.wantErr)
@@ -112,7 +111,7 @@ func TestHostingRepostitoryMap_GetAll(t *testing.T) {
 			name: "given a repository, when the list of hostins is required, then it's returned",
 			fields: fields{
 				store: &StoreMock{
-					GetAllFunc: func(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+					GetAllFunc: func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
 						return []interface{}{
 							&domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1},
 							&domain.Hosting{UUID: "uuid2", Name: "h2", Cores: 1, MemoryMb: 1, DiskMb: 1},
@@ -130,7 +129,7 @@ func TestHostingRepostitoryMap_GetAll(t *testing.T) {
 			h := HostingRepostitoryMap{
 				store: tt.fields.store,
 			}
-			got, err := h.GetAll()
+			got, err := h.GetAll(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("HostingRepostitoryMap.GetAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -153,7 +152,6 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 	cfg := populateConfig()
 
 	type fields struct {
-		Mutex sync.Mutex
 		cfg   *config.Config
 		store *StoreMock
 	}
@@ -169,13 +167,12 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 		{
 			name: "given a populated repository, when a new hosting is inserted then all works fine",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorNotFound
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -188,13 +185,12 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting with a existing UUID is tried to be inserted, then if fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorAlreadyExist
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -207,13 +203,12 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting with a existing Name is tried to be inserted, then if fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorAlreadyExist
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -228,11 +223,10 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 		var err error
 		t.Run(tt.name, func(t *testing.T) {
 			h := HostingRepostitoryMap{
-				Mutex: tt.fields.Mutex,
 				cfg:   tt.fields.cfg,
 				store: tt.fields.store,
 			}
-			if err = h.Insert(tt.args.hosting); (err != nil) != tt.wantErr {
+			if err = h.Insert(context.Background(), tt.args.hosting); (err != nil) != tt.wantErr {
 				t.Errorf("HostingRepostitoryMap.Insert() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -249,11 +243,39 @@ func TestHostingRepostitoryMap_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestHostingRepostitoryMap_InsertStoreFailure(t *testing.T) {
+	errStore := errors.New("store failure")
+	store := &StoreMock{
+		GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+			return nil, app.DbErrorNotFound
+		},
+		SetFunc: func(ctx context.Context, key string, item interface{}) error {
+			if key == hostingNameKeyPrefix+"h1" {
+				return errStore
+			}
+			return nil
+		},
+		RemoveFunc: func(ctx context.Context, key string) error {
+			return nil
+		},
+	}
+	h := HostingRepostitoryMap{cfg: populateConfig(), store: store}
+
+	// The failure is returned, and both writes are undone, as the failed one could have been applied anyway
+	err := h.Insert(context.Background(), &domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1})
+	assert.Equal(t, errStore, errors.Cause(err))
+	assert.Equal(t, 2, len(store.SetCalls()))
+	if assert.Equal(t, 2, len(store.RemoveCalls())) {
+		assert.Equal(t, hostingNameKeyPrefix+"h1", store.RemoveCalls()[0].Key)
+		assert.Equal(t, hostingKeyPrefix+"uuid1", store.RemoveCalls()[1].Key)
+	}
+}
+
 func TestHostingRepostitoryMap_Update(t *testing.T) {
 	cfg := populateConfig()
 
 	type fields struct {
-		Mutex sync.Mutex
 		cfg   *config.Config
 		store *StoreMock
 	}
@@ -269,13 +291,12 @@ func TestHostingRepostitoryMap_Update(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting is updated then all works fine",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return &domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 22, MemoryMb: 1, DiskMb: 1}, nil
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -288,13 +309,12 @@ func TestHostingRepostitoryMap_Update(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting with a not existing UUID is tried to be updated, then if fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorNotFound
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -307,16 +327,15 @@ func TestHostingRepostitoryMap_Update(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting with a existing Name is tried to be updated, then if fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
-						if key == "h2" {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+						if key == hostingNameKeyPrefix+"h2" {
 							return "0", nil
 						}
 						return &domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1}, nil
 					},
-					SetFunc: func(key string, item interface{}) error {
+					SetFunc: func(ctx context.Context, key string, item interface{}) error {
 						return nil
 					},
 				},
@@ -331,11 +350,10 @@ func TestHostingRepostitoryMap_Update(t *testing.T) {
 		var err error
 		t.Run(tt.name, func(t *testing.T) {
 			h := HostingRepostitoryMap{
-				Mutex: tt.fields.Mutex,
 				cfg:   tt.fields.cfg,
 				store: tt.fields.store,
 			}
-			if err = h.Update(tt.args.hosting); (err != nil) != tt.wantErr {
+			if err = h.Update(context.Background(), tt.args.hosting); (err != nil) != tt.wantErr {
 				t.Errorf("HostingRepostitoryMap.Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -367,7 +385,6 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 	cfg := populateConfig()
 
 	type fields struct {
-		Mutex sync.Mutex
 		cfg   *config.Config
 		store *StoreMock
 	}
@@ -384,13 +401,12 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting is removed then all works fine",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return &domain.Hosting{UUID: "uuid1", Name: "h1", Cores: 22, MemoryMb: 1, DiskMb: 1}, nil
 					},
-					RemoveFunc: func(key string) error {
+					RemoveFunc: func(ctx context.Context, key string) error {
 						return nil
 					},
 				},
@@ -404,13 +420,12 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 		{
 			name: "given a populated repository, when a hosting with not existing UUID is tried to be removed, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				cfg:   cfg,
+				cfg: cfg,
 				store: &StoreMock{
-					GetFunc: func(key string, item interface{}) (interface{}, error) {
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
 						return nil, app.DbErrorNotFound
 					},
-					RemoveFunc: func(key string) error {
+					RemoveFunc: func(ctx context.Context, key string) error {
 						return nil
 					},
 				},
@@ -429,11 +444,10 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 		)
 		t.Run(tt.name, func(t *testing.T) {
 			h := HostingRepostitoryMap{
-				Mutex: tt.fields.Mutex,
 				cfg:   tt.fields.cfg,
 				store: tt.fields.store,
 			}
-			got, err = h.Remove(tt.args.uuid)
+			got, err = h.Remove(context.Background(), tt.args.uuid)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("HostingRepostitoryMap.Remove() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -448,7 +462,7 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 				case service.DbErrorAlreadyExist:
 					t.Fail()
 				case service.DbErrorNotFound:
-					t.Logf(err.Error())
+					t.Log(err.Error())
 				default:
 					t.Fail()
 				}
@@ -456,3 +470,58 @@ func TestHostingRepostitoryMap_Remove(t *testing.T) {
 		})
 	}
 }
+
+func TestHostingRepostitoryMap_NameIndex(t *testing.T) {
+	stored := map[string]string{"hosting-name:h1": "uuid1", "hosting-name:h2": "0"}
+	tests := []struct {
+		name    string
+		keys    []string
+		want    map[string]domain.UUID
+		wantErr bool
+	}{
+		{
+			name: "given an index with current and older entries, when it's read, then each name points to its stored value",
+			keys: []string{"hosting-name:h1", "hosting-name:h2"},
+			want: map[string]domain.UUID{"h1": "uuid1", "h2": "0"},
+		},
+		{
+			name: "given an entry removed since the keys were listed, when it's read, then it's skipped",
+			keys: []string{"hosting-name:h1", "hosting-name:h3"},
+			want: map[string]domain.UUID{"h1": "uuid1"},
+		},
+		{
+			name:    "given an unavailable store, when it's read, then it fails",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HostingRepostitoryMap{
+				cfg: populateConfig(),
+				store: &StoreMock{
+					KeysFunc: func(ctx context.Context, pattern string) ([]string, error) {
+						if tt.keys == nil {
+							return nil, app.DbErrorUnavailable
+						}
+						return tt.keys, nil
+					},
+					GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+						v, ok := stored[key]
+						if !ok {
+							return nil, app.DbErrorNotFound
+						}
+						*item.(*string) = v
+						return item, nil
+					},
+				},
+			}
+			got, err := h.NameIndex(context.Background())
+			if tt.wantErr {
+				assert.Equal(t, app.DbErrorUnavailable, errors.Cause(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	// Migrator is a store which rewrites its records not written with the current codec and schema of their type
+	Migrator interface {
+		Migrate(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error)
+	}
+
+	// Migrated is the number of records of a kind rewritten by Migrate
+	Migrated struct {
+		Pattern string
+		Count   int
+	}
+)
+
+func emptyName() interface{} {
+	var s string
+	return &s
+}
+
+// The records of every repository, along with their indexes
+var records = []struct {
+	pattern         string
+	emptyRecordFunc config.EmptyRecordFunc
+}{
+	{hostingKeyPrefix + "*", func() interface{} { return &domain.Hosting{} }},
+	{hostingNameKeyPrefix + "*", emptyName},
+	{customerKeyPrefix + "*", func() interface{} { return &domain.Customer{} }},
+	{customerNameKeyPrefix + "*", emptyName},
+	{projectKeyPrefix + "*", func() interface{} { return &domain.Project{} }},
+	{projectNameKeyPrefix + "*", emptyName},
+	{apiKeyKeyPrefix + "*", func() interface{} { return &domain.APIKey{} }},
+}
+
+// Migrate rewrites the outdated records of all the repositories, and returns how many of each kind were rewritten
+func Migrate(ctx context.Context, store Migrator) ([]Migrated, error) {
+	migrated := make([]Migrated, 0, len(records))
+	for _, r := range records {
+		count, err := store.Migrate(ctx, r.pattern, r.emptyRecordFunc)
+		migrated = append(migrated, Migrated{Pattern: r.pattern, Count: count})
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
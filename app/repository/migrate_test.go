@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+type migratorFunc func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error)
+
+func (f migratorFunc) Migrate(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+	return f(ctx, pattern, emptyRecordFunc)
+}
+
+func TestMigrate(t *testing.T) {
+	// Each kind of record is migrated with its own type
+	types := make(map[string]string)
+	migrator := migratorFunc(func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+		types[pattern] = fmt.Sprintf("%T", emptyRecordFunc())
+		return 1, nil
+	})
+	migrated, err := Migrate(context.Background(), migrator)
+	assert.NoError(t, err)
+	assert.Len(t, migrated, 7)
+	assert.Equal(t, map[string]string{
+		"hosting:*":       "*domain.Hosting",
+		"hosting-name:*":  "*string",
+		"customer:*":      "*domain.Customer",
+		"customer-name:*": "*string",
+		"project:*":       "*domain.Project",
+		"project-name:*":  "*string",
+		"apikey:*":        "*domain.APIKey",
+	}, types)
+
+	// A failure stops the migration
+	failure := errors.New("boom")
+	migrated, err = Migrate(context.Background(), migratorFunc(func(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+		return 0, failure
+	}))
+	assert.Equal(t, failure, err)
+	assert.Equal(t, []Migrated{{Pattern: "hosting:*"}}, migrated)
+}
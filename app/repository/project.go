@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/uow"
+)
+
+const (
+	projectKeyPrefix     = "project:"
+	projectNameKeyPrefix = "project-name:"
+)
+
+type (
+	ProjectRepositoryMap struct {
+		sync.Mutex
+		cfg   *config.Config
+		store Store
+	}
+)
+
+func NewProjectRepositoryMap(cfg *config.Config, store Store) *ProjectRepositoryMap {
+	return &ProjectRepositoryMap{
+		Mutex: sync.Mutex{},
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+func (p *ProjectRepositoryMap) Get(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+	item, err := p.store.Get(ctx, projectKeyPrefix+string(uuid), &domain.Project{})
+	if err != nil {
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			return nil, errors.Wrapf(err, "project uuid: %s", string(uuid))
+		default:
+			return nil, err
+		}
+	}
+	return item.(*domain.Project), nil
+}
+
+func (p *ProjectRepositoryMap) GetAll(ctx context.Context) ([]domain.Project, error) {
+
+	var emptyRecordFunc config.EmptyRecordFunc = func() interface{} {
+		return &domain.Project{}
+	}
+	slice, err := p.store.GetAll(ctx, projectKeyPrefix+"*", emptyRecordFunc)
+	if err != nil {
+		return nil, err
+	}
+	projects := make([]domain.Project, len(slice))
+	for z, v := range slice {
+		projects[z] = *v.(*domain.Project)
+	}
+	return projects, nil
+}
+
+func (p *ProjectRepositoryMap) Insert(ctx context.Context, project *domain.Project) error {
+	// Check if already exists a project with the same UUID
+	_, err := p.store.Get(ctx, projectKeyPrefix+string(project.UUID), &domain.Project{})
+	switch errors.Cause(err) {
+	case nil:
+		return errors.Wrapf(app.DbErrorAlreadyExist, "project uuid: %s", string(project.UUID))
+	case app.DbErrorNotFound:
+	default:
+		return err
+	}
+
+	// Check if the customer already has a project with the same name
+	err = p.checkName(ctx, project.Owner, project.Name)
+	if err != nil {
+		return err
+	}
+
+	err = project.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Persist the new project
+	work := uow.New(ctx)
+	err = work.Try(ctx, p.set(project), p.remove(project))
+	if err == nil {
+		err = work.Try(ctx, p.setName(project.Owner, project.Name), p.removeName(project.Owner, project.Name))
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
+	return nil
+}
+
+func (p *ProjectRepositoryMap) Update(ctx context.Context, project *domain.Project) error {
+	old, err := p.Get(ctx, project.UUID)
+	if err != nil {
+		return err
+	}
+	if old.Name != project.Name {
+		err = p.checkName(ctx, project.Owner, project.Name)
+		if err != nil {
+			return err
+		}
+	}
+	err = project.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Persist the new project status
+	work := uow.New(ctx)
+	err = work.Try(ctx, p.set(project), p.set(old))
+	if err == nil && projectNameKey(old.Owner, old.Name) != projectNameKey(project.Owner, project.Name) {
+		err = work.Try(ctx, p.setName(project.Owner, project.Name), p.removeName(project.Owner, project.Name))
+		if err == nil {
+			err = work.Try(ctx, p.removeName(old.Owner, old.Name), p.setName(old.Owner, old.Name))
+		}
+	}
+	if err != nil {
+		return work.Abort(err)
+	}
+	work.Commit()
+	return nil
+}
+
+func (p *ProjectRepositoryMap) Remove(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+	project, err := p.Get(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	work := uow.New(ctx)
+	err = work.Try(ctx, p.remove(project), p.set(project))
+	if err == nil {
+		err = work.Try(ctx, p.removeName(project.Owner, project.Name), p.setName(project.Owner, project.Name))
+	}
+	if err != nil {
+		return nil, work.Abort(err)
+	}
+	work.Commit()
+	return project, nil
+}
+
+func (p *ProjectRepositoryMap) checkName(ctx context.Context, owner domain.UUID, name string) error {
+	var s string
+	_, err := p.store.Get(ctx, projectNameKey(owner, name), &s)
+	switch errors.Cause(err) {
+	case nil:
+		return errors.Wrapf(app.DbErrorAlreadyExist, "project name: %s", name)
+	case app.DbErrorNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+func (p *ProjectRepositoryMap) set(project *domain.Project) uow.Action {
+	item := *project
+	return func(ctx context.Context) error {
+		return p.store.Set(ctx, projectKeyPrefix+string(item.UUID), item)
+	}
+}
+
+func (p *ProjectRepositoryMap) remove(project *domain.Project) uow.Action {
+	return func(ctx context.Context) error {
+		return p.store.Remove(ctx, projectKeyPrefix+string(project.UUID))
+	}
+}
+
+func (p *ProjectRepositoryMap) setName(owner domain.UUID, name string) uow.Action {
+	return func(ctx context.Context) error {
+		return p.store.Set(ctx, projectNameKey(owner, name), "0")
+	}
+}
+
+func (p *ProjectRepositoryMap) removeName(owner domain.UUID, name string) uow.Action {
+	return func(ctx context.Context) error {
+		return p.store.Remove(ctx, projectNameKey(owner, name))
+	}
+}
+
+// Project names are unique by customer
+func projectNameKey(owner domain.UUID, name string) string {
+	return projectNameKeyPrefix + string(owner) + ":" + name
+}
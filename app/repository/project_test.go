@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestProjectRepositoryMap_Insert(t *testing.T) {
+
+	cfg := populateConfig()
+
+	tests := []struct {
+		name    string
+		store   *StoreMock
+		project *domain.Project
+		wantErr bool
+	}{
+		{
+			name: "given a repository, when a new project is inserted, then all works fine",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					return nil, app.DbErrorNotFound
+				},
+				SetFunc: func(ctx context.Context, key string, item interface{}) error {
+					return nil
+				},
+			},
+			project: &domain.Project{UUID: "project1", Owner: "customer1", Name: "p1"},
+			wantErr: false,
+		},
+		{
+			name: "given a repository, when a project with a name already used by its customer is inserted, then it fails",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					if key == projectNameKeyPrefix+"customer1:p1" {
+						return "0", nil
+					}
+					return nil, app.DbErrorNotFound
+				},
+			},
+			project: &domain.Project{UUID: "project1", Owner: "customer1", Name: "p1"},
+			wantErr: true,
+		},
+		{
+			name: "given a repository, when a project without owner is inserted, then it fails",
+			store: &StoreMock{
+				GetFunc: func(ctx context.Context, key string, item interface{}) (interface{}, error) {
+					return nil, app.DbErrorNotFound
+				},
+			},
+			project: &domain.Project{UUID: "project1", Name: "p1"},
+			wantErr: true,
+		},
+	}
+	for z, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ProjectRepositoryMap{
+				cfg:   cfg,
+				store: tt.store,
+			}
+			err := p.Insert(context.Background(), tt.project)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProjectRepositoryMap.Insert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			switch z {
+			case 0:
+				assert.Equal(t, projectKeyPrefix+"project1", tt.store.SetCalls()[0].Key)
+				assert.Equal(t, projectNameKeyPrefix+"customer1:p1", tt.store.SetCalls()[1].Key)
+			case 1:
+				assert.Equal(t, app.DbErrorAlreadyExist, errors.Cause(err))
+			case 2:
+				assert.Equal(t, 0, len(tt.store.SetCalls()))
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+type (
+	// Loader is a store which writes a batch of records at once, whole or not at all,
+	// and only if it has no key matching the patterns
+	Loader interface {
+		Load(ctx context.Context, patterns []string, items map[string]interface{}) error
+	}
+
+	// RestoreRepositoryMap writes the records of all the repositories, along with their indexes, in a single batch
+	RestoreRepositoryMap struct {
+		cfg   *config.Config
+		store Loader
+	}
+
+	// batch is a set of records by key, which refuses to set a key twice
+	batch map[string]interface{}
+)
+
+func NewRestoreRepositoryMap(cfg *config.Config, store Loader) *RestoreRepositoryMap {
+	return &RestoreRepositoryMap{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// Restore writes the records at once into a store without records of any repository, else it fails with
+// app.DbErrorInUse. The keys of other components, like the rate limit buckets, don't stop it. The records are
+// validated as their repositories do on insert, and the names of the customers, the projects and the hostings
+// are indexed again.
+func (r *RestoreRepositoryMap) Restore(ctx context.Context, customers []domain.Customer, projects []domain.Project, apiKeys []domain.APIKey, hostings []domain.Hosting) error {
+	b := make(batch)
+	for z := range customers {
+		customer := customers[z]
+		err := customer.Validate()
+		if err == nil {
+			err = b.add(customerKeyPrefix+string(customer.UUID), customer)
+		}
+		if err == nil {
+			err = b.add(customerNameKeyPrefix+customer.Name, "0")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for z := range projects {
+		project := projects[z]
+		err := project.Validate()
+		if err == nil {
+			err = b.add(projectKeyPrefix+string(project.UUID), project)
+		}
+		if err == nil {
+			err = b.add(projectNameKey(project.Owner, project.Name), "0")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for z := range apiKeys {
+		apiKey := apiKeys[z]
+		err := apiKey.Validate()
+		if err == nil {
+			err = b.add(apiKeyKeyPrefix+apiKey.Hash, apiKey)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for z := range hostings {
+		hosting := hostings[z]
+		err := hosting.Validate(r.cfg)
+		if err == nil {
+			err = b.add(hostingKeyPrefix+string(hosting.UUID), hosting)
+		}
+		if err == nil {
+			err = b.add(hostingNameKeyPrefix+hosting.Name, string(hosting.UUID))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	patterns := make([]string, len(records))
+	for z, record := range records {
+		patterns[z] = record.pattern
+	}
+	return r.store.Load(ctx, patterns, b)
+}
+
+func (b batch) add(key string, item interface{}) error {
+	if _, ok := b[key]; ok {
+		return errors.Wrapf(app.DbErrorAlreadyExist, "key: %s", key)
+	}
+	b[key] = item
+	return nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/store"
+)
+
+func TestRestoreRepositoryMap_Restore(t *testing.T) {
+	customer, err := domain.NewCustomer("c1", domain.Quota{Cores: 2, MemoryMb: 2, DiskMb: 2})
+	require.NoError(t, err)
+	project, err := domain.NewProject(customer.UUID, "p1", domain.Quota{Cores: 1, MemoryMb: 1, DiskMb: 1})
+	require.NoError(t, err)
+	apiKey, _, err := domain.NewAPIKey("k1", domain.RoleOperator, customer.UUID)
+	require.NoError(t, err)
+	h1, err := domain.NewHosting("h1", 1, 1, 1, customer.UUID, project.UUID)
+	require.NoError(t, err)
+	h2, err := domain.NewHosting("h2", 1, 1, 1, "", "")
+	require.NoError(t, err)
+	twin := *h2
+	twin.UUID = "twin"
+
+	tests := []struct {
+		name     string
+		hostings []domain.Hosting
+		wantErr  error
+	}{
+		{
+			name:     "given valid records, when they're restored, then they're written with their names indexed",
+			hostings: []domain.Hosting{*h1, *h2},
+		},
+		{
+			name:     "given two hostings of the same name, when they're restored, then nothing is written",
+			hostings: []domain.Hosting{*h1, *h2, twin},
+			wantErr:  app.DbErrorAlreadyExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := populateConfig()
+			memory := store.NewMemoryStore()
+			r := NewRestoreRepositoryMap(cfg, memory)
+
+			err := r.Restore(ctx, []domain.Customer{*customer}, []domain.Project{*project}, []domain.APIKey{*apiKey}, tt.hostings)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+
+			keys, err := memory.Keys(ctx, "*")
+			require.NoError(t, err)
+			if tt.wantErr != nil {
+				assert.Empty(t, keys)
+				return
+			}
+			assert.Len(t, keys, 9)
+
+			index, err := NewHostingReposytoryMap(cfg, memory).NameIndex(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]domain.UUID{"h1": h1.UUID, "h2": h2.UUID}, index)
+			got, err := NewAPIKeyRepositoryMap(cfg, memory).Get(ctx, apiKey.Hash)
+			require.NoError(t, err)
+			assert.Equal(t, apiKey.UUID, got.UUID)
+			assert.Equal(t, app.DbErrorAlreadyExist, errors.Cause(NewCustomerRepositoryMap(cfg, memory).checkName(ctx, "c1")))
+		})
+	}
+}
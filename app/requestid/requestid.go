@@ -0,0 +1,58 @@
+package requestid
+
+import (
+	"context"
+
+	gouuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Header carries the request ID from the client, and back in the response
+	Header = "X-Request-ID"
+
+	// Field is the log field of the request ID
+	Field = "request_id"
+
+	maxLength = 128
+)
+
+type key struct{}
+
+// New returns a new random request ID
+func New() string {
+	return gouuid.NewV4().String()
+}
+
+// Valid returns true if the ID given by a client can be used as is.
+// It must be short and printable, so it's safe to be logged.
+func Valid(id string) bool {
+	if len(id) == 0 || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext returns the request ID of the context, or an empty string if it has none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Logger returns a log entry with the request ID of the context, if it has one
+func Logger(log *logrus.Logger, ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(log)
+	if id := FromContext(ctx); len(id) > 0 {
+		entry = entry.WithField(Field, id)
+	}
+	return entry
+}
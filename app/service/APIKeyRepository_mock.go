@@ -0,0 +1,225 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package service
+
+import (
+	"context"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"sync"
+)
+
+var (
+	lockAPIKeyRepositoryMockGet    sync.RWMutex
+	lockAPIKeyRepositoryMockGetAll sync.RWMutex
+	lockAPIKeyRepositoryMockInsert sync.RWMutex
+	lockAPIKeyRepositoryMockRemove sync.RWMutex
+)
+
+// Ensure, that APIKeyRepositoryMock does implement APIKeyRepository.
+// If this is not the case, regenerate this file with moq.
+var _ APIKeyRepository = &APIKeyRepositoryMock{}
+
+// APIKeyRepositoryMock is a mock implementation of APIKeyRepository.
+//
+//     func TestSomethingThatUsesAPIKeyRepository(t *testing.T) {
+//
+//         // make and configure a mocked APIKeyRepository
+//         mockedAPIKeyRepository := &APIKeyRepositoryMock{
+//             GetFunc: func(ctx context.Context, hash string) (*domain.APIKey, error) {
+// 	               panic("mock out the Get method")
+//             },
+//             GetAllFunc: func(ctx context.Context) ([]domain.APIKey, error) {
+// 	               panic("mock out the GetAll method")
+//             },
+//             InsertFunc: func(ctx context.Context, apiKey *domain.APIKey) error {
+// 	               panic("mock out the Insert method")
+//             },
+//             RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.APIKey, error) {
+// 	               panic("mock out the Remove method")
+//             },
+//         }
+//
+//         // use mockedAPIKeyRepository in code that requires APIKeyRepository
+//         // and then make assertions.
+//
+//     }
+type APIKeyRepositoryMock struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, hash string) (*domain.APIKey, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context) ([]domain.APIKey, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, apiKey *domain.APIKey) error
+
+	// RemoveFunc mocks the Remove method.
+	RemoveFunc func(ctx context.Context, uuid domain.UUID) (*domain.APIKey, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ApiKey is the apiKey argument value.
+			ApiKey *domain.APIKey
+		}
+		// Remove holds details about calls to the Remove method.
+		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+	}
+}
+
+// Get calls GetFunc.
+func (mock *APIKeyRepositoryMock) Get(ctx context.Context, hash string) (*domain.APIKey, error) {
+	if mock.GetFunc == nil {
+		panic("APIKeyRepositoryMock.GetFunc: method is nil but APIKeyRepository.Get was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Hash string
+	}{
+		Ctx:  ctx,
+		Hash: hash,
+	}
+	lockAPIKeyRepositoryMockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	lockAPIKeyRepositoryMockGet.Unlock()
+	return mock.GetFunc(ctx, hash)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//     len(mockedAPIKeyRepository.GetCalls())
+func (mock *APIKeyRepositoryMock) GetCalls() []struct {
+	Ctx  context.Context
+	Hash string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Hash string
+	}
+	lockAPIKeyRepositoryMockGet.RLock()
+	calls = mock.calls.Get
+	lockAPIKeyRepositoryMockGet.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *APIKeyRepositoryMock) GetAll(ctx context.Context) ([]domain.APIKey, error) {
+	if mock.GetAllFunc == nil {
+		panic("APIKeyRepositoryMock.GetAllFunc: method is nil but APIKeyRepository.GetAll was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockAPIKeyRepositoryMockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	lockAPIKeyRepositoryMockGetAll.Unlock()
+	return mock.GetAllFunc(ctx)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//     len(mockedAPIKeyRepository.GetAllCalls())
+func (mock *APIKeyRepositoryMock) GetAllCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockAPIKeyRepositoryMockGetAll.RLock()
+	calls = mock.calls.GetAll
+	lockAPIKeyRepositoryMockGetAll.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *APIKeyRepositoryMock) Insert(ctx context.Context, apiKey *domain.APIKey) error {
+	if mock.InsertFunc == nil {
+		panic("APIKeyRepositoryMock.InsertFunc: method is nil but APIKeyRepository.Insert was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		ApiKey *domain.APIKey
+	}{
+		Ctx:    ctx,
+		ApiKey: apiKey,
+	}
+	lockAPIKeyRepositoryMockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	lockAPIKeyRepositoryMockInsert.Unlock()
+	return mock.InsertFunc(ctx, apiKey)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//     len(mockedAPIKeyRepository.InsertCalls())
+func (mock *APIKeyRepositoryMock) InsertCalls() []struct {
+	Ctx    context.Context
+	ApiKey *domain.APIKey
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ApiKey *domain.APIKey
+	}
+	lockAPIKeyRepositoryMockInsert.RLock()
+	calls = mock.calls.Insert
+	lockAPIKeyRepositoryMockInsert.RUnlock()
+	return calls
+}
+
+// Remove calls RemoveFunc.
+func (mock *APIKeyRepositoryMock) Remove(ctx context.Context, uuid domain.UUID) (*domain.APIKey, error) {
+	if mock.RemoveFunc == nil {
+		panic("APIKeyRepositoryMock.RemoveFunc: method is nil but APIKeyRepository.Remove was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		UUID: uuid,
+	}
+	lockAPIKeyRepositoryMockRemove.Lock()
+	mock.calls.Remove = append(mock.calls.Remove, callInfo)
+	lockAPIKeyRepositoryMockRemove.Unlock()
+	return mock.RemoveFunc(ctx, uuid)
+}
+
+// RemoveCalls gets all the calls that were made to Remove.
+// Check the length with:
+//     len(mockedAPIKeyRepository.RemoveCalls())
+func (mock *APIKeyRepositoryMock) RemoveCalls() []struct {
+	Ctx  context.Context
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}
+	lockAPIKeyRepositoryMockRemove.RLock()
+	calls = mock.calls.Remove
+	lockAPIKeyRepositoryMockRemove.RUnlock()
+	return calls
+}
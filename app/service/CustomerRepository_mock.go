@@ -0,0 +1,274 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package service
+
+import (
+	"context"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"sync"
+)
+
+var (
+	lockCustomerRepositoryMockGet    sync.RWMutex
+	lockCustomerRepositoryMockGetAll sync.RWMutex
+	lockCustomerRepositoryMockInsert sync.RWMutex
+	lockCustomerRepositoryMockRemove sync.RWMutex
+	lockCustomerRepositoryMockUpdate sync.RWMutex
+)
+
+// Ensure, that CustomerRepositoryMock does implement CustomerRepository.
+// If this is not the case, regenerate this file with moq.
+var _ CustomerRepository = &CustomerRepositoryMock{}
+
+// CustomerRepositoryMock is a mock implementation of CustomerRepository.
+//
+//     func TestSomethingThatUsesCustomerRepository(t *testing.T) {
+//
+//         // make and configure a mocked CustomerRepository
+//         mockedCustomerRepository := &CustomerRepositoryMock{
+//             GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+// 	               panic("mock out the Get method")
+//             },
+//             GetAllFunc: func(ctx context.Context) ([]domain.Customer, error) {
+// 	               panic("mock out the GetAll method")
+//             },
+//             InsertFunc: func(ctx context.Context, customer *domain.Customer) error {
+// 	               panic("mock out the Insert method")
+//             },
+//             RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+// 	               panic("mock out the Remove method")
+//             },
+//             UpdateFunc: func(ctx context.Context, customer *domain.Customer) error {
+// 	               panic("mock out the Update method")
+//             },
+//         }
+//
+//         // use mockedCustomerRepository in code that requires CustomerRepository
+//         // and then make assertions.
+//
+//     }
+type CustomerRepositoryMock struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context) ([]domain.Customer, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, customer *domain.Customer) error
+
+	// RemoveFunc mocks the Remove method.
+	RemoveFunc func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error)
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, customer *domain.Customer) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Customer is the customer argument value.
+			Customer *domain.Customer
+		}
+		// Remove holds details about calls to the Remove method.
+		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Customer is the customer argument value.
+			Customer *domain.Customer
+		}
+	}
+}
+
+// Get calls GetFunc.
+func (mock *CustomerRepositoryMock) Get(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+	if mock.GetFunc == nil {
+		panic("CustomerRepositoryMock.GetFunc: method is nil but CustomerRepository.Get was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		UUID: uuid,
+	}
+	lockCustomerRepositoryMockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	lockCustomerRepositoryMockGet.Unlock()
+	return mock.GetFunc(ctx, uuid)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//     len(mockedCustomerRepository.GetCalls())
+func (mock *CustomerRepositoryMock) GetCalls() []struct {
+	Ctx  context.Context
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}
+	lockCustomerRepositoryMockGet.RLock()
+	calls = mock.calls.Get
+	lockCustomerRepositoryMockGet.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *CustomerRepositoryMock) GetAll(ctx context.Context) ([]domain.Customer, error) {
+	if mock.GetAllFunc == nil {
+		panic("CustomerRepositoryMock.GetAllFunc: method is nil but CustomerRepository.GetAll was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockCustomerRepositoryMockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	lockCustomerRepositoryMockGetAll.Unlock()
+	return mock.GetAllFunc(ctx)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//     len(mockedCustomerRepository.GetAllCalls())
+func (mock *CustomerRepositoryMock) GetAllCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockCustomerRepositoryMockGetAll.RLock()
+	calls = mock.calls.GetAll
+	lockCustomerRepositoryMockGetAll.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *CustomerRepositoryMock) Insert(ctx context.Context, customer *domain.Customer) error {
+	if mock.InsertFunc == nil {
+		panic("CustomerRepositoryMock.InsertFunc: method is nil but CustomerRepository.Insert was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Customer *domain.Customer
+	}{
+		Ctx:      ctx,
+		Customer: customer,
+	}
+	lockCustomerRepositoryMockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	lockCustomerRepositoryMockInsert.Unlock()
+	return mock.InsertFunc(ctx, customer)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//     len(mockedCustomerRepository.InsertCalls())
+func (mock *CustomerRepositoryMock) InsertCalls() []struct {
+	Ctx      context.Context
+	Customer *domain.Customer
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Customer *domain.Customer
+	}
+	lockCustomerRepositoryMockInsert.RLock()
+	calls = mock.calls.Insert
+	lockCustomerRepositoryMockInsert.RUnlock()
+	return calls
+}
+
+// Remove calls RemoveFunc.
+func (mock *CustomerRepositoryMock) Remove(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+	if mock.RemoveFunc == nil {
+		panic("CustomerRepositoryMock.RemoveFunc: method is nil but CustomerRepository.Remove was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		UUID: uuid,
+	}
+	lockCustomerRepositoryMockRemove.Lock()
+	mock.calls.Remove = append(mock.calls.Remove, callInfo)
+	lockCustomerRepositoryMockRemove.Unlock()
+	return mock.RemoveFunc(ctx, uuid)
+}
+
+// RemoveCalls gets all the calls that were made to Remove.
+// Check the length with:
+//     len(mockedCustomerRepository.RemoveCalls())
+func (mock *CustomerRepositoryMock) RemoveCalls() []struct {
+	Ctx  context.Context
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}
+	lockCustomerRepositoryMockRemove.RLock()
+	calls = mock.calls.Remove
+	lockCustomerRepositoryMockRemove.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *CustomerRepositoryMock) Update(ctx context.Context, customer *domain.Customer) error {
+	if mock.UpdateFunc == nil {
+		panic("CustomerRepositoryMock.UpdateFunc: method is nil but CustomerRepository.Update was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Customer *domain.Customer
+	}{
+		Ctx:      ctx,
+		Customer: customer,
+	}
+	lockCustomerRepositoryMockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	lockCustomerRepositoryMockUpdate.Unlock()
+	return mock.UpdateFunc(ctx, customer)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//     len(mockedCustomerRepository.UpdateCalls())
+func (mock *CustomerRepositoryMock) UpdateCalls() []struct {
+	Ctx      context.Context
+	Customer *domain.Customer
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Customer *domain.Customer
+	}
+	lockCustomerRepositoryMockUpdate.RLock()
+	calls = mock.calls.Update
+	lockCustomerRepositoryMockUpdate.RUnlock()
+	return calls
+}
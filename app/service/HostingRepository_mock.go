@@ -4,16 +4,20 @@
 package service
 
 import (
+	"context"
 	"github.com/theskyinflames/cdmon2/app/domain"
 	"sync"
 )
 
 var (
-	lockHostingRepositoryMockGet    sync.RWMutex
-	lockHostingRepositoryMockGetAll sync.RWMutex
-	lockHostingRepositoryMockInsert sync.RWMutex
-	lockHostingRepositoryMockRemove sync.RWMutex
-	lockHostingRepositoryMockUpdate sync.RWMutex
+	lockHostingRepositoryMockGet        sync.RWMutex
+	lockHostingRepositoryMockGetAll     sync.RWMutex
+	lockHostingRepositoryMockInsert     sync.RWMutex
+	lockHostingRepositoryMockNameIndex  sync.RWMutex
+	lockHostingRepositoryMockRemove     sync.RWMutex
+	lockHostingRepositoryMockRemoveName sync.RWMutex
+	lockHostingRepositoryMockSetName    sync.RWMutex
+	lockHostingRepositoryMockUpdate     sync.RWMutex
 )
 
 // Ensure, that HostingRepositoryMock does implement HostingRepository.
@@ -26,19 +30,28 @@ var _ HostingRepository = &HostingRepositoryMock{}
 //
 //         // make and configure a mocked HostingRepository
 //         mockedHostingRepository := &HostingRepositoryMock{
-//             GetFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+//             GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 // 	               panic("mock out the Get method")
 //             },
-//             GetAllFunc: func() ([]domain.Hosting, error) {
+//             GetAllFunc: func(ctx context.Context) ([]domain.Hosting, error) {
 // 	               panic("mock out the GetAll method")
 //             },
-//             InsertFunc: func(hosting *domain.Hosting) error {
+//             InsertFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 // 	               panic("mock out the Insert method")
 //             },
-//             RemoveFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+//             NameIndexFunc: func(ctx context.Context) (map[string]domain.UUID, error) {
+// 	               panic("mock out the NameIndex method")
+//             },
+//             RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 // 	               panic("mock out the Remove method")
 //             },
-//             UpdateFunc: func(hosting *domain.Hosting) error {
+//             RemoveNameFunc: func(ctx context.Context, name string) error {
+// 	               panic("mock out the RemoveName method")
+//             },
+//             SetNameFunc: func(ctx context.Context, name string, uuid domain.UUID) error {
+// 	               panic("mock out the SetName method")
+//             },
+//             UpdateFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 // 	               panic("mock out the Update method")
 //             },
 //         }
@@ -49,42 +62,82 @@ var _ HostingRepository = &HostingRepositoryMock{}
 //     }
 type HostingRepositoryMock struct {
 	// GetFunc mocks the Get method.
-	GetFunc func(uuid domain.UUID) (*domain.Hosting, error)
+	GetFunc func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error)
 
 	// GetAllFunc mocks the GetAll method.
-	GetAllFunc func() ([]domain.Hosting, error)
+	GetAllFunc func(ctx context.Context) ([]domain.Hosting, error)
 
 	// InsertFunc mocks the Insert method.
-	InsertFunc func(hosting *domain.Hosting) error
+	InsertFunc func(ctx context.Context, hosting *domain.Hosting) error
+
+	// NameIndexFunc mocks the NameIndex method.
+	NameIndexFunc func(ctx context.Context) (map[string]domain.UUID, error)
 
 	// RemoveFunc mocks the Remove method.
-	RemoveFunc func(uuid domain.UUID) (*domain.Hosting, error)
+	RemoveFunc func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error)
+
+	// RemoveNameFunc mocks the RemoveName method.
+	RemoveNameFunc func(ctx context.Context, name string) error
+
+	// SetNameFunc mocks the SetName method.
+	SetNameFunc func(ctx context.Context, name string, uuid domain.UUID) error
 
 	// UpdateFunc mocks the Update method.
-	UpdateFunc func(hosting *domain.Hosting) error
+	UpdateFunc func(ctx context.Context, hosting *domain.Hosting) error
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// Get holds details about calls to the Get method.
 		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// UUID is the uuid argument value.
 			UUID domain.UUID
 		}
 		// GetAll holds details about calls to the GetAll method.
 		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 		}
 		// Insert holds details about calls to the Insert method.
 		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Hosting is the hosting argument value.
 			Hosting *domain.Hosting
 		}
+		// NameIndex holds details about calls to the NameIndex method.
+		NameIndex []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// Remove holds details about calls to the Remove method.
 		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+		// RemoveName holds details about calls to the RemoveName method.
+		RemoveName []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+		// SetName holds details about calls to the SetName method.
+		SetName []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
 			// UUID is the uuid argument value.
 			UUID domain.UUID
 		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Hosting is the hosting argument value.
 			Hosting *domain.Hosting
 		}
@@ -92,28 +145,32 @@ type HostingRepositoryMock struct {
 }
 
 // Get calls GetFunc.
-func (mock *HostingRepositoryMock) Get(uuid domain.UUID) (*domain.Hosting, error) {
+func (mock *HostingRepositoryMock) Get(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 	if mock.GetFunc == nil {
 		panic("HostingRepositoryMock.GetFunc: method is nil but HostingRepository.Get was just called")
 	}
 	callInfo := struct {
+		Ctx  context.Context
 		UUID domain.UUID
 	}{
+		Ctx:  ctx,
 		UUID: uuid,
 	}
 	lockHostingRepositoryMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockHostingRepositoryMockGet.Unlock()
-	return mock.GetFunc(uuid)
+	return mock.GetFunc(ctx, uuid)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
 //     len(mockedHostingRepository.GetCalls())
 func (mock *HostingRepositoryMock) GetCalls() []struct {
+	Ctx  context.Context
 	UUID domain.UUID
 } {
 	var calls []struct {
+		Ctx  context.Context
 		UUID domain.UUID
 	}
 	lockHostingRepositoryMockGet.RLock()
@@ -123,24 +180,29 @@ func (mock *HostingRepositoryMock) GetCalls() []struct {
 }
 
 // GetAll calls GetAllFunc.
-func (mock *HostingRepositoryMock) GetAll() ([]domain.Hosting, error) {
+func (mock *HostingRepositoryMock) GetAll(ctx context.Context) ([]domain.Hosting, error) {
 	if mock.GetAllFunc == nil {
 		panic("HostingRepositoryMock.GetAllFunc: method is nil but HostingRepository.GetAll was just called")
 	}
 	callInfo := struct {
-	}{}
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
 	lockHostingRepositoryMockGetAll.Lock()
 	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
 	lockHostingRepositoryMockGetAll.Unlock()
-	return mock.GetAllFunc()
+	return mock.GetAllFunc(ctx)
 }
 
 // GetAllCalls gets all the calls that were made to GetAll.
 // Check the length with:
 //     len(mockedHostingRepository.GetAllCalls())
 func (mock *HostingRepositoryMock) GetAllCalls() []struct {
+	Ctx context.Context
 } {
 	var calls []struct {
+		Ctx context.Context
 	}
 	lockHostingRepositoryMockGetAll.RLock()
 	calls = mock.calls.GetAll
@@ -149,28 +211,32 @@ func (mock *HostingRepositoryMock) GetAllCalls() []struct {
 }
 
 // Insert calls InsertFunc.
-func (mock *HostingRepositoryMock) Insert(hosting *domain.Hosting) error {
+func (mock *HostingRepositoryMock) Insert(ctx context.Context, hosting *domain.Hosting) error {
 	if mock.InsertFunc == nil {
 		panic("HostingRepositoryMock.InsertFunc: method is nil but HostingRepository.Insert was just called")
 	}
 	callInfo := struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}{
+		Ctx:     ctx,
 		Hosting: hosting,
 	}
 	lockHostingRepositoryMockInsert.Lock()
 	mock.calls.Insert = append(mock.calls.Insert, callInfo)
 	lockHostingRepositoryMockInsert.Unlock()
-	return mock.InsertFunc(hosting)
+	return mock.InsertFunc(ctx, hosting)
 }
 
 // InsertCalls gets all the calls that were made to Insert.
 // Check the length with:
 //     len(mockedHostingRepository.InsertCalls())
 func (mock *HostingRepositoryMock) InsertCalls() []struct {
+	Ctx     context.Context
 	Hosting *domain.Hosting
 } {
 	var calls []struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}
 	lockHostingRepositoryMockInsert.RLock()
@@ -179,29 +245,64 @@ func (mock *HostingRepositoryMock) InsertCalls() []struct {
 	return calls
 }
 
+// NameIndex calls NameIndexFunc.
+func (mock *HostingRepositoryMock) NameIndex(ctx context.Context) (map[string]domain.UUID, error) {
+	if mock.NameIndexFunc == nil {
+		panic("HostingRepositoryMock.NameIndexFunc: method is nil but HostingRepository.NameIndex was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockHostingRepositoryMockNameIndex.Lock()
+	mock.calls.NameIndex = append(mock.calls.NameIndex, callInfo)
+	lockHostingRepositoryMockNameIndex.Unlock()
+	return mock.NameIndexFunc(ctx)
+}
+
+// NameIndexCalls gets all the calls that were made to NameIndex.
+// Check the length with:
+//     len(mockedHostingRepository.NameIndexCalls())
+func (mock *HostingRepositoryMock) NameIndexCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockHostingRepositoryMockNameIndex.RLock()
+	calls = mock.calls.NameIndex
+	lockHostingRepositoryMockNameIndex.RUnlock()
+	return calls
+}
+
 // Remove calls RemoveFunc.
-func (mock *HostingRepositoryMock) Remove(uuid domain.UUID) (*domain.Hosting, error) {
+func (mock *HostingRepositoryMock) Remove(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 	if mock.RemoveFunc == nil {
 		panic("HostingRepositoryMock.RemoveFunc: method is nil but HostingRepository.Remove was just called")
 	}
 	callInfo := struct {
+		Ctx  context.Context
 		UUID domain.UUID
 	}{
+		Ctx:  ctx,
 		UUID: uuid,
 	}
 	lockHostingRepositoryMockRemove.Lock()
 	mock.calls.Remove = append(mock.calls.Remove, callInfo)
 	lockHostingRepositoryMockRemove.Unlock()
-	return mock.RemoveFunc(uuid)
+	return mock.RemoveFunc(ctx, uuid)
 }
 
 // RemoveCalls gets all the calls that were made to Remove.
 // Check the length with:
 //     len(mockedHostingRepository.RemoveCalls())
 func (mock *HostingRepositoryMock) RemoveCalls() []struct {
+	Ctx  context.Context
 	UUID domain.UUID
 } {
 	var calls []struct {
+		Ctx  context.Context
 		UUID domain.UUID
 	}
 	lockHostingRepositoryMockRemove.RLock()
@@ -210,29 +311,107 @@ func (mock *HostingRepositoryMock) RemoveCalls() []struct {
 	return calls
 }
 
+// RemoveName calls RemoveNameFunc.
+func (mock *HostingRepositoryMock) RemoveName(ctx context.Context, name string) error {
+	if mock.RemoveNameFunc == nil {
+		panic("HostingRepositoryMock.RemoveNameFunc: method is nil but HostingRepository.RemoveName was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	lockHostingRepositoryMockRemoveName.Lock()
+	mock.calls.RemoveName = append(mock.calls.RemoveName, callInfo)
+	lockHostingRepositoryMockRemoveName.Unlock()
+	return mock.RemoveNameFunc(ctx, name)
+}
+
+// RemoveNameCalls gets all the calls that were made to RemoveName.
+// Check the length with:
+//     len(mockedHostingRepository.RemoveNameCalls())
+func (mock *HostingRepositoryMock) RemoveNameCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	lockHostingRepositoryMockRemoveName.RLock()
+	calls = mock.calls.RemoveName
+	lockHostingRepositoryMockRemoveName.RUnlock()
+	return calls
+}
+
+// SetName calls SetNameFunc.
+func (mock *HostingRepositoryMock) SetName(ctx context.Context, name string, uuid domain.UUID) error {
+	if mock.SetNameFunc == nil {
+		panic("HostingRepositoryMock.SetNameFunc: method is nil but HostingRepository.SetName was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		Name: name,
+		UUID: uuid,
+	}
+	lockHostingRepositoryMockSetName.Lock()
+	mock.calls.SetName = append(mock.calls.SetName, callInfo)
+	lockHostingRepositoryMockSetName.Unlock()
+	return mock.SetNameFunc(ctx, name, uuid)
+}
+
+// SetNameCalls gets all the calls that were made to SetName.
+// Check the length with:
+//     len(mockedHostingRepository.SetNameCalls())
+func (mock *HostingRepositoryMock) SetNameCalls() []struct {
+	Ctx  context.Context
+	Name string
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+		UUID domain.UUID
+	}
+	lockHostingRepositoryMockSetName.RLock()
+	calls = mock.calls.SetName
+	lockHostingRepositoryMockSetName.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
-func (mock *HostingRepositoryMock) Update(hosting *domain.Hosting) error {
+func (mock *HostingRepositoryMock) Update(ctx context.Context, hosting *domain.Hosting) error {
 	if mock.UpdateFunc == nil {
 		panic("HostingRepositoryMock.UpdateFunc: method is nil but HostingRepository.Update was just called")
 	}
 	callInfo := struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}{
+		Ctx:     ctx,
 		Hosting: hosting,
 	}
 	lockHostingRepositoryMockUpdate.Lock()
 	mock.calls.Update = append(mock.calls.Update, callInfo)
 	lockHostingRepositoryMockUpdate.Unlock()
-	return mock.UpdateFunc(hosting)
+	return mock.UpdateFunc(ctx, hosting)
 }
 
 // UpdateCalls gets all the calls that were made to Update.
 // Check the length with:
 //     len(mockedHostingRepository.UpdateCalls())
 func (mock *HostingRepositoryMock) UpdateCalls() []struct {
+	Ctx     context.Context
 	Hosting *domain.Hosting
 } {
 	var calls []struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}
 	lockHostingRepositoryMockUpdate.RLock()
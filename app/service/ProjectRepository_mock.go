@@ -0,0 +1,274 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package service
+
+import (
+	"context"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"sync"
+)
+
+var (
+	lockProjectRepositoryMockGet    sync.RWMutex
+	lockProjectRepositoryMockGetAll sync.RWMutex
+	lockProjectRepositoryMockInsert sync.RWMutex
+	lockProjectRepositoryMockRemove sync.RWMutex
+	lockProjectRepositoryMockUpdate sync.RWMutex
+)
+
+// Ensure, that ProjectRepositoryMock does implement ProjectRepository.
+// If this is not the case, regenerate this file with moq.
+var _ ProjectRepository = &ProjectRepositoryMock{}
+
+// ProjectRepositoryMock is a mock implementation of ProjectRepository.
+//
+//     func TestSomethingThatUsesProjectRepository(t *testing.T) {
+//
+//         // make and configure a mocked ProjectRepository
+//         mockedProjectRepository := &ProjectRepositoryMock{
+//             GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+// 	               panic("mock out the Get method")
+//             },
+//             GetAllFunc: func(ctx context.Context) ([]domain.Project, error) {
+// 	               panic("mock out the GetAll method")
+//             },
+//             InsertFunc: func(ctx context.Context, project *domain.Project) error {
+// 	               panic("mock out the Insert method")
+//             },
+//             RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+// 	               panic("mock out the Remove method")
+//             },
+//             UpdateFunc: func(ctx context.Context, project *domain.Project) error {
+// 	               panic("mock out the Update method")
+//             },
+//         }
+//
+//         // use mockedProjectRepository in code that requires ProjectRepository
+//         // and then make assertions.
+//
+//     }
+type ProjectRepositoryMock struct {
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, uuid domain.UUID) (*domain.Project, error)
+
+	// GetAllFunc mocks the GetAll method.
+	GetAllFunc func(ctx context.Context) ([]domain.Project, error)
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(ctx context.Context, project *domain.Project) error
+
+	// RemoveFunc mocks the Remove method.
+	RemoveFunc func(ctx context.Context, uuid domain.UUID) (*domain.Project, error)
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(ctx context.Context, project *domain.Project) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+		// GetAll holds details about calls to the GetAll method.
+		GetAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Project is the project argument value.
+			Project *domain.Project
+		}
+		// Remove holds details about calls to the Remove method.
+		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UUID is the uuid argument value.
+			UUID domain.UUID
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Project is the project argument value.
+			Project *domain.Project
+		}
+	}
+}
+
+// Get calls GetFunc.
+func (mock *ProjectRepositoryMock) Get(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+	if mock.GetFunc == nil {
+		panic("ProjectRepositoryMock.GetFunc: method is nil but ProjectRepository.Get was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		UUID: uuid,
+	}
+	lockProjectRepositoryMockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	lockProjectRepositoryMockGet.Unlock()
+	return mock.GetFunc(ctx, uuid)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//     len(mockedProjectRepository.GetCalls())
+func (mock *ProjectRepositoryMock) GetCalls() []struct {
+	Ctx  context.Context
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}
+	lockProjectRepositoryMockGet.RLock()
+	calls = mock.calls.Get
+	lockProjectRepositoryMockGet.RUnlock()
+	return calls
+}
+
+// GetAll calls GetAllFunc.
+func (mock *ProjectRepositoryMock) GetAll(ctx context.Context) ([]domain.Project, error) {
+	if mock.GetAllFunc == nil {
+		panic("ProjectRepositoryMock.GetAllFunc: method is nil but ProjectRepository.GetAll was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockProjectRepositoryMockGetAll.Lock()
+	mock.calls.GetAll = append(mock.calls.GetAll, callInfo)
+	lockProjectRepositoryMockGetAll.Unlock()
+	return mock.GetAllFunc(ctx)
+}
+
+// GetAllCalls gets all the calls that were made to GetAll.
+// Check the length with:
+//     len(mockedProjectRepository.GetAllCalls())
+func (mock *ProjectRepositoryMock) GetAllCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockProjectRepositoryMockGetAll.RLock()
+	calls = mock.calls.GetAll
+	lockProjectRepositoryMockGetAll.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *ProjectRepositoryMock) Insert(ctx context.Context, project *domain.Project) error {
+	if mock.InsertFunc == nil {
+		panic("ProjectRepositoryMock.InsertFunc: method is nil but ProjectRepository.Insert was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project *domain.Project
+	}{
+		Ctx:     ctx,
+		Project: project,
+	}
+	lockProjectRepositoryMockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	lockProjectRepositoryMockInsert.Unlock()
+	return mock.InsertFunc(ctx, project)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//     len(mockedProjectRepository.InsertCalls())
+func (mock *ProjectRepositoryMock) InsertCalls() []struct {
+	Ctx     context.Context
+	Project *domain.Project
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project *domain.Project
+	}
+	lockProjectRepositoryMockInsert.RLock()
+	calls = mock.calls.Insert
+	lockProjectRepositoryMockInsert.RUnlock()
+	return calls
+}
+
+// Remove calls RemoveFunc.
+func (mock *ProjectRepositoryMock) Remove(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+	if mock.RemoveFunc == nil {
+		panic("ProjectRepositoryMock.RemoveFunc: method is nil but ProjectRepository.Remove was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}{
+		Ctx:  ctx,
+		UUID: uuid,
+	}
+	lockProjectRepositoryMockRemove.Lock()
+	mock.calls.Remove = append(mock.calls.Remove, callInfo)
+	lockProjectRepositoryMockRemove.Unlock()
+	return mock.RemoveFunc(ctx, uuid)
+}
+
+// RemoveCalls gets all the calls that were made to Remove.
+// Check the length with:
+//     len(mockedProjectRepository.RemoveCalls())
+func (mock *ProjectRepositoryMock) RemoveCalls() []struct {
+	Ctx  context.Context
+	UUID domain.UUID
+} {
+	var calls []struct {
+		Ctx  context.Context
+		UUID domain.UUID
+	}
+	lockProjectRepositoryMockRemove.RLock()
+	calls = mock.calls.Remove
+	lockProjectRepositoryMockRemove.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *ProjectRepositoryMock) Update(ctx context.Context, project *domain.Project) error {
+	if mock.UpdateFunc == nil {
+		panic("ProjectRepositoryMock.UpdateFunc: method is nil but ProjectRepository.Update was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Project *domain.Project
+	}{
+		Ctx:     ctx,
+		Project: project,
+	}
+	lockProjectRepositoryMockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	lockProjectRepositoryMockUpdate.Unlock()
+	return mock.UpdateFunc(ctx, project)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//     len(mockedProjectRepository.UpdateCalls())
+func (mock *ProjectRepositoryMock) UpdateCalls() []struct {
+	Ctx     context.Context
+	Project *domain.Project
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Project *domain.Project
+	}
+	lockProjectRepositoryMockUpdate.RLock()
+	calls = mock.calls.Update
+	lockProjectRepositoryMockUpdate.RUnlock()
+	return calls
+}
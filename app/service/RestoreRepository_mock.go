@@ -0,0 +1,102 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package service
+
+import (
+	"context"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"sync"
+)
+
+var (
+	lockRestoreRepositoryMockRestore sync.RWMutex
+)
+
+// Ensure, that RestoreRepositoryMock does implement RestoreRepository.
+// If this is not the case, regenerate this file with moq.
+var _ RestoreRepository = &RestoreRepositoryMock{}
+
+// RestoreRepositoryMock is a mock implementation of RestoreRepository.
+//
+//     func TestSomethingThatUsesRestoreRepository(t *testing.T) {
+//
+//         // make and configure a mocked RestoreRepository
+//         mockedRestoreRepository := &RestoreRepositoryMock{
+//             RestoreFunc: func(ctx context.Context, customers []domain.Customer, projects []domain.Project, apiKeys []domain.APIKey, hostings []domain.Hosting) error {
+// 	               panic("mock out the Restore method")
+//             },
+//         }
+//
+//         // use mockedRestoreRepository in code that requires RestoreRepository
+//         // and then make assertions.
+//
+//     }
+type RestoreRepositoryMock struct {
+	// RestoreFunc mocks the Restore method.
+	RestoreFunc func(ctx context.Context, customers []domain.Customer, projects []domain.Project, apiKeys []domain.APIKey, hostings []domain.Hosting) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Restore holds details about calls to the Restore method.
+		Restore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Customers is the customers argument value.
+			Customers []domain.Customer
+			// Projects is the projects argument value.
+			Projects []domain.Project
+			// ApiKeys is the apiKeys argument value.
+			ApiKeys []domain.APIKey
+			// Hostings is the hostings argument value.
+			Hostings []domain.Hosting
+		}
+	}
+}
+
+// Restore calls RestoreFunc.
+func (mock *RestoreRepositoryMock) Restore(ctx context.Context, customers []domain.Customer, projects []domain.Project, apiKeys []domain.APIKey, hostings []domain.Hosting) error {
+	if mock.RestoreFunc == nil {
+		panic("RestoreRepositoryMock.RestoreFunc: method is nil but RestoreRepository.Restore was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		Customers []domain.Customer
+		Projects  []domain.Project
+		ApiKeys   []domain.APIKey
+		Hostings  []domain.Hosting
+	}{
+		Ctx:       ctx,
+		Customers: customers,
+		Projects:  projects,
+		ApiKeys:   apiKeys,
+		Hostings:  hostings,
+	}
+	lockRestoreRepositoryMockRestore.Lock()
+	mock.calls.Restore = append(mock.calls.Restore, callInfo)
+	lockRestoreRepositoryMockRestore.Unlock()
+	return mock.RestoreFunc(ctx, customers, projects, apiKeys, hostings)
+}
+
+// RestoreCalls gets all the calls that were made to Restore.
+// Check the length with:
+//     len(mockedRestoreRepository.RestoreCalls())
+func (mock *RestoreRepositoryMock) RestoreCalls() []struct {
+	Ctx       context.Context
+	Customers []domain.Customer
+	Projects  []domain.Project
+	ApiKeys   []domain.APIKey
+	Hostings  []domain.Hosting
+} {
+	var calls []struct {
+		Ctx       context.Context
+		Customers []domain.Customer
+		Projects  []domain.Project
+		ApiKeys   []domain.APIKey
+		Hostings  []domain.Hosting
+	}
+	lockRestoreRepositoryMockRestore.RLock()
+	calls = mock.calls.Restore
+	lockRestoreRepositoryMockRestore.RUnlock()
+	return calls
+}
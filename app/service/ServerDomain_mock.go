@@ -4,6 +4,7 @@
 package service
 
 import (
+	"context"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
 	"sync"
@@ -11,7 +12,11 @@ import (
 
 var (
 	lockServerDomainMockAddHosting    sync.RWMutex
+	lockServerDomainMockLoadUsage     sync.RWMutex
 	lockServerDomainMockRemoveHosting sync.RWMutex
+	lockServerDomainMockResize        sync.RWMutex
+	lockServerDomainMockSetUsage      sync.RWMutex
+	lockServerDomainMockSnapshot      sync.RWMutex
 	lockServerDomainMockUpdateHosting sync.RWMutex
 )
 
@@ -25,13 +30,25 @@ var _ ServerDomain = &ServerDomainMock{}
 //
 //         // make and configure a mocked ServerDomain
 //         mockedServerDomain := &ServerDomainMock{
-//             AddHostingFunc: func(hosting *domain.Hosting, cfg *config.Config) error {
+//             AddHostingFunc: func(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error {
 // 	               panic("mock out the AddHosting method")
 //             },
-//             RemoveHostingFunc: func(hosting *domain.Hosting) error {
+//             LoadUsageFunc: func(ctx context.Context, used domain.Quota) error {
+// 	               panic("mock out the LoadUsage method")
+//             },
+//             RemoveHostingFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 // 	               panic("mock out the RemoveHosting method")
 //             },
-//             UpdateHostingFunc: func(hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error {
+//             ResizeFunc: func(ctx context.Context, cores int, memorymb int, diskmb int) error {
+// 	               panic("mock out the Resize method")
+//             },
+//             SetUsageFunc: func(ctx context.Context, used domain.Quota) error {
+// 	               panic("mock out the SetUsage method")
+//             },
+//             SnapshotFunc: func() domain.ServerStatus {
+// 	               panic("mock out the Snapshot method")
+//             },
+//             UpdateHostingFunc: func(ctx context.Context, hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error {
 // 	               panic("mock out the UpdateHosting method")
 //             },
 //         }
@@ -42,30 +59,76 @@ var _ ServerDomain = &ServerDomainMock{}
 //     }
 type ServerDomainMock struct {
 	// AddHostingFunc mocks the AddHosting method.
-	AddHostingFunc func(hosting *domain.Hosting, cfg *config.Config) error
+	AddHostingFunc func(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error
+
+	// LoadUsageFunc mocks the LoadUsage method.
+	LoadUsageFunc func(ctx context.Context, used domain.Quota) error
 
 	// RemoveHostingFunc mocks the RemoveHosting method.
-	RemoveHostingFunc func(hosting *domain.Hosting) error
+	RemoveHostingFunc func(ctx context.Context, hosting *domain.Hosting) error
+
+	// ResizeFunc mocks the Resize method.
+	ResizeFunc func(ctx context.Context, cores int, memorymb int, diskmb int) error
+
+	// SetUsageFunc mocks the SetUsage method.
+	SetUsageFunc func(ctx context.Context, used domain.Quota) error
+
+	// SnapshotFunc mocks the Snapshot method.
+	SnapshotFunc func() domain.ServerStatus
 
 	// UpdateHostingFunc mocks the UpdateHosting method.
-	UpdateHostingFunc func(hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error
+	UpdateHostingFunc func(ctx context.Context, hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// AddHosting holds details about calls to the AddHosting method.
 		AddHosting []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Hosting is the hosting argument value.
 			Hosting *domain.Hosting
 			// Cfg is the cfg argument value.
 			Cfg *config.Config
 		}
+		// LoadUsage holds details about calls to the LoadUsage method.
+		LoadUsage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Used is the used argument value.
+			Used domain.Quota
+		}
 		// RemoveHosting holds details about calls to the RemoveHosting method.
 		RemoveHosting []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Hosting is the hosting argument value.
 			Hosting *domain.Hosting
 		}
+		// Resize holds details about calls to the Resize method.
+		Resize []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cores is the cores argument value.
+			Cores int
+			// Memorymb is the memorymb argument value.
+			Memorymb int
+			// Diskmb is the diskmb argument value.
+			Diskmb int
+		}
+		// SetUsage holds details about calls to the SetUsage method.
+		SetUsage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Used is the used argument value.
+			Used domain.Quota
+		}
+		// Snapshot holds details about calls to the Snapshot method.
+		Snapshot []struct {
+		}
 		// UpdateHosting holds details about calls to the UpdateHosting method.
 		UpdateHosting []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Hosting is the hosting argument value.
 			Hosting *domain.Hosting
 			// Old is the old argument value.
@@ -77,31 +140,35 @@ type ServerDomainMock struct {
 }
 
 // AddHosting calls AddHostingFunc.
-func (mock *ServerDomainMock) AddHosting(hosting *domain.Hosting, cfg *config.Config) error {
+func (mock *ServerDomainMock) AddHosting(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error {
 	if mock.AddHostingFunc == nil {
 		panic("ServerDomainMock.AddHostingFunc: method is nil but ServerDomain.AddHosting was just called")
 	}
 	callInfo := struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 		Cfg     *config.Config
 	}{
+		Ctx:     ctx,
 		Hosting: hosting,
 		Cfg:     cfg,
 	}
 	lockServerDomainMockAddHosting.Lock()
 	mock.calls.AddHosting = append(mock.calls.AddHosting, callInfo)
 	lockServerDomainMockAddHosting.Unlock()
-	return mock.AddHostingFunc(hosting, cfg)
+	return mock.AddHostingFunc(ctx, hosting, cfg)
 }
 
 // AddHostingCalls gets all the calls that were made to AddHosting.
 // Check the length with:
 //     len(mockedServerDomain.AddHostingCalls())
 func (mock *ServerDomainMock) AddHostingCalls() []struct {
+	Ctx     context.Context
 	Hosting *domain.Hosting
 	Cfg     *config.Config
 } {
 	var calls []struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 		Cfg     *config.Config
 	}
@@ -111,29 +178,68 @@ func (mock *ServerDomainMock) AddHostingCalls() []struct {
 	return calls
 }
 
+// LoadUsage calls LoadUsageFunc.
+func (mock *ServerDomainMock) LoadUsage(ctx context.Context, used domain.Quota) error {
+	if mock.LoadUsageFunc == nil {
+		panic("ServerDomainMock.LoadUsageFunc: method is nil but ServerDomain.LoadUsage was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Used domain.Quota
+	}{
+		Ctx:  ctx,
+		Used: used,
+	}
+	lockServerDomainMockLoadUsage.Lock()
+	mock.calls.LoadUsage = append(mock.calls.LoadUsage, callInfo)
+	lockServerDomainMockLoadUsage.Unlock()
+	return mock.LoadUsageFunc(ctx, used)
+}
+
+// LoadUsageCalls gets all the calls that were made to LoadUsage.
+// Check the length with:
+//     len(mockedServerDomain.LoadUsageCalls())
+func (mock *ServerDomainMock) LoadUsageCalls() []struct {
+	Ctx  context.Context
+	Used domain.Quota
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Used domain.Quota
+	}
+	lockServerDomainMockLoadUsage.RLock()
+	calls = mock.calls.LoadUsage
+	lockServerDomainMockLoadUsage.RUnlock()
+	return calls
+}
+
 // RemoveHosting calls RemoveHostingFunc.
-func (mock *ServerDomainMock) RemoveHosting(hosting *domain.Hosting) error {
+func (mock *ServerDomainMock) RemoveHosting(ctx context.Context, hosting *domain.Hosting) error {
 	if mock.RemoveHostingFunc == nil {
 		panic("ServerDomainMock.RemoveHostingFunc: method is nil but ServerDomain.RemoveHosting was just called")
 	}
 	callInfo := struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}{
+		Ctx:     ctx,
 		Hosting: hosting,
 	}
 	lockServerDomainMockRemoveHosting.Lock()
 	mock.calls.RemoveHosting = append(mock.calls.RemoveHosting, callInfo)
 	lockServerDomainMockRemoveHosting.Unlock()
-	return mock.RemoveHostingFunc(hosting)
+	return mock.RemoveHostingFunc(ctx, hosting)
 }
 
 // RemoveHostingCalls gets all the calls that were made to RemoveHosting.
 // Check the length with:
 //     len(mockedServerDomain.RemoveHostingCalls())
 func (mock *ServerDomainMock) RemoveHostingCalls() []struct {
+	Ctx     context.Context
 	Hosting *domain.Hosting
 } {
 	var calls []struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 	}
 	lockServerDomainMockRemoveHosting.RLock()
@@ -142,16 +248,122 @@ func (mock *ServerDomainMock) RemoveHostingCalls() []struct {
 	return calls
 }
 
+// Resize calls ResizeFunc.
+func (mock *ServerDomainMock) Resize(ctx context.Context, cores int, memorymb int, diskmb int) error {
+	if mock.ResizeFunc == nil {
+		panic("ServerDomainMock.ResizeFunc: method is nil but ServerDomain.Resize was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Cores    int
+		Memorymb int
+		Diskmb   int
+	}{
+		Ctx:      ctx,
+		Cores:    cores,
+		Memorymb: memorymb,
+		Diskmb:   diskmb,
+	}
+	lockServerDomainMockResize.Lock()
+	mock.calls.Resize = append(mock.calls.Resize, callInfo)
+	lockServerDomainMockResize.Unlock()
+	return mock.ResizeFunc(ctx, cores, memorymb, diskmb)
+}
+
+// ResizeCalls gets all the calls that were made to Resize.
+// Check the length with:
+//     len(mockedServerDomain.ResizeCalls())
+func (mock *ServerDomainMock) ResizeCalls() []struct {
+	Ctx      context.Context
+	Cores    int
+	Memorymb int
+	Diskmb   int
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Cores    int
+		Memorymb int
+		Diskmb   int
+	}
+	lockServerDomainMockResize.RLock()
+	calls = mock.calls.Resize
+	lockServerDomainMockResize.RUnlock()
+	return calls
+}
+
+// SetUsage calls SetUsageFunc.
+func (mock *ServerDomainMock) SetUsage(ctx context.Context, used domain.Quota) error {
+	if mock.SetUsageFunc == nil {
+		panic("ServerDomainMock.SetUsageFunc: method is nil but ServerDomain.SetUsage was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Used domain.Quota
+	}{
+		Ctx:  ctx,
+		Used: used,
+	}
+	lockServerDomainMockSetUsage.Lock()
+	mock.calls.SetUsage = append(mock.calls.SetUsage, callInfo)
+	lockServerDomainMockSetUsage.Unlock()
+	return mock.SetUsageFunc(ctx, used)
+}
+
+// SetUsageCalls gets all the calls that were made to SetUsage.
+// Check the length with:
+//     len(mockedServerDomain.SetUsageCalls())
+func (mock *ServerDomainMock) SetUsageCalls() []struct {
+	Ctx  context.Context
+	Used domain.Quota
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Used domain.Quota
+	}
+	lockServerDomainMockSetUsage.RLock()
+	calls = mock.calls.SetUsage
+	lockServerDomainMockSetUsage.RUnlock()
+	return calls
+}
+
+// Snapshot calls SnapshotFunc.
+func (mock *ServerDomainMock) Snapshot() domain.ServerStatus {
+	if mock.SnapshotFunc == nil {
+		panic("ServerDomainMock.SnapshotFunc: method is nil but ServerDomain.Snapshot was just called")
+	}
+	callInfo := struct {
+	}{}
+	lockServerDomainMockSnapshot.Lock()
+	mock.calls.Snapshot = append(mock.calls.Snapshot, callInfo)
+	lockServerDomainMockSnapshot.Unlock()
+	return mock.SnapshotFunc()
+}
+
+// SnapshotCalls gets all the calls that were made to Snapshot.
+// Check the length with:
+//     len(mockedServerDomain.SnapshotCalls())
+func (mock *ServerDomainMock) SnapshotCalls() []struct {
+} {
+	var calls []struct {
+	}
+	lockServerDomainMockSnapshot.RLock()
+	calls = mock.calls.Snapshot
+	lockServerDomainMockSnapshot.RUnlock()
+	return calls
+}
+
 // UpdateHosting calls UpdateHostingFunc.
-func (mock *ServerDomainMock) UpdateHosting(hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error {
+func (mock *ServerDomainMock) UpdateHosting(ctx context.Context, hosting *domain.Hosting, old *domain.Hosting, cfg *config.Config) error {
 	if mock.UpdateHostingFunc == nil {
 		panic("ServerDomainMock.UpdateHostingFunc: method is nil but ServerDomain.UpdateHosting was just called")
 	}
 	callInfo := struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 		Old     *domain.Hosting
 		Cfg     *config.Config
 	}{
+		Ctx:     ctx,
 		Hosting: hosting,
 		Old:     old,
 		Cfg:     cfg,
@@ -159,18 +371,20 @@ func (mock *ServerDomainMock) UpdateHosting(hosting *domain.Hosting, old *domain
 	lockServerDomainMockUpdateHosting.Lock()
 	mock.calls.UpdateHosting = append(mock.calls.UpdateHosting, callInfo)
 	lockServerDomainMockUpdateHosting.Unlock()
-	return mock.UpdateHostingFunc(hosting, old, cfg)
+	return mock.UpdateHostingFunc(ctx, hosting, old, cfg)
 }
 
 // UpdateHostingCalls gets all the calls that were made to UpdateHosting.
 // Check the length with:
 //     len(mockedServerDomain.UpdateHostingCalls())
 func (mock *ServerDomainMock) UpdateHostingCalls() []struct {
+	Ctx     context.Context
 	Hosting *domain.Hosting
 	Old     *domain.Hosting
 	Cfg     *config.Config
 } {
 	var calls []struct {
+		Ctx     context.Context
 		Hosting *domain.Hosting
 		Old     *domain.Hosting
 		Cfg     *config.Config
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/auth"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+const bootstrapAPIKeyName = "bootstrap"
+
+type (
+	APIKeyRepository interface {
+		Get(ctx context.Context, hash string) (*domain.APIKey, error)
+		GetAll(ctx context.Context) ([]domain.APIKey, error)
+		Insert(ctx context.Context, apiKey *domain.APIKey) error
+		Remove(ctx context.Context, uuid domain.UUID) (*domain.APIKey, error)
+	}
+
+	AuthService struct {
+		log              *logrus.Logger
+		cfg              *config.Config
+		apiKeyRepository APIKeyRepository
+		now              func() time.Time
+	}
+)
+
+func NewAuth(apiKeyRepository APIKeyRepository, cfg *config.Config, log *logrus.Logger) *AuthService {
+	return &AuthService{
+		log:              log,
+		cfg:              cfg,
+		apiKeyRepository: apiKeyRepository,
+		now:              time.Now,
+	}
+}
+
+// Bootstrap registers the configured bootstrap key as an admin API key, so the first keys can be created
+func (s *AuthService) Bootstrap(ctx context.Context) error {
+	if len(s.cfg.BootstrapAPIKey) == 0 {
+		return nil
+	}
+
+	hash := domain.HashAPIKey(string(s.cfg.BootstrapAPIKey))
+	_, err := s.apiKeyRepository.Get(ctx, hash)
+	switch errors.Cause(err) {
+	case nil:
+		return nil
+	case app.DbErrorNotFound:
+	default:
+		return err
+	}
+
+	apiKey, _, err := domain.NewAPIKey(bootstrapAPIKeyName, domain.RoleAdmin, domain.UUID(""))
+	if err != nil {
+		return err
+	}
+	apiKey.Hash = hash
+
+	err = s.apiKeyRepository.Insert(ctx, apiKey)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(apiKey.UUID)}).Info("registered bootstrap api key")
+	return nil
+}
+
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (*domain.Principal, error) {
+	apiKey, err := s.apiKeyRepository.Get(ctx, domain.HashAPIKey(key))
+	if err != nil {
+		switch errors.Cause(err) {
+		case app.DbErrorNotFound:
+			return nil, errors.Wrap(app.AuthErrorUnauthenticated, "unknown api key")
+		default:
+			return nil, err
+		}
+	}
+	return apiKey.Principal(), nil
+}
+
+func (s *AuthService) AuthenticateJWT(ctx context.Context, token string) (*domain.Principal, error) {
+	if len(s.cfg.JWTSecret) == 0 {
+		return nil, errors.Wrap(app.AuthErrorUnauthenticated, "jwt authentication is disabled")
+	}
+
+	claims, err := auth.Parse(token, []byte(s.cfg.JWTSecret), s.now())
+	if err != nil {
+		return nil, errors.Wrap(app.AuthErrorUnauthenticated, err.Error())
+	}
+
+	principal := &domain.Principal{Subject: claims.Subject, Role: domain.Role(claims.Role), Tenant: domain.UUID(claims.Tenant)}
+	err = principal.Validate()
+	if err != nil {
+		return nil, errors.Wrap(app.AuthErrorUnauthenticated, err.Error())
+	}
+	return principal, nil
+}
+
+// CreateAPIKey returns the new API key and its plain value, which is not persisted.
+// The key only grants access to the resources of its tenant, unless it's an admin one, so the others need a tenant.
+func (s *AuthService) CreateAPIKey(ctx context.Context, name string, role domain.Role, tenant domain.UUID) (*domain.APIKey, string, error) {
+	apiKey, key, err := domain.NewAPIKey(name, role, tenant)
+	if err != nil {
+		return nil, "", err
+	}
+	err = apiKey.Validate()
+	if err != nil {
+		return nil, "", errors.Wrap(app.DomainErrorInvalid, err.Error())
+	}
+
+	err = s.apiKeyRepository.Insert(ctx, apiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(apiKey.UUID), "role": string(role), "tenant": string(tenant)}).Info("created api key")
+	return apiKey, key, nil
+}
+
+func (s *AuthService) GetAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return s.apiKeyRepository.GetAll(ctx)
+}
+
+func (s *AuthService) RemoveAPIKey(ctx context.Context, uuid domain.UUID) error {
+	_, err := s.apiKeyRepository.Remove(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(uuid)}).Info("removed api key")
+	return nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/auth"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func NewAPIKeyRepositoryMockOK(key string, role domain.Role) *APIKeyRepositoryMock {
+	return &APIKeyRepositoryMock{
+		GetFunc: func(ctx context.Context, hash string) (*domain.APIKey, error) {
+			if hash != domain.HashAPIKey(key) {
+				return nil, app.DbErrorNotFound
+			}
+			return &domain.APIKey{UUID: "key1", Name: "k1", Hash: hash, Role: role, Tenant: "customer1"}, nil
+		},
+		InsertFunc: func(ctx context.Context, apiKey *domain.APIKey) error {
+			return nil
+		},
+	}
+}
+
+func TestAuthService_AuthenticateAPIKey(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	tests := []struct {
+		name    string
+		key     string
+		want    *domain.Principal
+		wantErr error
+	}{
+		{
+			name:    "given a registered api key, when it's authenticated, then its principal is returned",
+			key:     "key",
+			want:    &domain.Principal{Subject: "k1", Role: domain.RoleOperator, Tenant: "customer1"},
+			wantErr: nil,
+		},
+		{
+			name:    "given an unknown api key, when it's authenticated, then it fails",
+			key:     "other",
+			want:    nil,
+			wantErr: app.AuthErrorUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAuth(NewAPIKeyRepositoryMockOK("key", domain.RoleOperator), cfg, log)
+			got, err := s.AuthenticateAPIKey(context.Background(), tt.key)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuthService_AuthenticateJWT(t *testing.T) {
+
+	cfg := populateConfig()
+	cfg.JWTSecret = "secret"
+	log := logrus.New()
+	now := time.Now()
+
+	sign := func(claims *auth.Claims) string {
+		token, err := auth.Sign(claims, []byte(cfg.JWTSecret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    *domain.Principal
+		wantErr error
+	}{
+		{
+			name:    "given a valid token, when it's authenticated, then its principal is returned",
+			token:   sign(&auth.Claims{Subject: "john", Role: "admin", ExpiresAt: now.Add(time.Hour).Unix()}),
+			want:    &domain.Principal{Subject: "john", Role: domain.RoleAdmin},
+			wantErr: nil,
+		},
+		{
+			name:    "given an expired token, when it's authenticated, then it fails",
+			token:   sign(&auth.Claims{Subject: "john", Role: "admin", ExpiresAt: now.Add(-time.Hour).Unix()}),
+			want:    nil,
+			wantErr: app.AuthErrorUnauthenticated,
+		},
+		{
+			name:    "given a token of an operator with tenant, when it's authenticated, then its principal is returned",
+			token:   sign(&auth.Claims{Subject: "john", Role: "operator", Tenant: "customer1", ExpiresAt: now.Add(time.Hour).Unix()}),
+			want:    &domain.Principal{Subject: "john", Role: domain.RoleOperator, Tenant: "customer1"},
+			wantErr: nil,
+		},
+		{
+			name:    "given a token of an operator without tenant, when it's authenticated, then it fails",
+			token:   sign(&auth.Claims{Subject: "john", Role: "operator", ExpiresAt: now.Add(time.Hour).Unix()}),
+			want:    nil,
+			wantErr: app.AuthErrorUnauthenticated,
+		},
+		{
+			name:    "given a token with an unknown role, when it's authenticated, then it fails",
+			token:   sign(&auth.Claims{Subject: "john", Role: "root", ExpiresAt: now.Add(time.Hour).Unix()}),
+			want:    nil,
+			wantErr: app.AuthErrorUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAuth(&APIKeyRepositoryMock{}, cfg, log)
+			got, err := s.AuthenticateJWT(context.Background(), tt.token)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuthService_Bootstrap(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	// Already registered
+	cfg.BootstrapAPIKey = "key"
+	repository := NewAPIKeyRepositoryMockOK("key", domain.RoleAdmin)
+	assert.NoError(t, NewAuth(repository, cfg, log).Bootstrap(context.Background()))
+	assert.Equal(t, 0, len(repository.InsertCalls()))
+
+	// Not registered yet
+	cfg.BootstrapAPIKey = "other"
+	assert.NoError(t, NewAuth(repository, cfg, log).Bootstrap(context.Background()))
+	assert.Equal(t, 1, len(repository.InsertCalls()))
+	assert.Equal(t, domain.HashAPIKey("other"), repository.InsertCalls()[0].ApiKey.Hash)
+	assert.Equal(t, domain.RoleAdmin, repository.InsertCalls()[0].ApiKey.Role)
+}
+
+func TestAuthService_CreateAPIKey(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	tests := []struct {
+		name    string
+		role    domain.Role
+		tenant  domain.UUID
+		wantErr error
+	}{
+		{
+			name:    "given an admin without tenant, when its key is created, then it's registered",
+			role:    domain.RoleAdmin,
+			wantErr: nil,
+		},
+		{
+			name:    "given an operator with tenant, when its key is created, then it's registered",
+			role:    domain.RoleOperator,
+			tenant:  "customer1",
+			wantErr: nil,
+		},
+		{
+			name:    "given an operator without tenant, when its key is created, then it's refused",
+			role:    domain.RoleOperator,
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:    "given an unknown role, when its key is created, then it's refused",
+			role:    domain.Role("root"),
+			tenant:  "customer1",
+			wantErr: app.DomainErrorInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := NewAPIKeyRepositoryMockOK("key", domain.RoleAdmin)
+			_, _, err := NewAuth(repository, cfg, log).CreateAPIKey(context.Background(), "k1", tt.role, tt.tenant)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if tt.wantErr != nil {
+				assert.Empty(t, repository.InsertCalls())
+			} else {
+				assert.Len(t, repository.InsertCalls(), 1)
+			}
+		})
+	}
+}
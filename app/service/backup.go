@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/backup"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+type (
+	RestoreRepository interface {
+		Restore(ctx context.Context, customers []domain.Customer, projects []domain.Project, apiKeys []domain.APIKey, hostings []domain.Hosting) error
+	}
+
+	BackupService struct {
+		log                *logrus.Logger
+		cfg                *config.Config
+		hostingRepository  HostingRepository
+		customerRepository CustomerRepository
+		projectRepository  ProjectRepository
+		apiKeyRepository   APIKeyRepository
+		restoreRepository  RestoreRepository
+		serverDomain       ServerDomain
+		now                func() time.Time
+	}
+)
+
+func NewBackup(hostingRepository HostingRepository, customerRepository CustomerRepository, projectRepository ProjectRepository, apiKeyRepository APIKeyRepository, restoreRepository RestoreRepository, serverDomain ServerDomain, cfg *config.Config, log *logrus.Logger) *BackupService {
+	return &BackupService{
+		log:                log,
+		cfg:                cfg,
+		hostingRepository:  hostingRepository,
+		customerRepository: customerRepository,
+		projectRepository:  projectRepository,
+		apiKeyRepository:   apiKeyRepository,
+		restoreRepository:  restoreRepository,
+		serverDomain:       serverDomain,
+		now:                time.Now,
+	}
+}
+
+// Backup returns a snapshot of the stored data and the server status. The records are sorted by UUID,
+// so two backups of the same data only differ in their creation time.
+func (s *BackupService) Backup(ctx context.Context) (*backup.Snapshot, error) {
+	data, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data.Server = s.serverDomain.Snapshot()
+
+	snapshot, err := backup.New(data, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"hostings": len(data.Hostings), "checksum": snapshot.Checksum}).Info("backed up the store")
+	return snapshot, nil
+}
+
+// Restore loads the snapshot into an empty store. The snapshot is validated first as its records would be created:
+// its hostings must have the minimal sizes and fit in the server of the current capacity, its names index must point
+// to them, their customers and projects must be in it, and they must fit in their quotas.
+// Its records are written at once, so the readers never see a part of them, and a failure leaves nothing written.
+// The store is checked to be empty before, to tell what it has, and again by the store as it writes them.
+func (s *BackupService) Restore(ctx context.Context, snapshot *backup.Snapshot) error {
+	err := s.validate(ctx, &snapshot.Data)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.read(ctx)
+	if err != nil {
+		return err
+	}
+	if !stored.Empty() {
+		return errors.Wrapf(app.DbErrorInUse, "the store is not empty, it has %d hostings, %d customers, %d projects and %d api keys",
+			len(stored.Hostings), len(stored.Customers), len(stored.Projects), len(stored.APIKeys))
+	}
+
+	// The names of the hostings are indexed again, as the snapshot one is checked to match
+	data := &snapshot.Data
+	apiKeys := make([]domain.APIKey, len(data.APIKeys))
+	for z := range data.APIKeys {
+		apiKeys[z] = data.APIKeys[z].APIKey
+		apiKeys[z].Hash = data.APIKeys[z].Hash
+	}
+	err = s.restoreRepository.Restore(ctx, data.Customers, data.Projects, apiKeys, data.Hostings)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"hostings": len(data.Hostings), "checksum": snapshot.Checksum}).Info("restored the store")
+	return nil
+}
+
+// validate checks the snapshot data against the current capacity, before anything is written
+func (s *BackupService) validate(ctx context.Context, data *backup.Data) error {
+	for z := range data.Hostings {
+		err := data.Hostings[z].Validate(s.cfg)
+		if err != nil {
+			return errors.Wrapf(app.DomainErrorInvalid, "the hosting %s of the snapshot is not valid: %s", string(data.Hostings[z].UUID), err.Error())
+		}
+	}
+
+	err := checkReferences(data)
+	if err != nil {
+		return err
+	}
+	for z := range data.Projects {
+		err = domain.CheckUsage(&data.Projects[z], data.Hostings)
+		if err != nil {
+			return errors.Wrap(err, "the hostings of the snapshot don't fit in their quotas")
+		}
+	}
+	for z := range data.Customers {
+		err = data.Customers[z].CheckUsage(data.Hostings)
+		if err != nil {
+			return errors.Wrap(err, "the hostings of the snapshot don't fit in their quotas")
+		}
+	}
+
+	// The hostings must fit in a server of the current capacity
+	server, err := domain.NewServer(s.cfg)
+	if err != nil {
+		return err
+	}
+	err = server.SetUsage(ctx, domain.Used(data.Hostings))
+	if err != nil {
+		return errors.Wrap(err, "the hostings of the snapshot don't fit in the server")
+	}
+
+	discrepancies := domain.CheckConsistency(server.Snapshot(), data.Hostings, data.Indexes.HostingNames)
+	if len(discrepancies) > 0 {
+		details := make([]string, len(discrepancies))
+		for z, d := range discrepancies {
+			details[z] = d.Detail
+		}
+		return errors.Wrapf(app.DomainErrorInvalid, "the names index of the snapshot doesn't match its hostings: %s", strings.Join(details, ", "))
+	}
+	return nil
+}
+
+// checkReferences checks that the projects of the snapshot belong to its customers, and that its hostings belong
+// to its customers and projects, as the owner of their project
+func checkReferences(data *backup.Data) error {
+	customers := make(map[domain.UUID]bool, len(data.Customers))
+	for _, c := range data.Customers {
+		customers[c.UUID] = true
+	}
+	owners := make(map[domain.UUID]domain.UUID, len(data.Projects))
+	for _, p := range data.Projects {
+		if !customers[p.Owner] {
+			return errors.Wrapf(app.DomainErrorInvalid, "the project %s of the snapshot belongs to the missing customer %s", string(p.UUID), string(p.Owner))
+		}
+		owners[p.UUID] = p.Owner
+	}
+
+	for _, h := range data.Hostings {
+		if len(h.Owner) > 0 && !customers[h.Owner] {
+			return errors.Wrapf(app.DomainErrorInvalid, "the hosting %s of the snapshot belongs to the missing customer %s", string(h.UUID), string(h.Owner))
+		}
+		if len(h.Project) == 0 {
+			continue
+		}
+		owner, ok := owners[h.Project]
+		if !ok {
+			return errors.Wrapf(app.DomainErrorInvalid, "the hosting %s of the snapshot belongs to the missing project %s", string(h.UUID), string(h.Project))
+		}
+		if owner != h.Owner {
+			return errors.Wrapf(app.DomainErrorInvalid, "the project %s of the hosting %s doesn't belong to the customer %s", string(h.Project), string(h.UUID), string(h.Owner))
+		}
+	}
+	return nil
+}
+
+// read reads the stored records, sorted by UUID
+func (s *BackupService) read(ctx context.Context) (backup.Data, error) {
+	var (
+		data backup.Data
+		err  error
+	)
+
+	data.Hostings, err = s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return data, err
+	}
+	sort.Slice(data.Hostings, func(i, j int) bool { return data.Hostings[i].UUID < data.Hostings[j].UUID })
+
+	data.Customers, err = s.customerRepository.GetAll(ctx)
+	if err != nil {
+		return data, err
+	}
+	sort.Slice(data.Customers, func(i, j int) bool { return data.Customers[i].UUID < data.Customers[j].UUID })
+
+	data.Projects, err = s.projectRepository.GetAll(ctx)
+	if err != nil {
+		return data, err
+	}
+	sort.Slice(data.Projects, func(i, j int) bool { return data.Projects[i].UUID < data.Projects[j].UUID })
+
+	apiKeys, err := s.apiKeyRepository.GetAll(ctx)
+	if err != nil {
+		return data, err
+	}
+	sort.Slice(apiKeys, func(i, j int) bool { return apiKeys[i].UUID < apiKeys[j].UUID })
+	data.APIKeys = make([]backup.APIKey, len(apiKeys))
+	for z, k := range apiKeys {
+		data.APIKeys[z] = backup.APIKey{APIKey: k, Hash: k.Hash}
+	}
+
+	data.Indexes.HostingNames, err = s.hostingRepository.NameIndex(ctx)
+	return data, err
+}
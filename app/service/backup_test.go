@@ -0,0 +1,254 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/backup"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/repository"
+	"github.com/theskyinflames/cdmon2/app/store"
+	"github.com/theskyinflames/cdmon2/app/store/faults"
+)
+
+type backupFixture struct {
+	backup   *BackupService
+	server   *ServerService
+	customer *CustomerService
+	auth     *AuthService
+	store    *faults.FaultyStore
+	memory   *store.MemoryStore
+}
+
+// newBackupFixture builds the services over an empty memory store, with a server of the given cores, and 10 mb of memory and disk
+func newBackupFixture(t *testing.T, cores int) *backupFixture {
+	cfg := &config.Config{
+		TotalNumberOfCores:    cores,
+		TotalSizeOfMemoryMb:   10,
+		TotalSizeOfDiskMb:     10,
+		MinimalNumberOfCores:  1,
+		MinimalSizeOfMemoryMb: 1,
+		MinimalSizeOfDiskMb:   1,
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	server, err := domain.NewServer(cfg)
+	require.NoError(t, err)
+	memory := store.NewMemoryStore()
+	faulty := faults.New(memory)
+	hostings := repository.NewHostingReposytoryMap(cfg, faulty)
+	customers := repository.NewCustomerRepositoryMap(cfg, faulty)
+	projects := repository.NewProjectRepositoryMap(cfg, faulty)
+	apiKeys := repository.NewAPIKeyRepositoryMap(cfg, faulty)
+	restore := repository.NewRestoreRepositoryMap(cfg, faulty)
+
+	serverService := NewServer(hostings, customers, projects, server, cfg, log)
+	backupService := NewBackup(hostings, customers, projects, apiKeys, restore, server, cfg, log)
+	backupService.now = func() time.Time { return time.Date(2019, 2, 10, 0, 0, 0, 0, time.UTC) }
+	return &backupFixture{
+		backup:   backupService,
+		server:   serverService,
+		customer: NewCustomer(customers, projects, hostings, serverService, cfg, log),
+		auth:     NewAuth(apiKeys, cfg, log),
+		store:    faulty,
+		memory:   memory,
+	}
+}
+
+// populate stores a customer with a project and an api key, and two hostings
+func (f *backupFixture) populate(t *testing.T) {
+	ctx := context.Background()
+	customer, err := f.customer.CreateCustomer(ctx, "c1", domain.Quota{Cores: 5, MemoryMb: 5, DiskMb: 5})
+	require.NoError(t, err)
+	project, err := f.customer.CreateProject(ctx, customer, "p1", domain.Quota{Cores: 2, MemoryMb: 2, DiskMb: 2})
+	require.NoError(t, err)
+	_, _, err = f.auth.CreateAPIKey(ctx, "k1", domain.RoleOperator, customer)
+	require.NoError(t, err)
+	_, err = f.server.CreateHosting(ctx, nil, "h1", 2, 2, 2, customer, project)
+	require.NoError(t, err)
+	_, err = f.server.CreateHosting(ctx, nil, "h2", 3, 1, 1, "", "")
+	require.NoError(t, err)
+}
+
+func TestBackupService_BackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source := newBackupFixture(t, 10)
+	source.populate(t)
+
+	snapshot, err := source.backup.Backup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, backup.Version, snapshot.Version)
+	assert.Len(t, snapshot.Data.Hostings, 2)
+	assert.Len(t, snapshot.Data.Customers, 1)
+	assert.Len(t, snapshot.Data.Projects, 1)
+	require.Len(t, snapshot.Data.APIKeys, 1)
+	assert.NotEmpty(t, snapshot.Data.APIKeys[0].Hash)
+	assert.Len(t, snapshot.Data.Indexes.HostingNames, 2)
+	assert.Equal(t, 5, snapshot.Data.Server.AvailableCores)
+
+	// The snapshot survives its JSON encoding
+	var b bytes.Buffer
+	require.NoError(t, snapshot.Write(&b))
+	read, err := backup.Read(&b)
+	require.NoError(t, err)
+
+	// Restored into an empty store of a smaller server, the same data is backed up again
+	target := newBackupFixture(t, 5)
+	require.NoError(t, target.backup.Restore(ctx, read))
+	require.NoError(t, target.server.LoadServer(ctx))
+	restored, err := target.backup.Backup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, restored.Data.Server.AvailableCores)
+	restored.Data.Server = snapshot.Data.Server
+	assert.Equal(t, snapshot.Data, restored.Data)
+
+	// The names of the customers and the projects are indexed again
+	_, err = target.customer.CreateCustomer(ctx, "c1", domain.Quota{Cores: 1, MemoryMb: 1, DiskMb: 1})
+	assert.Equal(t, app.DbErrorAlreadyExist, errors.Cause(err))
+
+	// A restore is only done into an empty store
+	assert.Equal(t, app.DbErrorInUse, errors.Cause(target.backup.Restore(ctx, read)))
+}
+
+func TestBackupService_Restore(t *testing.T) {
+	ctx := context.Background()
+	source := newBackupFixture(t, 10)
+	source.populate(t)
+	snapshot, err := source.backup.Backup(ctx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cores    int
+		edit     func(data *backup.Data)
+		scenario []faults.Rule
+		wantErr  error
+	}{
+		{
+			name:    "given a server smaller than the hostings of the snapshot, when it's restored, then it's refused",
+			cores:   4,
+			wantErr: app.DomainErrorQuotaExceeded,
+		},
+		{
+			name:  "given a hosting below the minimal size, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				data.Hostings[0].Cores = 0
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a names index which doesn't match the hostings, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				data.Indexes.HostingNames = map[string]domain.UUID{"h1": "u1"}
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a hosting of a project which is not in the snapshot, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				hostingNamed(data, "h1").Project = "missing"
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a hosting of a customer which is not in the snapshot, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				hostingNamed(data, "h2").Owner = "missing"
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a hosting of a project of another customer, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				data.Customers = append(data.Customers, domain.Customer{UUID: "other", Name: "other"})
+				hostingNamed(data, "h1").Owner = "other"
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a project of a customer which is not in the snapshot, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				data.Customers = nil
+				hostingNamed(data, "h1").Owner = ""
+				hostingNamed(data, "h1").Project = ""
+			},
+			wantErr: app.DomainErrorInvalid,
+		},
+		{
+			name:  "given a hosting over the quota of its project, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				hostingNamed(data, "h1").Cores = 3
+			},
+			wantErr: app.DomainErrorQuotaExceeded,
+		},
+		{
+			name:  "given a hosting over the quota of its customer, when it's restored, then it's refused",
+			cores: 10,
+			edit: func(data *backup.Data) {
+				data.Customers[0].Quota.MemoryMb = 1
+			},
+			wantErr: app.DomainErrorQuotaExceeded,
+		},
+		{
+			name:     "given a store which fails to write the records, when it's restored, then nothing is left",
+			cores:    10,
+			scenario: []faults.Rule{{Op: config.StoreOpLoad}},
+			wantErr:  faults.ErrInjected,
+		},
+		{
+			name:     "given a store which is written after it's checked to be empty, when it's restored, then it's refused",
+			cores:    10,
+			scenario: []faults.Rule{{Op: config.StoreOpLoad, Err: app.DbErrorInUse}},
+			wantErr:  app.DbErrorInUse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := snapshot.Data
+			data.Hostings = append([]domain.Hosting(nil), data.Hostings...)
+			data.Customers = append([]domain.Customer(nil), data.Customers...)
+			if tt.edit != nil {
+				tt.edit(&data)
+			}
+			edited, err := backup.New(data, snapshot.CreatedAt)
+			require.NoError(t, err)
+
+			target := newBackupFixture(t, tt.cores)
+			target.store.Script(tt.scenario...)
+			err = target.backup.Restore(ctx, edited)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+
+			keys, err := target.memory.Keys(ctx, "*")
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+		})
+	}
+}
+
+// hostingNamed returns the hosting of the data with the name
+func hostingNamed(data *backup.Data, name string) *domain.Hosting {
+	for z := range data.Hostings {
+		if data.Hostings[z].Name == name {
+			return &data.Hostings[z]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+type (
+	CustomerService struct {
+		// Customer changes are serialized with the hosting ones,
+		// so a quota can't be reduced while a hosting is being allocated.
+		locker             sync.Locker
+		log                *logrus.Logger
+		cfg                *config.Config
+		customerRepository CustomerRepository
+		projectRepository  ProjectRepository
+		hostingRepository  HostingRepository
+	}
+)
+
+func NewCustomer(customerRepository CustomerRepository, projectRepository ProjectRepository, hostingRepository HostingRepository, locker sync.Locker, cfg *config.Config, log *logrus.Logger) *CustomerService {
+	return &CustomerService{
+		locker:             locker,
+		log:                log,
+		cfg:                cfg,
+		customerRepository: customerRepository,
+		projectRepository:  projectRepository,
+		hostingRepository:  hostingRepository,
+	}
+}
+
+func (s *CustomerService) CreateCustomer(ctx context.Context, name string, quota domain.Quota) (domain.UUID, error) {
+
+	customer, err := domain.NewCustomer(name, quota)
+	if err != nil {
+		return domain.UUID(""), err
+	}
+
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	err = s.customerRepository.Insert(ctx, customer)
+	if err != nil {
+		return domain.UUID(""), err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(customer.UUID)}).Info("created customer")
+	return customer.UUID, nil
+}
+
+// GetCustomers returns the customers accessible by the principal, all of them for the administrators
+func (s *CustomerService) GetCustomers(ctx context.Context, principal *domain.Principal) ([]domain.Customer, error) {
+	customers, err := s.customerRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if principal.CanAccessAll() {
+		return customers, nil
+	}
+
+	accessible := make([]domain.Customer, 0, 1)
+	for _, c := range customers {
+		if principal.CanAccess(c.UUID) {
+			accessible = append(accessible, c)
+		}
+	}
+	return accessible, nil
+}
+
+func (s *CustomerService) GetCustomer(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.Customer, error) {
+	// Checked before the customer lookup, so it's not disclosed whether the customer exists
+	if !principal.CanAccess(uuid) {
+		return nil, errors.Wrapf(app.AuthErrorForbidden, "the customer %s is not accessible", string(uuid))
+	}
+	return s.customerRepository.Get(ctx, uuid)
+}
+
+func (s *CustomerService) UpdateCustomer(ctx context.Context, customer *domain.Customer) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	// The new quota must hold the resources already taken by the customer
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	err = customer.CheckUsage(hostings)
+	if err != nil {
+		return err
+	}
+
+	err = s.customerRepository.Update(ctx, customer)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(customer.UUID)}).Info("updated customer")
+	return nil
+}
+
+func (s *CustomerService) RemoveCustomer(ctx context.Context, uuid domain.UUID) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	// A customer can't be removed while it owns hostings or projects
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, h := range hostings {
+		if h.Owner == uuid {
+			return errors.Wrapf(app.DbErrorInUse, "customer %s owns the hosting %s", string(uuid), string(h.UUID))
+		}
+	}
+	projects, err := s.projectRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if p.Owner == uuid {
+			return errors.Wrapf(app.DbErrorInUse, "customer %s owns the project %s", string(uuid), string(p.UUID))
+		}
+	}
+
+	_, err = s.customerRepository.Remove(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(uuid)}).Info("removed customer")
+	return nil
+}
+
+// GetCustomerUsage returns the resources consumed and the limits at every level of the customer quotas hierarchy
+func (s *CustomerService) GetCustomerUsage(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.CustomerUsage, error) {
+	if !principal.CanAccess(uuid) {
+		return nil, errors.Wrapf(app.AuthErrorForbidden, "the usage of the customer %s is not accessible", string(uuid))
+	}
+
+	customer, err := s.customerRepository.Get(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewCustomerUsage(customer, projects, hostings), nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestCustomerService_UpdateCustomer(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	tests := []struct {
+		name     string
+		customer *domain.Customer
+		wantErr  error
+	}{
+		{
+			name:     "given a customer, when its quota is updated over its usage, then all works fine",
+			customer: &domain.Customer{UUID: "customer1", Name: "c1", Quota: domain.Quota{Cores: 1, MemoryMb: 1, DiskMb: 1}},
+			wantErr:  nil,
+		},
+		{
+			name:     "given a customer, when its quota is reduced under its usage, then it fails",
+			customer: &domain.Customer{UUID: "customer1", Name: "c1", Quota: domain.Quota{Cores: 1, MemoryMb: 1, DiskMb: 1}},
+			wantErr:  app.DomainErrorQuotaExceeded,
+		},
+	}
+	for z, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerRepository := &CustomerRepositoryMock{
+				UpdateFunc: func(ctx context.Context, customer *domain.Customer) error {
+					return nil
+				},
+			}
+			hostingRepository := NewHostingRepositoryMockOK()
+			if z == 1 {
+				hostingRepository.GetAllFunc = func(ctx context.Context) ([]domain.Hosting, error) {
+					hostings := populateHostings()
+					hostings[1].Owner = "customer1"
+					return hostings, nil
+				}
+			}
+			s := NewCustomer(customerRepository, &ProjectRepositoryMock{}, hostingRepository, &sync.Mutex{}, cfg, log)
+			err := s.UpdateCustomer(context.Background(), tt.customer)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if tt.wantErr != nil {
+				assert.Equal(t, 0, len(customerRepository.UpdateCalls()))
+			}
+		})
+	}
+}
+
+func TestCustomerService_RemoveCustomer(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	tests := []struct {
+		name    string
+		uuid    domain.UUID
+		wantErr error
+	}{
+		{
+			name:    "given a customer without hostings, when it's removed, then all works fine",
+			uuid:    "customer2",
+			wantErr: nil,
+		},
+		{
+			name:    "given a customer which owns hostings, when it's removed, then it fails",
+			uuid:    "customer1",
+			wantErr: app.DbErrorInUse,
+		},
+		{
+			name:    "given a customer which owns projects, when it's removed, then it fails",
+			uuid:    "customer3",
+			wantErr: app.DbErrorInUse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerRepository := &CustomerRepositoryMock{
+				RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+					return &domain.Customer{UUID: uuid}, nil
+				},
+			}
+			s := NewCustomer(customerRepository, NewProjectRepositoryMockOK(), NewHostingRepositoryMockOK(), &sync.Mutex{}, cfg, log)
+			err := s.RemoveCustomer(context.Background(), tt.uuid)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			if tt.wantErr != nil {
+				assert.Equal(t, 0, len(customerRepository.RemoveCalls()))
+			} else {
+				assert.Equal(t, 1, len(customerRepository.RemoveCalls()))
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+func (s *CustomerService) CreateProject(ctx context.Context, owner domain.UUID, name string, quota domain.Quota) (domain.UUID, error) {
+
+	project, err := domain.NewProject(owner, name, quota)
+	if err != nil {
+		return domain.UUID(""), err
+	}
+
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	// The project owner must exist
+	_, err = s.customerRepository.Get(ctx, owner)
+	if err != nil {
+		return domain.UUID(""), err
+	}
+
+	err = s.projectRepository.Insert(ctx, project)
+	if err != nil {
+		return domain.UUID(""), err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(project.UUID), "owner": string(owner)}).Info("created project")
+	return project.UUID, nil
+}
+
+func (s *CustomerService) GetProjects(ctx context.Context, principal *domain.Principal, owner domain.UUID) ([]domain.Project, error) {
+	if !principal.CanAccess(owner) {
+		return nil, errors.Wrapf(app.AuthErrorForbidden, "the projects of the customer %s are not accessible", string(owner))
+	}
+
+	_, err := s.customerRepository.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]domain.Project, 0, len(projects))
+	for _, p := range projects {
+		if p.Owner == owner {
+			owned = append(owned, p)
+		}
+	}
+	return owned, nil
+}
+
+// GetProject returns the project when it's accessible by the principal. As for the hostings, a missing
+// project is forbidden too for the tenants, so they can't tell whether the projects of others exist.
+func (s *CustomerService) GetProject(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.Project, error) {
+	forbidden := errors.Wrapf(app.AuthErrorForbidden, "the project %s is not accessible", string(uuid))
+
+	project, err := s.projectRepository.Get(ctx, uuid)
+	if err != nil {
+		if errors.Cause(err) == app.DbErrorNotFound && !principal.CanAccessAll() {
+			return nil, forbidden
+		}
+		return nil, err
+	}
+
+	if !principal.CanAccess(project.Owner) {
+		return nil, forbidden
+	}
+	return project, nil
+}
+
+func (s *CustomerService) UpdateProject(ctx context.Context, project *domain.Project) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	// A project can't be moved to another customer
+	old, err := s.projectRepository.Get(ctx, project.UUID)
+	if err != nil {
+		return err
+	}
+	project.Owner = old.Owner
+
+	// The new quota must hold the resources already taken by the project hostings
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	err = domain.CheckUsage(project, hostings)
+	if err != nil {
+		return err
+	}
+
+	err = s.projectRepository.Update(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(project.UUID)}).Info("updated project")
+	return nil
+}
+
+func (s *CustomerService) RemoveProject(ctx context.Context, uuid domain.UUID) error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+
+	// A project can't be removed while it has hostings
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, h := range hostings {
+		if h.Project == uuid {
+			return errors.Wrapf(app.DbErrorInUse, "project %s has the hosting %s", string(uuid), string(h.UUID))
+		}
+	}
+
+	_, err = s.projectRepository.Remove(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(uuid)}).Info("removed project")
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+)
+
+// LoadServer takes the server resources of the stored hostings, so a server started over a store
+// which already has hostings makes them available. If they don't fit in the server, they're taken all the same,
+// so no hosting can be created until some are removed or the server is resized, and app.DomainErrorQuotaExceeded
+// is returned to tell it.
+func (s *ServerService) LoadServer(ctx context.Context) error {
+	s.Lock()
+	defer s.Unlock()
+
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	err = s.serverDomain.LoadUsage(ctx, domain.Used(hostings))
+	if err != nil {
+		return err
+	}
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"hostings": len(hostings)}).Info("loaded the server")
+	return nil
+}
+
+// Reconcile checks the server resources and the names index against the stored hostings, see domain.CheckConsistency,
+// and returns the discrepancies found. If repair is set, the repairable ones are repaired. The check is done out
+// of any change of the hostings, so it doesn't find the discrepancies of a change in progress.
+func (s *ServerService) Reconcile(ctx context.Context, repair bool) ([]domain.Discrepancy, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.hostingRepository.NameIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log := requestid.Logger(s.log, ctx)
+	discrepancies := domain.CheckConsistency(s.serverDomain.Snapshot(), hostings, names)
+	if len(discrepancies) == 0 {
+		log.Info("the server and the hostings are consistent")
+		return discrepancies, nil
+	}
+
+	// The availability is recalculated at once for all the resources
+	availabilityRepaired := false
+	for z := range discrepancies {
+		d := &discrepancies[z]
+		if repair && d.Repairable {
+			switch d.Kind {
+			case domain.DiscrepancyAvailability:
+				if !availabilityRepaired {
+					err = s.serverDomain.SetUsage(ctx, domain.Used(hostings))
+					availabilityRepaired = err == nil
+				}
+			case domain.DiscrepancyMissingName, domain.DiscrepancyWrongName:
+				err = s.hostingRepository.SetName(ctx, d.Subject, d.UUID)
+			case domain.DiscrepancyDanglingName:
+				err = s.hostingRepository.RemoveName(ctx, d.Subject)
+			}
+			if err != nil {
+				log.WithError(err).Errorf("the discrepancy %s of %s could not be repaired", d.Kind, d.Subject)
+				return discrepancies, err
+			}
+			d.Repaired = true
+		}
+
+		log.WithFields(logrus.Fields{"kind": d.Kind, "subject": d.Subject, "repaired": d.Repaired}).Warn(d.Detail)
+	}
+	return discrepancies, nil
+}
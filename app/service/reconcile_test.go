@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestServerService_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	f := newFaultyServer(t)
+	h1, err := f.service.CreateHosting(ctx, nil, "h1", 2, 2, 2, "", "")
+	require.NoError(t, err)
+	_, err = f.service.CreateHosting(ctx, nil, "h2", 1, 1, 1, "", "")
+	require.NoError(t, err)
+
+	discrepancies, err := f.service.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, discrepancies)
+
+	// Break the server availability and the names index behind the service
+	require.NoError(t, f.server.SetUsage(ctx, domain.Quota{Cores: 1, MemoryMb: 3, DiskMb: 3}))
+	require.NoError(t, f.inspect.RemoveName(ctx, "h2"))
+	require.NoError(t, f.inspect.SetName(ctx, "h1", "0"))
+	require.NoError(t, f.inspect.SetName(ctx, "h3", "u3"))
+
+	want := []domain.Discrepancy{
+		{Kind: domain.DiscrepancyAvailability, Subject: "cores", Repairable: true, Detail: "9 cores are available, but the hostings leave 7 of 10"},
+		{Kind: domain.DiscrepancyWrongName, Subject: "h1", UUID: h1, Repairable: true, Detail: `the name h1 points to "0" instead of the hosting ` + string(h1)},
+		{Kind: domain.DiscrepancyMissingName, Subject: "h2", Repairable: true},
+		{Kind: domain.DiscrepancyDanglingName, Subject: "h3", Repairable: true, Detail: "the name h3 is indexed, but there is no hosting with it"},
+	}
+	check := func(repair bool) {
+		discrepancies, err := f.service.Reconcile(ctx, repair)
+		require.NoError(t, err)
+		require.Len(t, discrepancies, len(want))
+		for z, d := range discrepancies {
+			assert.Equal(t, want[z].Kind, d.Kind)
+			assert.Equal(t, want[z].Subject, d.Subject)
+			assert.Equal(t, repair, d.Repaired)
+			if len(want[z].Detail) > 0 {
+				assert.Equal(t, want[z].Detail, d.Detail)
+			}
+		}
+	}
+
+	// A check doesn't repair anything
+	check(false)
+	check(false)
+
+	// A repair leaves them consistent
+	check(true)
+	discrepancies, err = f.service.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, discrepancies)
+	f.assertConsistent(t)
+}
+
+func TestServerService_Reconcile_OverCapacity(t *testing.T) {
+	ctx := context.Background()
+	f := newFaultyServer(t)
+	_, err := f.service.CreateHosting(ctx, nil, "h1", 8, 2, 2, "", "")
+	require.NoError(t, err)
+
+	// The server shrinks behind the service, below its hostings
+	f.server.TotalCores = 5
+
+	discrepancies, err := f.service.Reconcile(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, domain.DiscrepancyOverCapacity, discrepancies[0].Kind)
+	assert.False(t, discrepancies[0].Repaired)
+}
+
+func TestServerService_LoadServer(t *testing.T) {
+	ctx := context.Background()
+	f := newFaultyServer(t)
+	_, err := f.service.CreateHosting(ctx, nil, "h1", 2, 3, 4, "", "")
+	require.NoError(t, err)
+
+	// A server started over the same store takes the resources of the stored hostings
+	server, err := domain.NewServer(&config.Config{TotalNumberOfCores: 10, TotalSizeOfMemoryMb: 10, TotalSizeOfDiskMb: 10})
+	require.NoError(t, err)
+	s := NewServer(f.hostingRepository, nil, nil, server, f.service.cfg, f.service.log)
+	require.NoError(t, s.LoadServer(ctx))
+	status := server.Snapshot()
+	assert.Equal(t, [3]int{8, 7, 6}, [3]int{status.AvailableCores, status.AvailableSizeOfMemoryMb, status.AvailableSizeOfDiskMb})
+
+	// Even if they don't fit in it, which is reported
+	server, err = domain.NewServer(&config.Config{TotalNumberOfCores: 1, TotalSizeOfMemoryMb: 10, TotalSizeOfDiskMb: 10})
+	require.NoError(t, err)
+	s = NewServer(f.hostingRepository, nil, nil, server, f.service.cfg, f.service.log)
+	assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(s.LoadServer(ctx)))
+	status = server.Snapshot()
+	assert.Equal(t, [3]int{-1, 7, 6}, [3]int{status.AvailableCores, status.AvailableSizeOfMemoryMb, status.AvailableSizeOfDiskMb})
+}
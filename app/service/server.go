@@ -1,51 +1,92 @@
 package service
 
 import (
+	"context"
+	"strings"
 	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
+	"github.com/theskyinflames/cdmon2/app/uow"
 )
 
 type (
 	HostingRepository interface {
-		Get(uuid domain.UUID) (*domain.Hosting, error)
-		GetAll() ([]domain.Hosting, error)
-		Insert(hosting *domain.Hosting) error
-		Update(hosting *domain.Hosting) error
-		Remove(uuid domain.UUID) (*domain.Hosting, error)
+		Get(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error)
+		GetAll(ctx context.Context) ([]domain.Hosting, error)
+		Insert(ctx context.Context, hosting *domain.Hosting) error
+		Update(ctx context.Context, hosting *domain.Hosting) error
+		Remove(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error)
+		NameIndex(ctx context.Context) (map[string]domain.UUID, error)
+		SetName(ctx context.Context, name string, uuid domain.UUID) error
+		RemoveName(ctx context.Context, name string) error
+	}
+
+	CustomerRepository interface {
+		Get(ctx context.Context, uuid domain.UUID) (*domain.Customer, error)
+		GetAll(ctx context.Context) ([]domain.Customer, error)
+		Insert(ctx context.Context, customer *domain.Customer) error
+		Update(ctx context.Context, customer *domain.Customer) error
+		Remove(ctx context.Context, uuid domain.UUID) (*domain.Customer, error)
+	}
+
+	ProjectRepository interface {
+		Get(ctx context.Context, uuid domain.UUID) (*domain.Project, error)
+		GetAll(ctx context.Context) ([]domain.Project, error)
+		Insert(ctx context.Context, project *domain.Project) error
+		Update(ctx context.Context, project *domain.Project) error
+		Remove(ctx context.Context, uuid domain.UUID) (*domain.Project, error)
 	}
 
 	ServerDomain interface {
-		AddHosting(hosting *domain.Hosting, cfg *config.Config) error
-		UpdateHosting(hosting, old *domain.Hosting, cfg *config.Config) error
-		RemoveHosting(hosting *domain.Hosting) error
+		AddHosting(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error
+		UpdateHosting(ctx context.Context, hosting, old *domain.Hosting, cfg *config.Config) error
+		RemoveHosting(ctx context.Context, hosting *domain.Hosting) error
+		Resize(ctx context.Context, cores, memorymb, diskmb int) error
+		SetUsage(ctx context.Context, used domain.Quota) error
+		LoadUsage(ctx context.Context, used domain.Quota) error
+		Snapshot() domain.ServerStatus
 	}
 
 	ServerService struct {
 		sync.Mutex
-		log               *logrus.Logger
-		cfg               *config.Config
-		hostingRepository HostingRepository
-		serverDomain      ServerDomain
+		log                *logrus.Logger
+		cfg                *config.Config
+		hostingRepository  HostingRepository
+		customerRepository CustomerRepository
+		projectRepository  ProjectRepository
+		serverDomain       ServerDomain
 	}
 )
 
-func NewServer(hostingRepository HostingRepository, serverDomain ServerDomain, cfg *config.Config, log *logrus.Logger) *ServerService {
+func NewServer(hostingRepository HostingRepository, customerRepository CustomerRepository, projectRepository ProjectRepository, serverDomain ServerDomain, cfg *config.Config, log *logrus.Logger) *ServerService {
 	return &ServerService{
-		Mutex:             sync.Mutex{},
-		log:               log,
-		cfg:               cfg,
-		hostingRepository: hostingRepository,
-		serverDomain:      serverDomain,
+		Mutex:              sync.Mutex{},
+		log:                log,
+		cfg:                cfg,
+		hostingRepository:  hostingRepository,
+		customerRepository: customerRepository,
+		projectRepository:  projectRepository,
+		serverDomain:       serverDomain,
 	}
 }
 
-func (s *ServerService) CreateHosting(name string, cores int, memorymb int, diskmb int) (domain.UUID, error) {
+func (s *ServerService) CreateHosting(ctx context.Context, principal *domain.Principal, name string, cores int, memorymb int, diskmb int, owner, project domain.UUID) (domain.UUID, error) {
+
+	// The hostings created by a tenant belong to it
+	if len(owner) == 0 && !principal.CanAccessAll() {
+		owner = principal.Tenant
+	}
+	if !principal.CanAccess(owner) {
+		return domain.UUID(""), errors.Wrapf(app.AuthErrorForbidden, "the hosting can't be created for the customer %s", string(owner))
+	}
 
-	hosting, err := domain.NewHosting(name, cores, memorymb, diskmb)
+	hosting, err := domain.NewHosting(name, cores, memorymb, diskmb, owner, project)
 	if err != nil {
 		return domain.UUID(""), err
 	}
@@ -53,74 +94,284 @@ func (s *ServerService) CreateHosting(name string, cores int, memorymb int, disk
 	s.Lock()
 	defer s.Unlock()
 
-	// Take server resources
-	err = s.serverDomain.AddHosting(hosting, s.cfg)
+	// Check the project and owner quotas
+	err = s.checkQuotas(ctx, hosting, nil)
 	if err != nil {
 		return domain.UUID(""), err
 	}
 
-	// Persist the new hosting
-	err = s.hostingRepository.Insert(hosting)
+	// Take server resources and persist the new hosting, both or none
+	work := uow.New(ctx)
+	err = work.Do(ctx, s.addToServer(hosting), s.removeFromServer(hosting))
+	if err == nil {
+		err = work.Do(ctx, s.insert(hosting), s.remove(hosting))
+	}
 	if err != nil {
-		s.serverDomain.RemoveHosting(hosting)
-		return domain.UUID(""), err
+		return domain.UUID(""), s.abort(ctx, work, err)
 	}
+	work.Commit()
 
-	s.log.WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("created hosting")
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("created hosting")
 	return hosting.UUID, nil
 }
 
-func (s *ServerService) GetHostings() ([]domain.Hosting, error) {
-	return s.hostingRepository.GetAll()
+// GetHostings returns the hostings the principal can access
+func (s *ServerService) GetHostings(ctx context.Context, principal *domain.Principal) ([]domain.Hosting, error) {
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accessible := make([]domain.Hosting, 0, len(hostings))
+	for _, h := range hostings {
+		if principal.CanAccess(h.Owner) {
+			accessible = append(accessible, h)
+		}
+	}
+	return accessible, nil
 }
 
-func (s *ServerService) RemoveHosting(uuid domain.UUID) error {
+func (s *ServerService) GetCustomerHostings(ctx context.Context, principal *domain.Principal, owner domain.UUID) ([]domain.Hosting, error) {
+	// Checked before the customer lookup, so it's not disclosed whether the customer exists
+	if !principal.CanAccess(owner) {
+		return nil, errors.Wrapf(app.AuthErrorForbidden, "the hostings of the customer %s are not accessible", string(owner))
+	}
+
+	_, err := s.customerRepository.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]domain.Hosting, 0, len(hostings))
+	for _, h := range hostings {
+		if h.Owner == owner {
+			owned = append(owned, h)
+		}
+	}
+	return owned, nil
+}
+
+func (s *ServerService) RemoveHosting(ctx context.Context, principal *domain.Principal, uuid domain.UUID) error {
 	s.Lock()
 	defer s.Unlock()
 
-	// Remove the hosting from persistence layer
-	hosting, err := s.hostingRepository.Remove(uuid)
+	hosting, err := s.getAccessibleHosting(ctx, principal, uuid)
 	if err != nil {
 		return err
 	}
 
-	// Release hosting resources in the server
-	err = s.serverDomain.RemoveHosting(hosting)
+	// Remove the hosting from persistence layer and release its resources in the server, both or none
+	work := uow.New(ctx)
+	err = work.Do(ctx, s.remove(hosting), s.insert(hosting))
+	if err == nil {
+		err = work.Do(ctx, s.removeFromServer(hosting), s.addToServer(hosting))
+	}
 	if err != nil {
-		s.hostingRepository.Insert(hosting)
-		return err
+		return s.abort(ctx, work, err)
 	}
+	work.Commit()
 
-	s.log.WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("removed hosting")
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("removed hosting")
 	return nil
 }
 
-func (s *ServerService) UpdateHosting(hosting *domain.Hosting) error {
+func (s *ServerService) UpdateHosting(ctx context.Context, principal *domain.Principal, hosting *domain.Hosting) error {
 	s.Lock()
 	defer s.Unlock()
 
 	// Getting the current version
-	old, err := s.hostingRepository.Get(hosting.UUID)
+	old, err := s.getAccessibleHosting(ctx, principal, hosting.UUID)
 	if err != nil {
 		return err
 	}
 
-	// Recalculate server resources availability
-	err = s.serverDomain.UpdateHosting(hosting, old, s.cfg)
+	// A hosting keeps its owner and project unless new ones are given
+	if len(hosting.Owner) == 0 {
+		hosting.Owner = old.Owner
+	}
+	if len(hosting.Project) == 0 {
+		hosting.Project = old.Project
+	}
+
+	// A tenant can't hand over its hostings to another one
+	if !principal.CanAccess(hosting.Owner) {
+		return errors.Wrapf(app.AuthErrorForbidden, "the hosting can't be moved to the customer %s", string(hosting.Owner))
+	}
+
+	// Check the project and owner quotas
+	err = s.checkQuotas(ctx, hosting, old)
 	if err != nil {
 		return err
 	}
 
-	// Persist the new hosting status
-	err = s.hostingRepository.Update(hosting)
+	// Recalculate server resources availability and persist the new hosting status, both or none
+	work := uow.New(ctx)
+	err = work.Do(ctx, s.updateInServer(hosting, old), s.updateInServer(old, hosting))
+	if err == nil {
+		err = work.Do(ctx, s.update(hosting), s.update(old))
+	}
 	if err != nil {
-		return err
+		return s.abort(ctx, work, err)
 	}
+	work.Commit()
 
-	s.log.WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("updated hosting")
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"uuid": string(hosting.UUID)}).Info("updated hosting")
 	return nil
 }
 
-func (s *ServerService) GetServerStatus() *domain.Server {
-	return s.serverDomain.(*domain.Server)
+// abort rolls back the work which failed because of err. A failed rollback leaves the server resources
+// out of sync with the persisted hostings, so it's logged apart from the error returned to the caller.
+func (s *ServerService) abort(ctx context.Context, work *uow.UnitOfWork, err error) error {
+	rerr := work.Rollback()
+	if rerr != nil {
+		requestid.Logger(s.log, ctx).WithError(rerr).Errorf("the rollback after %s failed", err)
+		return errors.Wrap(err, rerr.Error())
+	}
+	return err
+}
+
+func (s *ServerService) addToServer(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return s.serverDomain.AddHosting(ctx, hosting, s.cfg)
+	}
+}
+
+func (s *ServerService) updateInServer(hosting, old *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return s.serverDomain.UpdateHosting(ctx, hosting, old, s.cfg)
+	}
+}
+
+func (s *ServerService) removeFromServer(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return s.serverDomain.RemoveHosting(ctx, hosting)
+	}
+}
+
+func (s *ServerService) insert(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return s.hostingRepository.Insert(ctx, hosting)
+	}
+}
+
+func (s *ServerService) update(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		return s.hostingRepository.Update(ctx, hosting)
+	}
+}
+
+func (s *ServerService) remove(hosting *domain.Hosting) uow.Action {
+	return func(ctx context.Context) error {
+		_, err := s.hostingRepository.Remove(ctx, hosting.UUID)
+		return err
+	}
+}
+
+// getAccessibleHosting returns the hosting if the principal can access it.
+// Only the callers which can act across tenants are told whether the hosting exists,
+// for the rest a missing hosting is as forbidden as a hosting of another tenant.
+func (s *ServerService) getAccessibleHosting(ctx context.Context, principal *domain.Principal, uuid domain.UUID) (*domain.Hosting, error) {
+	forbidden := errors.Wrapf(app.AuthErrorForbidden, "the hosting %s is not accessible", string(uuid))
+
+	hosting, err := s.hostingRepository.Get(ctx, uuid)
+	if err != nil {
+		if errors.Cause(err) == app.DbErrorNotFound && !principal.CanAccessAll() {
+			return nil, forbidden
+		}
+		return nil, err
+	}
+
+	if !principal.CanAccess(hosting.Owner) {
+		return nil, forbidden
+	}
+	return hosting, nil
+}
+
+// checkQuotas walks the quotas hierarchy of the hosting, from its project up to its owner.
+// Hostings without owner are only limited by the server resources.
+func (s *ServerService) checkQuotas(ctx context.Context, hosting, old *domain.Hosting) error {
+	var scopes []domain.QuotaScope
+
+	if len(hosting.Project) > 0 {
+		project, err := s.projectRepository.Get(ctx, hosting.Project)
+		if err != nil {
+			return err
+		}
+
+		// The hosting of a project belongs to the project owner
+		if len(hosting.Owner) == 0 {
+			hosting.Owner = project.Owner
+		}
+		if hosting.Owner != project.Owner {
+			return errors.Wrapf(app.DomainErrorInvalid, "the project %s doesn't belong to the customer %s", string(project.UUID), string(hosting.Owner))
+		}
+		scopes = append(scopes, project)
+	}
+
+	if len(hosting.Owner) > 0 {
+		customer, err := s.customerRepository.Get(ctx, hosting.Owner)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, customer)
+	}
+
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	hostings, err := s.hostingRepository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	return domain.CheckQuotas(hosting, old, hostings, scopes...)
+}
+
+// ReloadCapacity loads the configuration again and applies its capacity settings, see SetCapacity.
+// The configuration sources are the same as at start up, so the changes are expected in the config file.
+func (s *ServerService) ReloadCapacity(ctx context.Context) ([]string, error) {
+	capacity, err := config.LoadCapacity()
+	if err != nil {
+		requestid.Logger(s.log, ctx).WithError(err).Error("the capacity could not be reloaded")
+		// A configuration with problems is the caller's to fix, unlike a config file which can't be read
+		if _, invalid := errors.Cause(err).(config.Problems); invalid {
+			return nil, errors.Wrapf(app.DomainErrorInvalid, "the capacity could not be reloaded: %s", err.Error())
+		}
+		return nil, errors.Wrap(err, "the capacity could not be reloaded")
+	}
+	return s.SetCapacity(ctx, capacity)
+}
+
+// SetCapacity resizes the server and replaces the minimal size of the hostings. It's refused if
+// the current hostings don't fit in the new resources. It returns the settings which changed.
+func (s *ServerService) SetCapacity(ctx context.Context, capacity config.Capacity) ([]string, error) {
+	// The minimal sizes are read while the hostings are changed, so they are replaced out of any change
+	s.Lock()
+	defer s.Unlock()
+
+	diff := s.cfg.Capacity().Diff(capacity)
+	if len(diff) == 0 {
+		requestid.Logger(s.log, ctx).Info("the capacity is unchanged")
+		return diff, nil
+	}
+
+	err := s.serverDomain.Resize(ctx, capacity.TotalNumberOfCores, capacity.TotalSizeOfMemoryMb, capacity.TotalSizeOfDiskMb)
+	if err != nil {
+		requestid.Logger(s.log, ctx).WithError(err).Warnf("the capacity change was refused: %s", strings.Join(diff, ", "))
+		return nil, err
+	}
+	s.cfg.SetCapacity(capacity)
+
+	requestid.Logger(s.log, ctx).WithFields(logrus.Fields{"changes": diff}).Info("reloaded capacity")
+	return diff, nil
+}
+
+func (s *ServerService) GetServerStatus() domain.ServerStatus {
+	return s.serverDomain.Snapshot()
 }
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/repository"
+	"github.com/theskyinflames/cdmon2/app/store"
+	"github.com/theskyinflames/cdmon2/app/store/faults"
+)
+
+type faultyServer struct {
+	service           *ServerService
+	server            *domain.Server
+	hostingRepository *repository.HostingRepostitoryMap
+	memory            *store.MemoryStore
+	store             *faults.FaultyStore
+
+	// inspect reads the hostings straight from the memory, out of the faults
+	inspect *repository.HostingRepostitoryMap
+}
+
+func newFaultyServer(t *testing.T) *faultyServer {
+	cfg := &config.Config{
+		TotalNumberOfCores:    10,
+		TotalSizeOfMemoryMb:   10,
+		TotalSizeOfDiskMb:     10,
+		MinimalNumberOfCores:  1,
+		MinimalSizeOfMemoryMb: 1,
+		MinimalSizeOfDiskMb:   1,
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	server, err := domain.NewServer(cfg)
+	require.NoError(t, err)
+	memory := store.NewMemoryStore()
+	faulty := faults.New(memory)
+	hostingRepository := repository.NewHostingReposytoryMap(cfg, faulty)
+
+	return &faultyServer{
+		service:           NewServer(hostingRepository, nil, nil, server, cfg, log),
+		server:            server,
+		hostingRepository: hostingRepository,
+		memory:            memory,
+		store:             faulty,
+		inspect:           repository.NewHostingReposytoryMap(cfg, memory),
+	}
+}
+
+// assertConsistent checks that the server availability matches the persisted hostings, and so does the names index
+func (f *faultyServer) assertConsistent(t *testing.T, msgAndArgs ...interface{}) []domain.Hosting {
+	hostings, err := f.inspect.GetAll(context.Background())
+	require.NoError(t, err)
+	status := f.server.Snapshot()
+
+	cores, memory, disk := 0, 0, 0
+	for _, h := range hostings {
+		cores += h.Cores
+		memory += h.MemoryMb
+		disk += h.DiskMb
+	}
+	assert.Equal(t, status.TotalCores-cores, status.AvailableCores, msgAndArgs...)
+	assert.Equal(t, status.TotalSizeOfMemoryMb-memory, status.AvailableSizeOfMemoryMb, msgAndArgs...)
+	assert.Equal(t, status.TotalSizeOfDiskMb-disk, status.AvailableSizeOfDiskMb, msgAndArgs...)
+	names, err := f.memory.Keys(context.Background(), "hosting-name:*")
+	require.NoError(t, err)
+	assert.Equal(t, len(hostings), len(names), msgAndArgs...)
+	return hostings
+}
+
+// TestServerService_StoreFailures fails each one of the store calls of every operation in turn,
+// and checks that neither server resources nor store records are leaked.
+func TestServerService_StoreFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		partial bool
+		op      func(s *ServerService, seeded domain.UUID) error
+	}{
+		{
+			name: "given a server, when a hosting creation fails to be persisted, then its resources are released",
+			op: func(s *ServerService, _ domain.UUID) error {
+				_, err := s.CreateHosting(ctx, nil, "h2", 3, 3, 3, "", "")
+				return err
+			},
+		},
+		{
+			name: "given a server, when a hosting update fails to be persisted, then its former resources are restored",
+			op: func(s *ServerService, seeded domain.UUID) error {
+				return s.UpdateHosting(ctx, nil, &domain.Hosting{UUID: seeded, Name: "h1-renamed", Cores: 4, MemoryMb: 1, DiskMb: 1})
+			},
+		},
+		{
+			name: "given a server, when a hosting removal fails to be persisted, then its resources stay taken",
+			op: func(s *ServerService, seeded domain.UUID) error {
+				return s.RemoveHosting(ctx, nil, seeded)
+			},
+		},
+		{
+			name:    "given a server, when a hosting creation is persisted but fails anyway, then its resources are released",
+			partial: true,
+			op: func(s *ServerService, _ domain.UUID) error {
+				_, err := s.CreateHosting(ctx, nil, "h2", 3, 3, 3, "", "")
+				return err
+			},
+		},
+		{
+			name:    "given a server, when a hosting update is persisted but fails anyway, then its former resources are restored",
+			partial: true,
+			op: func(s *ServerService, seeded domain.UUID) error {
+				return s.UpdateHosting(ctx, nil, &domain.Hosting{UUID: seeded, Name: "h1-renamed", Cores: 4, MemoryMb: 1, DiskMb: 1})
+			},
+		},
+		{
+			name:    "given a server, when a hosting removal is persisted but fails anyway, then its resources stay taken",
+			partial: true,
+			op: func(s *ServerService, seeded domain.UUID) error {
+				return s.RemoveHosting(ctx, nil, seeded)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every call of the operation is failed in turn, until the operation has no more calls to fail
+			for n := 1; ; n++ {
+				f := newFaultyServer(t)
+				seeded, err := f.service.CreateHosting(ctx, nil, "h1", 2, 2, 2, "", "")
+				require.NoError(t, err)
+				before := f.server.Snapshot()
+				hostingsBefore := f.assertConsistent(t)
+
+				f.store.Script(faults.Rule{After: n - 1, Times: 1, Partial: tt.partial})
+				err = tt.op(f.service, seeded)
+				calls := f.store.Calls()
+				hostings := f.assertConsistent(t, "call %d", n)
+
+				if len(calls) < n {
+					// No call was left to fail
+					assert.NoError(t, err)
+					assert.True(t, n > 1)
+					return
+				}
+
+				// The failed operation left everything as it was
+				assert.Equal(t, faults.ErrInjected, errors.Cause(err), "call %d", n)
+				assert.Equal(t, before, f.server.Snapshot(), "call %d", n)
+				assert.ElementsMatch(t, hostingsBefore, hostings, "call %d", n)
+			}
+		})
+	}
+}
+
+// TestServerService_Resilience runs a sequence of operations under several failure scenarios, and checks
+// that the server availability stays consistent with the persisted hostings after each one of them.
+// Once the store recovers, the service must keep working as usual. The failures are spread enough
+// for the rollbacks to succeed, as a store which can't undo a change leaves it out of sync anyway.
+func TestServerService_Resilience(t *testing.T) {
+	errDown := errors.New("redis down")
+
+	tests := []struct {
+		name     string
+		scenario faults.Scenario
+		timeout  time.Duration
+	}{
+		{
+			name:     "given a store which fails on the third call, when the hostings are managed, then they are kept consistent",
+			scenario: faults.Scenario{{After: 2, Times: 1, Err: errDown}},
+		},
+		{
+			name:     "given a store which fails now and then, when the hostings are managed, then they are kept consistent",
+			scenario: faults.Scenario{{After: 4, Times: 1}, {After: 11, Times: 1}, {After: 17, Times: 1}, {After: 26, Times: 1}},
+		},
+		{
+			name:     "given a store which can't write the names index, when the hostings are managed, then they are kept consistent",
+			scenario: faults.Scenario{{Op: config.StoreOpSet, Key: "hosting-name:*", After: 1, Times: 2}},
+		},
+		{
+			name:     "given a store which can't remove hostings, when the hostings are managed, then they are kept consistent",
+			scenario: faults.Scenario{{Op: config.StoreOpRemove, Key: "hosting:*", Times: 1}},
+		},
+		{
+			name:     "given a store which loses the replies of its writes, when the hostings are managed, then they are kept consistent",
+			scenario: faults.Scenario{{Op: config.StoreOpSet, After: 1, Times: 2, Partial: true}, {Op: config.StoreOpRemove, After: 1, Times: 1, Partial: true}},
+		},
+		{
+			name:     "given a store which writes slowly, when the requests time out, then they are kept consistent",
+			scenario: faults.Scenario{{Op: config.StoreOpSet, Key: "hosting-name:*", Times: 2, Latency: time.Second, Partial: true}},
+			timeout:  20 * time.Millisecond,
+		},
+		{
+			name:     "given a store whose writes land after the requests time out, when they're rolled back, then they are kept consistent",
+			scenario: faults.Scenario{{Op: config.StoreOpSet, Key: "hosting:*", After: 1, Times: 2, Latency: 100 * time.Millisecond, Late: true}},
+			timeout:  20 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFaultyServer(t)
+
+			type step func(ctx context.Context, s *ServerService) error
+			hostings := make(map[string]domain.UUID)
+			create := func(name string, cores int) step {
+				return func(ctx context.Context, s *ServerService) error {
+					uuid, err := s.CreateHosting(ctx, nil, name, cores, 1, 1, "", "")
+					if err == nil {
+						hostings[name] = uuid
+					}
+					return err
+				}
+			}
+			update := func(name, newName string, cores int) step {
+				return func(ctx context.Context, s *ServerService) error {
+					err := s.UpdateHosting(ctx, nil, &domain.Hosting{UUID: hostings[name], Name: newName, Cores: cores, MemoryMb: 1, DiskMb: 1})
+					if err == nil {
+						hostings[newName] = hostings[name]
+					}
+					return err
+				}
+			}
+			remove := func(name string) step {
+				return func(ctx context.Context, s *ServerService) error {
+					return s.RemoveHosting(ctx, nil, hostings[name])
+				}
+			}
+			steps := []step{
+				create("h1", 1), create("h2", 2), create("h3", 1), update("h1", "h1b", 2), remove("h2"),
+				create("h4", 3), update("h3", "h3", 3), remove("h1b"), create("h5", 1), remove("h3"),
+			}
+
+			f.store.Script(tt.scenario...)
+			failures := 0
+			for z, step := range steps {
+				ctx, cancel := context.WithCancel(context.Background())
+				if tt.timeout > 0 {
+					ctx, cancel = context.WithTimeout(context.Background(), tt.timeout)
+				}
+				err := step(ctx, f.service)
+				cancel()
+				if err != nil {
+					failures++
+				}
+				f.assertConsistent(t, "step %d", z)
+			}
+			assert.True(t, failures > 0, "the scenario didn't fail any step")
+
+			// The store is healthy again
+			f.store.Script()
+			_, err := f.service.CreateHosting(context.Background(), nil, "h-recovered", 1, 1, 1, "", "")
+			assert.NoError(t, err)
+			f.assertConsistent(t, "recovered")
+		})
+	}
+}
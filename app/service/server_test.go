@@ -1,13 +1,14 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"reflect"
-	"sync"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
 )
@@ -22,7 +23,7 @@ func populateConfig() *config.Config {
 
 func populateHostings() []domain.Hosting {
 	return []domain.Hosting{
-		domain.Hosting{UUID: domain.UUID("uuid1"), Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1},
+		domain.Hosting{UUID: domain.UUID("uuid1"), Owner: domain.UUID("customer1"), Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1},
 		domain.Hosting{UUID: domain.UUID("uuid2"), Name: "h2", Cores: 1, MemoryMb: 1, DiskMb: 1},
 		domain.Hosting{UUID: domain.UUID("uuid3"), Name: "h3", Cores: 1, MemoryMb: 1, DiskMb: 1},
 	}
@@ -30,32 +31,56 @@ func populateHostings() []domain.Hosting {
 
 func NewHostingRepositoryMockOK() *HostingRepositoryMock {
 	return &HostingRepositoryMock{
-		InsertFunc: func(hosting *domain.Hosting) error {
+		InsertFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 			return nil
 		},
-		RemoveFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+		RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 			return &populateHostings()[0], nil
 		},
-		GetAllFunc: func() ([]domain.Hosting, error) {
+		GetAllFunc: func(ctx context.Context) ([]domain.Hosting, error) {
 			return populateHostings(), nil
 		},
-		GetFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+		GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 			return &populateHostings()[0], nil
 		},
-		UpdateFunc: func(hosting *domain.Hosting) error {
+		UpdateFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 			return nil
 		},
 	}
 }
+
+// NewCustomerRepositoryMockOK returns a customer which owns the hosting uuid1 and has quota for one more core
+func NewCustomerRepositoryMockOK() *CustomerRepositoryMock {
+	return &CustomerRepositoryMock{
+		GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Customer, error) {
+			return &domain.Customer{UUID: uuid, Name: "c1", Quota: domain.Quota{Cores: 2}}, nil
+		},
+	}
+}
+
+// NewProjectRepositoryMockOK returns a project of the customer3 with quota for one core
+func NewProjectRepositoryMockOK() *ProjectRepositoryMock {
+	return &ProjectRepositoryMock{
+		GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Project, error) {
+			return &domain.Project{UUID: uuid, Owner: "customer3", Name: "p1", Quota: domain.Quota{Cores: 1}}, nil
+		},
+		GetAllFunc: func(ctx context.Context) ([]domain.Project, error) {
+			return []domain.Project{
+				domain.Project{UUID: "project1", Owner: "customer3", Name: "p1", Quota: domain.Quota{Cores: 1}},
+			}, nil
+		},
+	}
+}
+
 func NewServerDomainMockOK() *ServerDomainMock {
 	return &ServerDomainMock{
-		AddHostingFunc: func(hosting *domain.Hosting, cfg *config.Config) error {
+		AddHostingFunc: func(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error {
 			return nil
 		},
-		RemoveHostingFunc: func(hosting *domain.Hosting) error {
+		RemoveHostingFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 			return nil
 		},
-		UpdateHostingFunc: func(hosting, old *domain.Hosting, cfg *config.Config) error {
+		UpdateHostingFunc: func(ctx context.Context, hosting, old *domain.Hosting, cfg *config.Config) error {
 			return nil
 		},
 	}
@@ -67,17 +92,21 @@ func TestServerService_CreateHosting(t *testing.T) {
 	log := logrus.New()
 
 	type fields struct {
-		Mutex             sync.Mutex
-		log               *logrus.Logger
-		cfg               *config.Config
-		hostingRepository *HostingRepositoryMock
-		serverDomain      *ServerDomainMock
+		log                *logrus.Logger
+		cfg                *config.Config
+		hostingRepository  *HostingRepositoryMock
+		customerRepository *CustomerRepositoryMock
+		projectRepository  *ProjectRepositoryMock
+		serverDomain       *ServerDomainMock
 	}
 	type args struct {
-		name     string
-		cores    int
-		memorymb int
-		diskmb   int
+		principal *domain.Principal
+		name      string
+		cores     int
+		memorymb  int
+		diskmb    int
+		owner     domain.UUID
+		project   domain.UUID
 	}
 	tests := []struct {
 		name    string
@@ -88,7 +117,6 @@ func TestServerService_CreateHosting(t *testing.T) {
 		{
 			name: "given a server, when a new hosting is created, then all works fine",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
@@ -100,11 +128,10 @@ func TestServerService_CreateHosting(t *testing.T) {
 		{
 			name: "given a server, when a new hosting is created and the repository fails, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				log:   log,
-				cfg:   cfg,
+				log: log,
+				cfg: cfg,
 				hostingRepository: &HostingRepositoryMock{
-					InsertFunc: func(hosting *domain.Hosting) error {
+					InsertFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 						return errors.New("random error")
 					},
 				},
@@ -116,15 +143,14 @@ func TestServerService_CreateHosting(t *testing.T) {
 		{
 			name: "given a server, when a new hosting is created and the server domains call fails, then it fails",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
 				serverDomain: &ServerDomainMock{
-					AddHostingFunc: func(hosting *domain.Hosting, cfg *config.Config) error {
+					AddHostingFunc: func(ctx context.Context, hosting *domain.Hosting, cfg *config.Config) error {
 						return errors.New("random error")
 					},
-					RemoveHostingFunc: func(hosting *domain.Hosting) error {
+					RemoveHostingFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 						return nil
 					},
 				},
@@ -132,6 +158,92 @@ func TestServerService_CreateHosting(t *testing.T) {
 			args:    args{name: "h1", cores: 1, memorymb: 1, diskmb: 1},
 			wantErr: true,
 		},
+		{
+			name: "given a server, when a new hosting is created for a customer with quota enough, then all works fine",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{name: "h4", cores: 1, memorymb: 1, diskmb: 1, owner: "customer1"},
+			wantErr: false,
+		},
+		{
+			name: "given a server, when a new hosting exceeds the customer quota, then it fails",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{name: "h4", cores: 2, memorymb: 1, diskmb: 1, owner: "customer1"},
+			wantErr: true,
+		},
+		{
+			name: "given a server, when a new hosting exceeds its project quota, then it fails",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				projectRepository:  NewProjectRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{name: "h4", cores: 2, memorymb: 1, diskmb: 1, project: "project1"},
+			wantErr: true,
+		},
+		{
+			name: "given a server, when a new hosting is created in a project of another customer, then it fails",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				projectRepository:  NewProjectRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{name: "h4", cores: 1, memorymb: 1, diskmb: 1, owner: "customer1", project: "project1"},
+			wantErr: true,
+		},
+		{
+			name: "given a server, when a new hosting fits in its project and customer quotas, then all works fine",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				projectRepository:  NewProjectRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{name: "h4", cores: 1, memorymb: 1, diskmb: 1, project: "project1"},
+			wantErr: false,
+		},
+		{
+			name: "given a tenant, when it creates a hosting without owner, then the hosting belongs to the tenant",
+			fields: fields{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: NewCustomerRepositoryMockOK(),
+				serverDomain:       NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer1"}, name: "h4", cores: 1, memorymb: 1, diskmb: 1},
+			wantErr: false,
+		},
+		{
+			name: "given a tenant, when it creates a hosting for another customer, then it's forbidden",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer1"}, name: "h4", cores: 1, memorymb: 1, diskmb: 1, owner: "customer2"},
+			wantErr: true,
+		},
 	}
 	for z, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -140,13 +252,18 @@ func TestServerService_CreateHosting(t *testing.T) {
 				err error
 			)
 			s := &ServerService{
-				Mutex:             tt.fields.Mutex,
 				log:               tt.fields.log,
 				cfg:               tt.fields.cfg,
 				hostingRepository: tt.fields.hostingRepository,
 				serverDomain:      tt.fields.serverDomain,
 			}
-			got, err = s.CreateHosting(tt.args.name, tt.args.cores, tt.args.memorymb, tt.args.diskmb)
+			if tt.fields.customerRepository != nil {
+				s.customerRepository = tt.fields.customerRepository
+			}
+			if tt.fields.projectRepository != nil {
+				s.projectRepository = tt.fields.projectRepository
+			}
+			got, err = s.CreateHosting(context.Background(), tt.args.principal, tt.args.name, tt.args.cores, tt.args.memorymb, tt.args.diskmb, tt.args.owner, tt.args.project)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ServerService.CreateHosting() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -170,6 +287,31 @@ func TestServerService_CreateHosting(t *testing.T) {
 				assert.Equal(t, 1, len(tt.fields.serverDomain.AddHostingCalls()))
 				assert.Equal(t, 0, len(tt.fields.hostingRepository.InsertCalls()))
 				assert.Equal(t, 0, len(tt.fields.serverDomain.RemoveHostingCalls()))
+			case 3:
+				assert.Equal(t, 1, len(tt.fields.customerRepository.GetCalls()))
+				assert.Equal(t, 1, len(tt.fields.serverDomain.AddHostingCalls()))
+				assert.Equal(t, 1, len(tt.fields.hostingRepository.InsertCalls()))
+			case 4:
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+				assert.Equal(t, 1, len(tt.fields.customerRepository.GetCalls()))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.AddHostingCalls()))
+				assert.Equal(t, 0, len(tt.fields.hostingRepository.InsertCalls()))
+			case 5:
+				assert.Equal(t, app.DomainErrorQuotaExceeded, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.AddHostingCalls()))
+			case 6:
+				assert.Equal(t, app.DomainErrorInvalid, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.customerRepository.GetCalls()))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.AddHostingCalls()))
+			case 7:
+				assert.Equal(t, domain.UUID("customer3"), tt.fields.hostingRepository.InsertCalls()[0].Hosting.Owner)
+				assert.Equal(t, 1, len(tt.fields.projectRepository.GetCalls()))
+				assert.Equal(t, 1, len(tt.fields.customerRepository.GetCalls()))
+			case 8:
+				assert.Equal(t, domain.UUID("customer1"), tt.fields.hostingRepository.InsertCalls()[0].Hosting.Owner)
+			case 9:
+				assert.Equal(t, app.AuthErrorForbidden, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.AddHostingCalls()))
 			}
 		})
 	}
@@ -182,22 +324,21 @@ func TestServerService_GetHostings(t *testing.T) {
 	log := logrus.New()
 
 	type fields struct {
-		Mutex             sync.Mutex
 		log               *logrus.Logger
 		cfg               *config.Config
 		hostingRepository *HostingRepositoryMock
 		serverDomain      *ServerDomainMock
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		want    []domain.Hosting
-		wantErr bool
+		name      string
+		fields    fields
+		principal *domain.Principal
+		want      []domain.Hosting
+		wantErr   bool
 	}{
 		{
 			name: "given a server, when the list of hosting is requested, then it's returned",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
@@ -206,14 +347,37 @@ func TestServerService_GetHostings(t *testing.T) {
 			want:    hostings,
 			wantErr: false,
 		},
+		{
+			name: "given an admin, when the list of hosting is requested, then all of them are returned",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      nil,
+			},
+			principal: &domain.Principal{Role: domain.RoleAdmin, Tenant: "customer2"},
+			want:      hostings,
+			wantErr:   false,
+		},
+		{
+			name: "given a tenant, when the list of hosting is requested, then only its hostings are returned",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      nil,
+			},
+			principal: &domain.Principal{Role: domain.RoleReadOnly, Tenant: "customer1"},
+			want:      hostings[:1],
+			wantErr:   false,
+		},
 		{
 			name: "given a server, when the list of hosting is requested and the repository fails, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				log:   log,
-				cfg:   cfg,
+				log: log,
+				cfg: cfg,
 				hostingRepository: &HostingRepositoryMock{
-					GetAllFunc: func() ([]domain.Hosting, error) {
+					GetAllFunc: func(ctx context.Context) ([]domain.Hosting, error) {
 						return nil, errors.New("random error")
 					},
 				},
@@ -226,13 +390,12 @@ func TestServerService_GetHostings(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &ServerService{
-				Mutex:             tt.fields.Mutex,
 				log:               tt.fields.log,
 				cfg:               tt.fields.cfg,
 				hostingRepository: tt.fields.hostingRepository,
 				serverDomain:      tt.fields.serverDomain,
 			}
-			got, err := s.GetHostings()
+			got, err := s.GetHostings(context.Background(), tt.principal)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ServerService.GetHostings() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -253,14 +416,14 @@ func TestServerService_RemoveHosting(t *testing.T) {
 	log := logrus.New()
 
 	type fields struct {
-		Mutex             sync.Mutex
 		log               *logrus.Logger
 		cfg               *config.Config
 		hostingRepository *HostingRepositoryMock
 		serverDomain      *ServerDomainMock
 	}
 	type args struct {
-		uuid domain.UUID
+		principal *domain.Principal
+		uuid      domain.UUID
 	}
 	tests := []struct {
 		name    string
@@ -271,7 +434,6 @@ func TestServerService_RemoveHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is removed, then all works fine",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
@@ -283,14 +445,16 @@ func TestServerService_RemoveHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is going to be removed and the repository fails, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				log:   log,
-				cfg:   cfg,
+				log: log,
+				cfg: cfg,
 				hostingRepository: &HostingRepositoryMock{
-					InsertFunc: func(hosting *domain.Hosting) error {
+					GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
+						return &hosting, nil
+					},
+					InsertFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 						return nil
 					},
-					RemoveFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+					RemoveFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 						return nil, errors.New("random error")
 					},
 				},
@@ -302,12 +466,11 @@ func TestServerService_RemoveHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is going to be removed and the server domains fails, then it fails",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
 				serverDomain: &ServerDomainMock{
-					RemoveHostingFunc: func(hosting *domain.Hosting) error {
+					RemoveHostingFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 						return errors.New("random error")
 					},
 				},
@@ -315,17 +478,58 @@ func TestServerService_RemoveHosting(t *testing.T) {
 			args:    args{uuid: hosting.UUID},
 			wantErr: true,
 		},
+		{
+			name: "given a tenant, when it removes a hosting of another tenant, then it's forbidden",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer2"}, uuid: hosting.UUID},
+			wantErr: true,
+		},
+		{
+			name: "given a tenant, when it removes a hosting which doesn't exist, then it's forbidden",
+			fields: fields{
+				log: log,
+				cfg: cfg,
+				hostingRepository: &HostingRepositoryMock{
+					GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
+						return nil, errors.Wrapf(app.DbErrorNotFound, "hosting %s", string(uuid))
+					},
+				},
+				serverDomain: NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer2"}, uuid: "uuid4"},
+			wantErr: true,
+		},
+		{
+			name: "given an admin, when it removes a hosting which doesn't exist, then it's not found",
+			fields: fields{
+				log: log,
+				cfg: cfg,
+				hostingRepository: &HostingRepositoryMock{
+					GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
+						return nil, errors.Wrapf(app.DbErrorNotFound, "hosting %s", string(uuid))
+					},
+				},
+				serverDomain: NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleAdmin}, uuid: "uuid4"},
+			wantErr: true,
+		},
 	}
 	for z, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &ServerService{
-				Mutex:             tt.fields.Mutex,
 				log:               tt.fields.log,
 				cfg:               tt.fields.cfg,
 				hostingRepository: tt.fields.hostingRepository,
 				serverDomain:      tt.fields.serverDomain,
 			}
-			if err := s.RemoveHosting(tt.args.uuid); (err != nil) != tt.wantErr {
+			err := s.RemoveHosting(context.Background(), tt.args.principal, tt.args.uuid)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("ServerService.RemoveHosting() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -342,6 +546,15 @@ func TestServerService_RemoveHosting(t *testing.T) {
 				assert.Equal(t, 1, len(tt.fields.hostingRepository.RemoveCalls()))
 				assert.Equal(t, 1, len(tt.fields.hostingRepository.InsertCalls()))
 				assert.Equal(t, 1, len(tt.fields.serverDomain.RemoveHostingCalls()))
+			case 3:
+				assert.Equal(t, app.AuthErrorForbidden, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.hostingRepository.RemoveCalls()))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.RemoveHostingCalls()))
+			case 4:
+				assert.Equal(t, app.AuthErrorForbidden, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.hostingRepository.RemoveCalls()))
+			case 5:
+				assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
 			}
 		})
 	}
@@ -354,14 +567,14 @@ func TestServerService_UpdateHosting(t *testing.T) {
 	log := logrus.New()
 
 	type fields struct {
-		Mutex             sync.Mutex
 		log               *logrus.Logger
 		cfg               *config.Config
 		hostingRepository *HostingRepositoryMock
 		serverDomain      *ServerDomainMock
 	}
 	type args struct {
-		hosting *domain.Hosting
+		principal *domain.Principal
+		hosting   *domain.Hosting
 	}
 	tests := []struct {
 		name    string
@@ -372,7 +585,6 @@ func TestServerService_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is updated, then all workds fine",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
@@ -384,11 +596,10 @@ func TestServerService_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a hosting is tried to be updated and the repository.Get op fails, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				log:   log,
-				cfg:   cfg,
+				log: log,
+				cfg: cfg,
 				hostingRepository: &HostingRepositoryMock{
-					GetFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+					GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 						return nil, errors.New("random error")
 					},
 				},
@@ -400,12 +611,11 @@ func TestServerService_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a existing hosting is tried to be updated and the server domain fails, then it fails",
 			fields: fields{
-				Mutex:             sync.Mutex{},
 				log:               log,
 				cfg:               cfg,
 				hostingRepository: NewHostingRepositoryMockOK(),
 				serverDomain: &ServerDomainMock{
-					UpdateHostingFunc: func(hosting, old *domain.Hosting, cfg *config.Config) error {
+					UpdateHostingFunc: func(ctx context.Context, hosting, old *domain.Hosting, cfg *config.Config) error {
 						return errors.New("random error")
 					},
 				},
@@ -416,14 +626,16 @@ func TestServerService_UpdateHosting(t *testing.T) {
 		{
 			name: "given a server, when a existing hosting is tried to be updated and the repository.Update op fails, then it fails",
 			fields: fields{
-				Mutex: sync.Mutex{},
-				log:   log,
-				cfg:   cfg,
+				log: log,
+				cfg: cfg,
 				hostingRepository: &HostingRepositoryMock{
-					GetFunc: func(uuid domain.UUID) (*domain.Hosting, error) {
+					GetFunc: func(ctx context.Context, uuid domain.UUID) (*domain.Hosting, error) {
 						return &hosting, nil
 					},
-					UpdateFunc: func(hosting *domain.Hosting) error {
+					GetAllFunc: func(ctx context.Context) ([]domain.Hosting, error) {
+						return populateHostings(), nil
+					},
+					UpdateFunc: func(ctx context.Context, hosting *domain.Hosting) error {
 						return errors.New("random error")
 					},
 				},
@@ -432,17 +644,40 @@ func TestServerService_UpdateHosting(t *testing.T) {
 			args:    args{hosting: &hosting},
 			wantErr: true,
 		},
+		{
+			name: "given a tenant, when it updates a hosting of another tenant, then it's forbidden",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer2"}, hosting: &domain.Hosting{UUID: hosting.UUID, Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1}},
+			wantErr: true,
+		},
+		{
+			name: "given a tenant, when it moves its hosting to another tenant, then it's forbidden",
+			fields: fields{
+				log:               log,
+				cfg:               cfg,
+				hostingRepository: NewHostingRepositoryMockOK(),
+				serverDomain:      NewServerDomainMockOK(),
+			},
+			args:    args{principal: &domain.Principal{Role: domain.RoleOperator, Tenant: "customer1"}, hosting: &domain.Hosting{UUID: hosting.UUID, Owner: "customer2", Name: "h1", Cores: 1, MemoryMb: 1, DiskMb: 1}},
+			wantErr: true,
+		},
 	}
 	for z, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &ServerService{
-				Mutex:             tt.fields.Mutex,
-				log:               tt.fields.log,
-				cfg:               tt.fields.cfg,
-				hostingRepository: tt.fields.hostingRepository,
-				serverDomain:      tt.fields.serverDomain,
+				log:                tt.fields.log,
+				cfg:                tt.fields.cfg,
+				hostingRepository:  tt.fields.hostingRepository,
+				customerRepository: NewCustomerRepositoryMockOK(),
+				serverDomain:       tt.fields.serverDomain,
 			}
-			if err := s.UpdateHosting(tt.args.hosting); (err != nil) != tt.wantErr {
+			err := s.UpdateHosting(context.Background(), tt.args.principal, tt.args.hosting)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("ServerService.UpdateHosting() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -460,9 +695,135 @@ func TestServerService_UpdateHosting(t *testing.T) {
 				assert.Equal(t, 1, len(tt.fields.serverDomain.UpdateHostingCalls()))
 				assert.Equal(t, 0, len(tt.fields.hostingRepository.UpdateCalls()))
 			case 3:
+				// The server resources are given back as they were
 				assert.Equal(t, 1, len(tt.fields.hostingRepository.GetCalls()))
-				assert.Equal(t, 1, len(tt.fields.serverDomain.UpdateHostingCalls()))
+				assert.Equal(t, 2, len(tt.fields.serverDomain.UpdateHostingCalls()))
+				assert.Equal(t, tt.fields.serverDomain.UpdateHostingCalls()[0].Old, tt.fields.serverDomain.UpdateHostingCalls()[1].Hosting)
 				assert.Equal(t, 1, len(tt.fields.hostingRepository.UpdateCalls()))
+			case 4, 5:
+				assert.Equal(t, app.AuthErrorForbidden, errors.Cause(err))
+				assert.Equal(t, 0, len(tt.fields.serverDomain.UpdateHostingCalls()))
+				assert.Equal(t, 0, len(tt.fields.hostingRepository.UpdateCalls()))
+			}
+		})
+	}
+}
+
+func TestServerService_GetCustomerHostings(t *testing.T) {
+
+	cfg := populateConfig()
+	log := logrus.New()
+
+	tests := []struct {
+		name      string
+		principal *domain.Principal
+		owner     domain.UUID
+		want      int
+		wantErr   error
+	}{
+		{
+			name:      "given a tenant, when it requests its hostings, then they're returned",
+			principal: &domain.Principal{Role: domain.RoleReadOnly, Tenant: "customer1"},
+			owner:     "customer1",
+			want:      1,
+			wantErr:   nil,
+		},
+		{
+			name:      "given a tenant, when it requests the hostings of another customer, then it's forbidden",
+			principal: &domain.Principal{Role: domain.RoleReadOnly, Tenant: "customer2"},
+			owner:     "customer1",
+			want:      0,
+			wantErr:   app.AuthErrorForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerRepository := NewCustomerRepositoryMockOK()
+			s := &ServerService{
+				log:                log,
+				cfg:                cfg,
+				hostingRepository:  NewHostingRepositoryMockOK(),
+				customerRepository: customerRepository,
+			}
+			got, err := s.GetCustomerHostings(context.Background(), tt.principal, tt.owner)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			assert.Equal(t, tt.want, len(got))
+			if tt.wantErr != nil {
+				assert.Equal(t, 0, len(customerRepository.GetCalls()))
+			}
+		})
+	}
+}
+
+func TestServerService_SetCapacity(t *testing.T) {
+
+	log := logrus.New()
+	refused := errors.Wrap(app.DomainErrorQuotaExceeded, "the hostings take 3 cores")
+
+	tests := []struct {
+		name      string
+		capacity  func(c config.Capacity) config.Capacity
+		resizeErr error
+		wantDiff  []string
+		wantErr   error
+		resized   bool
+	}{
+		{
+			name: "given a server, when its capacity grows, then it's resized and the minimal sizes are replaced",
+			capacity: func(c config.Capacity) config.Capacity {
+				c.TotalNumberOfCores = 8
+				c.MinimalSizeOfMemoryMb = 64
+				return c
+			},
+			wantDiff: []string{
+				config.TotalNumberOfCores + ": 4 -> 8",
+				config.MinimalSizeOfMemoryMb + ": 1 -> 64",
+			},
+			resized: true,
+		},
+		{
+			name:     "given a server, when its capacity is reloaded unchanged, then nothing is done",
+			capacity: func(c config.Capacity) config.Capacity { return c },
+		},
+		{
+			name: "given a server, when its capacity shrinks below its hostings, then it's refused and nothing changes",
+			capacity: func(c config.Capacity) config.Capacity {
+				c.TotalNumberOfCores = 2
+				c.MinimalNumberOfCores = 2
+				return c
+			},
+			resizeErr: refused,
+			wantErr:   app.DomainErrorQuotaExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := populateConfig()
+			cfg.TotalNumberOfCores, cfg.TotalSizeOfMemoryMb, cfg.TotalSizeOfDiskMb = 4, 1024, 1024
+			old := cfg.Capacity()
+			capacity := tt.capacity(old)
+
+			serverDomain := &ServerDomainMock{
+				ResizeFunc: func(ctx context.Context, cores, memorymb, diskmb int) error {
+					return tt.resizeErr
+				},
+			}
+			s := &ServerService{log: log, cfg: cfg, serverDomain: serverDomain}
+
+			diff, err := s.SetCapacity(context.Background(), capacity)
+			assert.Equal(t, tt.wantErr, errors.Cause(err))
+			assert.Equal(t, tt.wantDiff, diff)
+
+			if tt.wantErr != nil {
+				assert.Equal(t, old, cfg.Capacity())
+			} else {
+				assert.Equal(t, capacity, cfg.Capacity())
+			}
+			if tt.resized || tt.resizeErr != nil {
+				assert.Equal(t, 1, len(serverDomain.ResizeCalls()))
+				assert.Equal(t, capacity.TotalNumberOfCores, serverDomain.ResizeCalls()[0].Cores)
+			} else {
+				assert.Equal(t, 0, len(serverDomain.ResizeCalls()))
 			}
 		})
 	}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type (
+	// Breaker is a circuit breaker. It opens after a number of consecutive failures, and while it's open
+	// the calls fail fast, without waiting for an unhealthy Redis. Once the cooldown has passed, a single
+	// call is let through as a trial, which closes the breaker if it succeeds, or opens it again if it fails.
+	Breaker struct {
+		sync.Mutex
+		failures int
+		cooldown time.Duration
+		now      func() time.Time
+
+		state    int
+		failed   int
+		openedAt time.Time
+	}
+)
+
+// NewBreaker returns a breaker which opens after the given consecutive failures. It's never opened if they're zero.
+func NewBreaker(failures int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		failures: failures,
+		cooldown: cooldown,
+		now:      time.Now,
+	}
+}
+
+// Allow returns app.DbErrorUnavailable if the call must not be done. Otherwise,
+// the outcome of the call must be told to Done, or the call given up with Release.
+func (b *Breaker) Allow() error {
+	if b == nil || b.failures <= 0 {
+		return nil
+	}
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return errors.Wrap(app.DbErrorUnavailable, "circuit breaker open")
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// The trial call is still running
+		return errors.Wrap(app.DbErrorUnavailable, "circuit breaker half open")
+	default:
+		return nil
+	}
+}
+
+// Done records the outcome of an allowed call
+func (b *Breaker) Done(failed bool) {
+	if b == nil || b.failures <= 0 {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+
+	if !failed {
+		b.state = breakerClosed
+		b.failed = 0
+		return
+	}
+
+	b.failed++
+	if b.state == breakerHalfOpen || b.failed >= b.failures {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release gives up an allowed call without recording its outcome, as when its caller is gone.
+// A trial call given up leaves the breaker open, so the next call is let through as a new trial.
+func (b *Breaker) Release() {
+	if b == nil || b.failures <= 0 {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// Open tells if the calls are failing fast
+func (b *Breaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.Lock()
+	defer b.Unlock()
+	return b.state != breakerClosed
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// The failures must be consecutive to open it
+	for _, failed := range []bool{true, true, false, true, true} {
+		assert.NoError(t, b.Allow())
+		b.Done(failed)
+	}
+	assert.False(t, b.Open())
+
+	assert.NoError(t, b.Allow())
+	b.Done(true)
+	assert.True(t, b.Open())
+
+	// While it's open, the calls fail fast
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(b.Allow()))
+
+	// After the cooldown, a single trial is let through, and a failed one opens it again
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(b.Allow()))
+	b.Done(true)
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(b.Allow()))
+
+	// A successful trial closes it
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Done(false)
+	assert.False(t, b.Open())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_Release(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Allow())
+	b.Done(true)
+	assert.True(t, b.Open())
+
+	// A trial given up doesn't close the breaker, nor does it start another cooldown
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.True(t, b.Open())
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(b.Allow()))
+	b.Done(false)
+	assert.False(t, b.Open())
+
+	// Nor does a call given up while it's closed count as a success
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.False(t, b.Open())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := NewBreaker(0, time.Minute)
+	for z := 0; z < 10; z++ {
+		assert.NoError(t, b.Allow())
+		b.Done(true)
+	}
+	assert.False(t, b.Open())
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+type (
+	// Codec encodes the items into the payload of the stored records
+	Codec interface {
+		Name() string
+		Encode(item interface{}) ([]byte, error)
+		// Decode decodes the payload into the item, which must be a pointer
+		Decode(b []byte, item interface{}) error
+	}
+
+	// GobCodec is the binary encoding of Go, compact and fast, but only readable from Go.
+	// The types of the items must be registered with gob.Register.
+	GobCodec struct{}
+
+	// JSONCodec is readable by other tools, at the cost of a bigger size. The fields
+	// skipped by the JSON encoding of the items, like the hash of the API keys, are not kept.
+	JSONCodec struct{}
+)
+
+var codecs = map[string]Codec{
+	config.StoreCodecGob:  GobCodec{},
+	config.StoreCodecJSON: JSONCodec{},
+}
+
+// NewCodec returns the codec of the name, as set by CDMON2_STORE_CODEC
+func NewCodec(name string) (Codec, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		names := make([]string, 0, len(codecs))
+		for n := range codecs {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, errors.Errorf("unknown store codec %q, it must be one of %v", name, names)
+	}
+	return codec, nil
+}
+
+func (GobCodec) Name() string {
+	return config.StoreCodecGob
+}
+
+func (GobCodec) Encode(item interface{}) ([]byte, error) {
+	b := bytes.Buffer{}
+	err := gob.NewEncoder(&b).Encode(item)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (GobCodec) Decode(b []byte, item interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(item)
+}
+
+func (JSONCodec) Name() string {
+	return config.StoreCodecJSON
+}
+
+func (JSONCodec) Encode(item interface{}) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+func (JSONCodec) Decode(b []byte, item interface{}) error {
+	return json.Unmarshal(b, item)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/store/storetest"
+)
+
+// testRedisAddr is the Redis which the contract of the Redis store is run against. Its database is flushed
+// by every test, so it must be one for the tests only. Without it, the Redis store is not tested.
+const testRedisAddr = "CDMON2_TEST_REDIS_ADDR"
+
+var codecNames = []string{config.StoreCodecGob, config.StoreCodecJSON}
+
+func TestMemoryStore_Contract(t *testing.T) {
+	for _, name := range codecNames {
+		t.Run(name, func(t *testing.T) {
+			codec, err := NewCodec(name)
+			require.NoError(t, err)
+			storetest.Run(t, func(t *testing.T) storetest.Store {
+				return NewMemoryStoreWithCodec(codec)
+			})
+		})
+	}
+}
+
+func TestFileStore_Contract(t *testing.T) {
+	for _, fsync := range []string{config.StoreFsyncAlways, config.StoreFsyncOnInterval, config.StoreFsyncNever} {
+		for _, name := range codecNames {
+			t.Run(fsync+" "+name, func(t *testing.T) {
+				storetest.Run(t, func(t *testing.T) storetest.Store {
+					cfg := fileStoreConfig(t, fsync)
+					cfg.StoreCodec = name
+					cfg.StoreFsyncInterval = time.Millisecond
+					f := openFileStore(t, cfg)
+					t.Cleanup(func() { f.Close() })
+					return f
+				})
+			})
+		}
+	}
+}
+
+func TestStore_Contract(t *testing.T) {
+	addr := os.Getenv(testRedisAddr)
+	if len(addr) == 0 {
+		t.Skipf("%s is not set", testRedisAddr)
+	}
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	for _, name := range codecNames {
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) storetest.Store {
+				cfg := &config.Config{
+					RedisAddr:            addr,
+					RedisDialTimeout:     time.Second,
+					RedisConnectAttempts: 1,
+					StoreTimeout:         5 * time.Second,
+					StoreCodec:           name,
+				}
+				s, err := NewStore(cfg, log)
+				require.NoError(t, err)
+				t.Cleanup(func() { s.Close() })
+				require.NoError(t, s.Flush())
+				return s
+			})
+		})
+	}
+}
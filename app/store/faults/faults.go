@@ -0,0 +1,242 @@
+// Package faults decorates a store to inject failures in its operations, to test how its callers
+// behave when the store fails. The failures are scripted as a scenario of rules, each one telling
+// which operations on which keys fail, when, and how.
+package faults
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+// ErrInjected is the failure given by the rules without an error of their own
+var ErrInjected = errors.New("injected store failure")
+
+type (
+	Store interface {
+		Connect() error
+		Close() error
+		Get(ctx context.Context, key string, item interface{}) (interface{}, error)
+		GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+		Set(ctx context.Context, key string, item interface{}) error
+		Remove(ctx context.Context, key string) error
+		Keys(ctx context.Context, pattern string) ([]string, error)
+		Load(ctx context.Context, patterns []string, items map[string]interface{}) error
+	}
+
+	// Rule is a fault of the scenario. The calls matching the rule are counted from
+	// the moment the scenario is set, and the ones within its window are faulted.
+	Rule struct {
+		// Op is the operation, one of the config.StoreOp* ones. Any operation if empty.
+		Op string
+		// Key is a pattern of the keys, with the path.Match syntax. For GetAll and Keys, it's matched
+		// against the pattern of the operation, and for Load, against "*". Any key if empty.
+		Key string
+		// After is the number of matching calls let through before faulting them
+		After int
+		// Times is the number of matching calls faulted. All of them from After on if zero.
+		Times int
+		// Latency delays the faulted calls. The caller context is honored while waiting.
+		Latency time.Duration
+		// Err is returned by the faulted calls. ErrInjected if it's nil and there is no latency,
+		// so a rule always does something.
+		Err error
+		// Partial makes the faulted writes be applied before failing, as when the reply of
+		// a write is lost. It makes no difference on reads.
+		Partial bool
+		// Late makes the faulted writes be applied once their latency has passed, even if their caller gave up
+		// meanwhile, as a Redis write waiting for a connection or in flight. The call returns once the write has
+		// been applied, as the Redis store waits for its abandoned writes. It makes no difference on reads.
+		Late bool
+	}
+
+	// Scenario is the script of the faults. For each call, the first matching rule
+	// within its window decides the fault.
+	Scenario []Rule
+
+	// Call is a store operation done through the decorator
+	Call struct {
+		Op      string
+		Key     string
+		Faulted bool
+		Err     error
+	}
+
+	// FaultyStore is the decorator which injects the faults of its scenario
+	FaultyStore struct {
+		Store
+		mutex    sync.Mutex
+		scenario Scenario
+		matches  []int
+		calls    []Call
+	}
+)
+
+func New(store Store, scenario ...Rule) *FaultyStore {
+	f := &FaultyStore{Store: store}
+	f.Script(scenario...)
+	return f
+}
+
+// Script replaces the scenario, and starts counting the calls again. Without rules, no call is faulted.
+func (f *FaultyStore) Script(scenario ...Rule) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.scenario = scenario
+	f.matches = make([]int, len(scenario))
+	f.calls = nil
+}
+
+// Calls returns the calls done since the scenario was set
+func (f *FaultyStore) Calls() []Call {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	calls := make([]Call, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+func (f *FaultyStore) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
+	var got interface{}
+	err := f.do(ctx, config.StoreOpGet, key, false, func() (err error) {
+		got, err = f.Store.Get(ctx, key, item)
+		return
+	})
+	return got, err
+}
+
+func (f *FaultyStore) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+	var items []interface{}
+	err := f.do(ctx, config.StoreOpGetAll, pattern, false, func() (err error) {
+		items, err = f.Store.GetAll(ctx, pattern, emptyRecordFunc)
+		return
+	})
+	return items, err
+}
+
+func (f *FaultyStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := f.do(ctx, config.StoreOpKeys, pattern, false, func() (err error) {
+		keys, err = f.Store.Keys(ctx, pattern)
+		return
+	})
+	return keys, err
+}
+
+func (f *FaultyStore) Set(ctx context.Context, key string, item interface{}) error {
+	return f.do(ctx, config.StoreOpSet, key, true, func() error {
+		return f.Store.Set(ctx, key, item)
+	})
+}
+
+func (f *FaultyStore) Remove(ctx context.Context, key string) error {
+	return f.do(ctx, config.StoreOpRemove, key, true, func() error {
+		return f.Store.Remove(ctx, key)
+	})
+}
+
+func (f *FaultyStore) Load(ctx context.Context, patterns []string, items map[string]interface{}) error {
+	return f.do(ctx, config.StoreOpLoad, "*", true, func() error {
+		return f.Store.Load(ctx, patterns, items)
+	})
+}
+
+func (f *FaultyStore) do(ctx context.Context, op, key string, write bool, operation func() error) error {
+	rule, faulted := f.fault(op, key)
+	if !faulted {
+		err := operation()
+		f.record(Call{Op: op, Key: key, Err: err})
+		return err
+	}
+
+	if rule.Late && write {
+		return f.late(ctx, op, key, rule, operation)
+	}
+
+	if rule.Partial && write {
+		if err := operation(); err != nil {
+			f.record(Call{Op: op, Key: key, Faulted: true, Err: err})
+			return err
+		}
+	}
+
+	err := rule.Err
+	if rule.Latency > 0 {
+		select {
+		case <-time.After(rule.Latency):
+		case <-ctx.Done():
+			err = errors.Wrapf(ctx.Err(), "store %s operation", op)
+		}
+	} else if err == nil {
+		err = ErrInjected
+	}
+
+	// A delay alone lets the operation through, unless it has already been done
+	if err == nil && !(rule.Partial && write) {
+		err = operation()
+	}
+	f.record(Call{Op: op, Key: key, Faulted: true, Err: err})
+	return err
+}
+
+// late applies the write once the latency of the rule has passed, whether its caller is still waiting or not
+func (f *FaultyStore) late(ctx context.Context, op, key string, rule Rule, operation func() error) error {
+	time.Sleep(rule.Latency)
+	err := operation()
+	if err == nil && ctx.Err() != nil {
+		err = errors.Wrapf(ctx.Err(), "store %s operation", op)
+	}
+	f.record(Call{Op: op, Key: key, Faulted: true, Err: err})
+	return err
+}
+
+// fault counts the call on the rules it matches, and returns the rule which faults it, if any
+func (f *FaultyStore) fault(op, key string) (Rule, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var (
+		faulting Rule
+		faulted  bool
+	)
+	for z, r := range f.scenario {
+		if !r.matches(op, key) {
+			continue
+		}
+		f.matches[z]++
+		if !faulted && r.within(f.matches[z]) {
+			faulting, faulted = r, true
+		}
+	}
+	return faulting, faulted
+}
+
+func (f *FaultyStore) record(call Call) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (r Rule) matches(op, key string) bool {
+	if len(r.Op) > 0 && r.Op != op {
+		return false
+	}
+	if len(r.Key) > 0 {
+		ok, _ := path.Match(r.Key, key)
+		return ok
+	}
+	return true
+}
+
+// within tells if the nth matching call, counting from one, is in the window of the rule
+func (r Rule) within(n int) bool {
+	if n <= r.After {
+		return false
+	}
+	return r.Times == 0 || n <= r.After+r.Times
+}
@@ -0,0 +1,124 @@
+package faults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/store"
+)
+
+func TestFaultyStore(t *testing.T) {
+	errDown := errors.New("down")
+
+	tests := []struct {
+		name     string
+		scenario Scenario
+		// Keys set one after another, and the errors expected for each one
+		keys    []string
+		want    []error
+		written []string
+	}{
+		{
+			name:     "given no scenario, when the store is used, then nothing fails",
+			scenario: nil,
+			keys:     []string{"a:1", "a:2"},
+			want:     []error{nil, nil},
+			written:  []string{"a:1", "a:2"},
+		},
+		{
+			name:     "given a failure on the third call, when the store is used, then only the third call fails",
+			scenario: Scenario{{After: 2, Times: 1, Err: errDown}},
+			keys:     []string{"a:1", "a:2", "a:3", "a:4"},
+			want:     []error{nil, nil, errDown, nil},
+			written:  []string{"a:1", "a:2", "a:4"},
+		},
+		{
+			name:     "given an outage from the second call, when the store is used, then every call fails from then on",
+			scenario: Scenario{{After: 1}},
+			keys:     []string{"a:1", "a:2", "a:3"},
+			want:     []error{nil, ErrInjected, ErrInjected},
+			written:  []string{"a:1"},
+		},
+		{
+			name:     "given a failure by key pattern, when the store is used, then only the matching keys fail",
+			scenario: Scenario{{Op: config.StoreOpSet, Key: "b:*", Err: errDown}},
+			keys:     []string{"a:1", "b:1", "a:2", "b:2"},
+			want:     []error{nil, errDown, nil, errDown},
+			written:  []string{"a:1", "a:2"},
+		},
+		{
+			name:     "given a failure on other operation, when the store is used, then nothing fails",
+			scenario: Scenario{{Op: config.StoreOpRemove, Err: errDown}},
+			keys:     []string{"a:1"},
+			want:     []error{nil},
+			written:  []string{"a:1"},
+		},
+		{
+			name:     "given partial failures, when the store is used, then the writes are applied although they fail",
+			scenario: Scenario{{Key: "b:*", Partial: true, Err: errDown}},
+			keys:     []string{"a:1", "b:1"},
+			want:     []error{nil, errDown},
+			written:  []string{"a:1", "b:1"},
+		},
+		{
+			name:     "given a latency, when the store is used, then the calls are delayed but done",
+			scenario: Scenario{{Latency: time.Millisecond}},
+			keys:     []string{"a:1"},
+			want:     []error{nil},
+			written:  []string{"a:1"},
+		},
+		{
+			name:     "given several rules, when a call matches them, then the first one within its window decides",
+			scenario: Scenario{{After: 1, Err: errDown}, {Err: ErrInjected}},
+			keys:     []string{"a:1", "a:2"},
+			want:     []error{ErrInjected, errDown},
+			written:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := store.NewMemoryStore()
+			f := New(memory, tt.scenario...)
+
+			for z, k := range tt.keys {
+				err := f.Set(context.Background(), k, "0")
+				assert.Equal(t, tt.want[z], errors.Cause(err), "key %s", k)
+			}
+			written, err := memory.Keys(context.Background(), "*")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.written, written)
+
+			calls := f.Calls()
+			assert.Equal(t, len(tt.keys), len(calls))
+			for z, c := range calls {
+				assert.Equal(t, tt.keys[z], c.Key)
+				assert.Equal(t, tt.want[z], errors.Cause(c.Err))
+			}
+		})
+	}
+}
+
+func TestFaultyStore_Latency(t *testing.T) {
+	f := New(store.NewMemoryStore(), Rule{Op: config.StoreOpGet, Latency: time.Hour})
+
+	// The caller deadline is honored
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var s string
+	_, err := f.Get(ctx, "a:1", &s)
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// A new script starts clean
+	f.Script()
+	_, err = f.Get(context.Background(), "a:1", &s)
+	assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+	assert.Equal(t, 1, len(f.Calls()))
+}
@@ -0,0 +1,526 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+// The log starts with this line, which tells its format
+const fileLogHeader = "cdmon2-log:1\n"
+
+// Operations of the log entries
+const (
+	fileOpSet    byte = 's'
+	fileOpRemove byte = 'r'
+)
+
+// A longer entry is taken as a corrupted length
+const maxFileEntry = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornEntry is an entry cut short by the end of the log, as the one being written when the service crashed
+var errTornEntry = errors.New("the entry is incomplete")
+
+type (
+	// FileStore keeps the items in memory, as the MemoryStore does, and appends every change to a log file,
+	// which is replayed when the store is opened. It's meant for the small installations without Redis.
+	// The log is compacted periodically, rewriting only the live records, and it's flushed to disk after
+	// each change, periodically or never, as its fsync policy says. Only one process can open it at once.
+	FileStore struct {
+		sync.Mutex // serializes the changes, so the log and the items follow the same order
+		log        *logrus.Logger
+		cfg        *config.Config
+		items      *MemoryStore
+		file       *os.File
+		lock       *os.File
+		size       int64 // bytes of whole entries in the log
+		entries    int   // entries in the log, to tell whether it's worth compacting
+		dirty      bool  // changes not flushed to disk yet
+		broken     error // the log could not be reopened after a compaction
+		closed     bool
+		done       chan struct{}
+		wg         sync.WaitGroup
+	}
+)
+
+// NewFileStore opens the log file of the configuration, creating it if it doesn't exist, and recovers its records
+func NewFileStore(cfg *config.Config, log *logrus.Logger) (*FileStore, error) {
+	codec, err := NewCodec(cfg.StoreCodec)
+	if err != nil {
+		return nil, err
+	}
+	f := &FileStore{
+		log:   log,
+		cfg:   cfg,
+		items: NewMemoryStoreWithCodec(codec),
+		done:  make(chan struct{}),
+	}
+	err = f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.StoreFsync == config.StoreFsyncOnInterval {
+		f.every(cfg.StoreFsyncInterval, f.flush)
+	}
+	if cfg.StoreCompactInterval > 0 {
+		f.every(cfg.StoreCompactInterval, f.Compact)
+	}
+	return f, nil
+}
+
+// open locks the log, creates it if it's missing and replays it
+func (f *FileStore) open() error {
+	path := f.cfg.StoreFile
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+
+	// A new log is written aside and renamed, as the compacted ones, so it's never found half written
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		_, err = writeLog(path, nil)
+	}
+	if err == nil {
+		f.file, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0)
+	}
+	if err == nil {
+		err = f.replay()
+		if err != nil {
+			f.file.Close()
+		}
+	}
+	if err != nil {
+		lock.Close()
+		return errors.Wrapf(err, "opening the store log %s", path)
+	}
+	f.lock = lock
+	return nil
+}
+
+// replay applies the entries of the log to the items. The last entry may be torn by a crash, as it's cut short
+// or doesn't match its checksum: it's cut from the log. A broken entry followed by others is a corruption instead,
+// and cutting the log there would lose all the changes after it, so the log is refused and left as it is.
+func (f *FileStore) replay() error {
+	r := bufio.NewReader(f.file)
+	header := make([]byte, len(fileLogHeader))
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header) != fileLogHeader {
+		return errors.New("it's not a store log")
+	}
+
+	f.size = int64(len(header))
+	for {
+		op, key, value, n, err := readEntry(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			info, serr := f.file.Stat()
+			if serr != nil {
+				return serr
+			}
+			if errors.Cause(err) != errTornEntry && (n == 0 || f.size+n < info.Size()) {
+				return errors.Wrapf(err, "the store log is corrupted at byte %d, followed by %d more bytes", f.size, info.Size()-f.size-n)
+			}
+			f.log.Warnf("the store log %s is broken at byte %d, %s, so its last %d bytes are dropped", f.cfg.StoreFile, f.size, err.Error(), info.Size()-f.size)
+			err = f.file.Truncate(f.size)
+			if err == nil {
+				err = f.file.Sync()
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+
+		if op == fileOpSet {
+			f.items.put(key, value)
+		} else {
+			f.items.delete(key)
+		}
+		f.size += n
+		f.entries++
+	}
+
+	f.log.Infof("store log %s replayed, %d records from %d entries", f.cfg.StoreFile, len(f.items.items), f.entries)
+	return nil
+}
+
+// every runs the task periodically until the store is closed. Its failures are logged.
+func (f *FileStore) every(interval time.Duration, task func() error) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.done:
+				return
+			case <-ticker.C:
+				err := task()
+				if err != nil {
+					f.log.Errorf("store log %s: %s", f.cfg.StoreFile, err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// flush writes the pending changes to disk
+func (f *FileStore) flush() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed || f.broken != nil || !f.dirty {
+		return nil
+	}
+	err := f.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "flushing the store log")
+	}
+	f.dirty = false
+	return nil
+}
+
+// append writes the entry to the log, and flushes it if the policy is always. The caller holds the lock.
+// An entry which fails is cut from the log, so it doesn't hide the next ones on the replay.
+func (f *FileStore) append(op byte, key string, value []byte) error {
+	err := f.writable()
+	if err != nil {
+		return err
+	}
+
+	entry := encodeEntry(op, key, value)
+	_, err = f.file.Write(entry)
+	if err == nil && f.cfg.StoreFsync == config.StoreFsyncAlways {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		terr := f.file.Truncate(f.size)
+		if terr != nil {
+			f.log.Errorf("the failed entry of %s could not be cut from the store log, the next entries will be lost on the replay: %s", key, terr.Error())
+		}
+		return errors.Wrapf(err, "writing %s to the store log", key)
+	}
+
+	f.size += int64(len(entry))
+	f.entries++
+	f.dirty = f.cfg.StoreFsync != config.StoreFsyncAlways
+	return nil
+}
+
+func (f *FileStore) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
+	return f.items.Get(ctx, key, item)
+}
+
+// GetAll returns the items whose keys match the pattern, with the same syntax as the Redis KEYS command
+func (f *FileStore) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+	return f.items.GetAll(ctx, pattern, emptyRecordFunc)
+}
+
+// Keys returns the stored keys matching the pattern, sorted
+func (f *FileStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return f.items.Keys(ctx, pattern)
+}
+
+func (f *FileStore) Set(ctx context.Context, key string, item interface{}) error {
+	bin, err := f.items.records.Encode(item)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s", key)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	err = f.append(fileOpSet, key, bin)
+	if err != nil {
+		return err
+	}
+	f.items.put(key, bin)
+	return nil
+}
+
+// Remove deletes the key. As in Redis, removing a missing key is not an error.
+func (f *FileStore) Remove(ctx context.Context, key string) error {
+	f.Lock()
+	defer f.Unlock()
+	_, err := f.items.raw(key)
+	if err != nil {
+		return nil
+	}
+	err = f.append(fileOpRemove, key, nil)
+	if err != nil {
+		return err
+	}
+	f.items.delete(key)
+	return nil
+}
+
+// Migrate rewrites the outdated records of the keys matching the pattern, as the Redis store does
+func (f *FileStore) Migrate(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+	keys, err := f.items.Keys(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	set := func(key string, bin []byte) error {
+		f.Lock()
+		defer f.Unlock()
+		err := f.append(fileOpSet, key, bin)
+		if err != nil {
+			return err
+		}
+		f.items.put(key, bin)
+		return nil
+	}
+	return f.items.records.migrate(keys, emptyRecordFunc, f.items.raw, set)
+}
+
+// Compact rewrites the log with the live records only. The new log is written aside and renamed over
+// the old one once it's on disk, so a crash in between leaves one of them whole.
+func (f *FileStore) Compact() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed || f.broken != nil {
+		return nil
+	}
+	items := f.items.snapshot()
+	if f.entries == len(items) {
+		return nil
+	}
+
+	entries := f.entries
+	err := f.rewrite(items)
+	if err != nil {
+		return errors.Wrap(err, "compacting the store log")
+	}
+	f.log.Infof("store log %s compacted, from %d to %d entries", f.cfg.StoreFile, entries, len(items))
+	return nil
+}
+
+// Flush removes all the records, rewriting the log empty
+func (f *FileStore) Flush() error {
+	f.Lock()
+	defer f.Unlock()
+	err := f.writable()
+	if err != nil {
+		return err
+	}
+
+	err = f.rewrite(nil)
+	if err != nil {
+		return errors.Wrap(err, "flushing the store log")
+	}
+	f.items.replace(make(map[string][]byte))
+	return nil
+}
+
+// Load writes all the items at once, only if no key matches the patterns, else it fails with app.DbErrorInUse.
+// The log is rewritten with them along with the stored records, so after a crash either all of them are recovered or none.
+func (f *FileStore) Load(ctx context.Context, patterns []string, items map[string]interface{}) error {
+	bins, err := f.items.records.encodeAll(items)
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	err = f.writable()
+	if err != nil {
+		return err
+	}
+	err = f.items.loadable(patterns)
+	if err != nil {
+		return err
+	}
+
+	loaded := f.items.snapshot()
+	for k, bin := range bins {
+		loaded[k] = bin
+	}
+	err = f.rewrite(loaded)
+	if err != nil {
+		return errors.Wrap(err, "loading the store log")
+	}
+	f.items.replace(loaded)
+	return nil
+}
+
+// writable tells why the log can't be written, if it can't. The caller holds the lock of the store.
+func (f *FileStore) writable() error {
+	if f.closed {
+		return errors.Wrap(app.DbErrorUnavailable, "the store is closed")
+	}
+	if f.broken != nil {
+		return errors.Wrapf(app.DbErrorUnavailable, "the store log is not writable: %s", f.broken.Error())
+	}
+	return nil
+}
+
+// rewrite replaces the log with one of the items. The caller holds the lock of the store.
+func (f *FileStore) rewrite(items map[string][]byte) error {
+	path := f.cfg.StoreFile
+	size, err := writeLog(path, items)
+	if err != nil {
+		return err
+	}
+
+	// The old log has been replaced, so the changes can't be appended to it anymore
+	f.file.Close()
+	f.file, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		f.broken = err
+		return errors.Wrap(err, "reopening the rewritten store log")
+	}
+	f.size, f.entries, f.dirty = size, len(items), false
+	return nil
+}
+
+// Connect does nothing, as the log is opened along with the store
+func (f *FileStore) Connect() error {
+	return nil
+}
+
+// Ping fails once the store is closed, or when its log can't be written
+func (f *FileStore) Ping() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return errors.Wrap(app.DbErrorUnavailable, "the store is closed")
+	}
+	if f.broken != nil {
+		return errors.Wrapf(app.DbErrorUnavailable, "the store log is not writable: %s", f.broken.Error())
+	}
+	return nil
+}
+
+// Close flushes the log whatever the fsync policy, and releases it for other processes
+func (f *FileStore) Close() error {
+	f.Lock()
+	if f.closed {
+		f.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.Unlock()
+
+	close(f.done)
+	f.wg.Wait()
+
+	err := f.file.Sync()
+	cerr := f.file.Close()
+	if err == nil {
+		err = cerr
+	}
+	f.lock.Close()
+	return errors.Wrap(err, "closing the store log")
+}
+
+// writeLog writes a log with the records aside the path, flushes it and renames it to the path.
+// It returns the size of the log.
+func writeLog(path string, items map[string][]byte) (int64, error) {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(file)
+	size, _ := w.WriteString(fileLogHeader)
+	for _, k := range keys {
+		n, _ := w.Write(encodeEntry(fileOpSet, k, items[k]))
+		size += n
+	}
+	err = w.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	cerr := file.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return int64(size), syncDir(filepath.Dir(path))
+}
+
+// encodeEntry returns the entry of the log, which is the length and the checksum of its body, followed by the body:
+// the operation, the length of the key, the key and the value.
+func encodeEntry(op byte, key string, value []byte) []byte {
+	body := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(key)+len(value))
+	body[0] = op
+	n := binary.PutUvarint(body[1:], uint64(len(key)))
+	body = append(body[:1+n], key...)
+	body = append(body, value...)
+
+	entry := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(entry[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(entry[4:8], crc32.Checksum(body, crcTable))
+	return append(entry, body...)
+}
+
+// readEntry reads the next entry of the log, and returns its size. io.EOF tells the log ended where an entry should start,
+// and errTornEntry that it ended within the entry. The size is also returned for the broken entries whose length is valid.
+func readEntry(r *bufio.Reader) (byte, string, []byte, int64, error) {
+	head := make([]byte, 8)
+	n, err := io.ReadFull(r, head)
+	if err == io.EOF {
+		return 0, "", nil, 0, io.EOF
+	}
+	if err != nil {
+		return 0, "", nil, 0, errors.Wrapf(errTornEntry, "%d bytes", n)
+	}
+
+	length := binary.BigEndian.Uint32(head[0:4])
+	if length < 2 || length > maxFileEntry {
+		return 0, "", nil, 0, errors.Errorf("the entry length %d is not valid", length)
+	}
+	size := int64(8 + length)
+	body := make([]byte, length)
+	n, err = io.ReadFull(r, body)
+	if err != nil {
+		return 0, "", nil, 0, errors.Wrapf(errTornEntry, "%d bytes", 8+n)
+	}
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(head[4:8]) {
+		return 0, "", nil, size, errors.New("the entry doesn't match its checksum")
+	}
+
+	op := body[0]
+	if op != fileOpSet && op != fileOpRemove {
+		return 0, "", nil, size, errors.Errorf("unknown operation %q", op)
+	}
+	keyLen, k := binary.Uvarint(body[1:])
+	if k <= 0 || keyLen > uint64(len(body)-1-k) {
+		return 0, "", nil, size, errors.New("the entry key is not valid")
+	}
+	start := 1 + k
+	key := string(body[start : start+int(keyLen)])
+	value := body[start+int(keyLen):]
+	return op, key, value, size, nil
+}
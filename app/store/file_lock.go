@@ -0,0 +1,43 @@
+//go:build !windows
+// +build !windows
+
+package store
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lockFile opens the lock file of a store log and takes it, so no other process opens the same log
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		f.Close()
+		return nil, errors.Errorf("the store log is in use by another process, as %s is locked", path)
+	}
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "locking %s", path)
+	}
+	return f, nil
+}
+
+// syncDir flushes the directory, so the files renamed in it are found after a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	cerr := d.Close()
+	if err == nil {
+		err = cerr
+	}
+	return err
+}
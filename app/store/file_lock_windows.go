@@ -0,0 +1,15 @@
+package store
+
+import (
+	"os"
+)
+
+// lockFile opens the lock file of a store log. It's not taken on Windows, so only one process must open the log.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+}
+
+// syncDir does nothing, as the directories can't be flushed on Windows
+func syncDir(dir string) error {
+	return nil
+}
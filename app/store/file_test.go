@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+func fileStoreConfig(t *testing.T, fsync string) *config.Config {
+	return &config.Config{
+		StoreCodec: config.StoreCodecGob,
+		StoreFile:  filepath.Join(t.TempDir(), "cdmon2.store"),
+		StoreFsync: fsync,
+	}
+}
+
+func openFileStore(t *testing.T, cfg *config.Config) *FileStore {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	f, err := NewFileStore(cfg, log)
+	require.NoError(t, err)
+	return f
+}
+
+func TestFileStore_Recovery(t *testing.T) {
+	for _, fsync := range []string{config.StoreFsyncAlways, config.StoreFsyncNever} {
+		t.Run(fsync, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := fileStoreConfig(t, fsync)
+			f := openFileStore(t, cfg)
+			require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+			require.NoError(t, f.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+			require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 23}))
+			require.NoError(t, f.Remove(ctx, "item:2"))
+			require.NoError(t, f.Close())
+
+			// The records are recovered when the log is opened again
+			f = openFileStore(t, cfg)
+			defer f.Close()
+			got, err := f.Get(ctx, "item:1", &Item{})
+			require.NoError(t, err)
+			assert.Equal(t, Item{Name: "Bartolo", Age: 23}, *got.(*Item))
+			_, err = f.Get(ctx, "item:2", &Item{})
+			assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+			assert.Equal(t, 4, f.entries)
+		})
+	}
+}
+
+func TestFileStore_BrokenTail(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+	require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+	require.NoError(t, f.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+	size := f.size
+	require.NoError(t, f.Close())
+
+	tests := []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{
+			name:    "given a log whose last entry was cut by a crash, when it's opened, then the entry is dropped",
+			corrupt: func(b []byte) []byte { return b[:len(b)-3] },
+		},
+		{
+			name: "given a log whose last entry doesn't match its checksum, when it's opened, then the entry is dropped",
+			corrupt: func(b []byte) []byte {
+				b[len(b)-1]++
+				return b
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(cfg.StoreFile)
+			require.NoError(t, err)
+			broken := filepath.Join(t.TempDir(), "broken.store")
+			require.NoError(t, ioutil.WriteFile(broken, tt.corrupt(append([]byte(nil), b...)), 0600))
+
+			cfg := *cfg
+			cfg.StoreFile = broken
+			f := openFileStore(t, &cfg)
+			defer f.Close()
+			keys, err := f.Keys(ctx, "*")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"item:1"}, keys)
+
+			// The log is cut after the last whole entry, so the next ones are appended after it
+			info, err := os.Stat(broken)
+			require.NoError(t, err)
+			assert.Equal(t, f.size, info.Size())
+			assert.True(t, f.size < size)
+			require.NoError(t, f.Set(ctx, "item:3", Item{Name: "Lola"}))
+			require.NoError(t, f.Close())
+			f = openFileStore(t, &cfg)
+			defer f.Close()
+			keys, err = f.Keys(ctx, "*")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"item:1", "item:3"}, keys)
+		})
+	}
+}
+
+func TestFileStore_Corrupted(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+	require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+	require.NoError(t, f.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+	require.NoError(t, f.Close())
+
+	first := len(fileLogHeader)
+	tests := []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{
+			name: "given a log whose middle entry doesn't match its checksum, when it's opened, then it's refused",
+			corrupt: func(b []byte) []byte {
+				b[first+10]++
+				return b
+			},
+		},
+		{
+			name: "given a log whose middle entry has a length not valid, when it's opened, then it's refused",
+			corrupt: func(b []byte) []byte {
+				copy(b[first:], []byte{0xff, 0xff, 0xff, 0xff})
+				return b
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ioutil.ReadFile(cfg.StoreFile)
+			require.NoError(t, err)
+			corrupted := tt.corrupt(append([]byte(nil), b...))
+			broken := filepath.Join(t.TempDir(), "broken.store")
+			require.NoError(t, ioutil.WriteFile(broken, corrupted, 0600))
+
+			cfg := *cfg
+			cfg.StoreFile = broken
+			log := logrus.New()
+			log.Out = ioutil.Discard
+			_, err = NewFileStore(&cfg, log)
+			assert.Error(t, err)
+
+			// The entries after the corrupted one are kept in the log, to be recovered by hand
+			got, err := ioutil.ReadFile(broken)
+			require.NoError(t, err)
+			assert.Equal(t, corrupted, got)
+		})
+	}
+}
+
+func TestFileStore_Compact(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+	for age := 0; age < 10; age++ {
+		require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: age}))
+	}
+	require.NoError(t, f.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+	require.NoError(t, f.Remove(ctx, "item:2"))
+	before := f.size
+
+	require.NoError(t, f.Compact())
+	assert.Equal(t, 1, f.entries)
+	assert.True(t, f.size < before)
+	info, err := os.Stat(cfg.StoreFile)
+	require.NoError(t, err)
+	assert.Equal(t, f.size, info.Size())
+
+	// The changes after the compaction are appended to the new log
+	require.NoError(t, f.Set(ctx, "item:3", Item{Name: "Lola"}))
+	require.NoError(t, f.Close())
+	f = openFileStore(t, cfg)
+	defer f.Close()
+	items, err := f.GetAll(ctx, "*", func() interface{} { return &Item{} })
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{&Item{Name: "Bartolo", Age: 9}, &Item{Name: "Lola"}}, items)
+}
+
+func TestFileStore_Flush(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+	require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+	require.NoError(t, f.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+
+	require.NoError(t, f.Flush())
+	keys, err := f.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// The store keeps working after the flush, and only the later changes are recovered
+	require.NoError(t, f.Set(ctx, "item:3", Item{Name: "Juan", Age: 44}))
+	require.NoError(t, f.Close())
+	f = openFileStore(t, cfg)
+	defer f.Close()
+	keys, err = f.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item:3"}, keys)
+	assert.Equal(t, 1, f.entries)
+}
+
+func TestFileStore_Load(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+	require.NoError(t, f.Load(ctx, []string{"item:*"}, map[string]interface{}{
+		"item:1": Item{Name: "Bartolo", Age: 22},
+		"item:2": Item{Name: "Maria", Age: 33},
+	}))
+	require.NoError(t, f.Close())
+
+	// The loaded records are written as a new log, so they're recovered as a whole
+	f = openFileStore(t, cfg)
+	defer f.Close()
+	keys, err := f.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item:1", "item:2"}, keys)
+	assert.Equal(t, 2, f.entries)
+}
+
+func TestFileStore_Periodic(t *testing.T) {
+	ctx := context.Background()
+	cfg := fileStoreConfig(t, config.StoreFsyncOnInterval)
+	cfg.StoreFsyncInterval = 10 * time.Millisecond
+	cfg.StoreCompactInterval = 10 * time.Millisecond
+	f := openFileStore(t, cfg)
+	defer f.Close()
+
+	require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+	require.NoError(t, f.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 23}))
+
+	// The log is flushed and compacted in the background
+	flushed := func() bool {
+		f.Lock()
+		defer f.Unlock()
+		return !f.dirty && f.entries == 1
+	}
+	deadline := time.Now().Add(time.Second)
+	for !flushed() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.True(t, flushed())
+}
+
+func TestFileStore_Exclusive(t *testing.T) {
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	f := openFileStore(t, cfg)
+
+	// Only one process opens the log at once, as the service and a maintenance command would corrupt it
+	_, err := NewFileStore(cfg, logrus.New())
+	assert.Error(t, err)
+
+	require.NoError(t, f.Close())
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(f.Ping()))
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(f.Set(context.Background(), "item:1", Item{})))
+	f = openFileStore(t, cfg)
+	assert.NoError(t, f.Close())
+}
+
+func TestFileStore_NotALog(t *testing.T) {
+	cfg := fileStoreConfig(t, config.StoreFsyncAlways)
+	require.NoError(t, ioutil.WriteFile(cfg.StoreFile, []byte("{}"), 0600))
+
+	_, err := NewFileStore(cfg, logrus.New())
+	assert.EqualError(t, err, "opening the store log "+cfg.StoreFile+": it's not a store log")
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+type (
+	// MemoryStore keeps the items in memory. They are serialized as the Redis store does,
+	// so the callers get copies of them, and the items which Redis would reject are rejected too.
+	MemoryStore struct {
+		sync.Mutex
+		records Records
+		items   map[string][]byte
+	}
+)
+
+// NewMemoryStore returns a memory store whose records are gob encoded, as the Redis store does by default
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithCodec(GobCodec{})
+}
+
+func NewMemoryStoreWithCodec(codec Codec) *MemoryStore {
+	return &MemoryStore{
+		records: NewRecords(codec),
+		items:   make(map[string][]byte),
+	}
+}
+
+func (m *MemoryStore) Connect() error {
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	bin, ok := m.items[key]
+	if !ok {
+		return nil, app.DbErrorNotFound
+	}
+	return m.records.Decode(bin, item)
+}
+
+// GetAll returns the items whose keys match the pattern, with the same syntax as the Redis KEYS command
+func (m *MemoryStore) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+	if len(pattern) == 0 {
+		pattern = "*"
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	keys := make([]string, 0, len(m.items))
+	for k := range m.items {
+		if matchKey(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	slice := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		item, err := m.records.Decode(m.items[k], emptyRecordFunc())
+		if err != nil {
+			return nil, err
+		}
+		slice = append(slice, item)
+	}
+	return slice, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, item interface{}) error {
+	bin, err := m.records.Encode(item)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s", key)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.items[key] = bin
+	return nil
+}
+
+// Remove deletes the key. As in Redis, removing a missing key is not an error.
+func (m *MemoryStore) Remove(ctx context.Context, key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// Keys returns the stored keys matching the pattern, sorted
+func (m *MemoryStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var keys []string
+	for k := range m.items {
+		if matchKey(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Load writes all the items at once, only if no key matches the patterns, else it fails with app.DbErrorInUse
+func (m *MemoryStore) Load(ctx context.Context, patterns []string, items map[string]interface{}) error {
+	bins, err := m.records.encodeAll(items)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	err = m.unused(patterns)
+	if err != nil {
+		return err
+	}
+	for k, bin := range bins {
+		m.items[k] = bin
+	}
+	return nil
+}
+
+// unused fails with app.DbErrorInUse if any key matches the patterns. The caller holds the lock of the store.
+func (m *MemoryStore) unused(patterns []string) error {
+	for _, pattern := range patterns {
+		for k := range m.items {
+			if matchKey(pattern, k) {
+				return errors.Wrapf(app.DbErrorInUse, "the store has keys matching %s, like %s", pattern, k)
+			}
+		}
+	}
+	return nil
+}
+
+// Migrate rewrites the outdated records of the keys matching the pattern, as the Redis store does
+func (m *MemoryStore) Migrate(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+	keys, err := m.Keys(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	set := func(key string, bin []byte) error {
+		m.put(key, bin)
+		return nil
+	}
+	return m.records.migrate(keys, emptyRecordFunc, m.raw, set)
+}
+
+// raw returns the record of the key as it's stored
+func (m *MemoryStore) raw(key string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+	bin, ok := m.items[key]
+	if !ok {
+		return nil, app.DbErrorNotFound
+	}
+	return bin, nil
+}
+
+// put stores the record of the key, already encoded
+func (m *MemoryStore) put(key string, bin []byte) {
+	m.Lock()
+	defer m.Unlock()
+	m.items[key] = bin
+}
+
+func (m *MemoryStore) delete(key string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.items, key)
+}
+
+// loadable fails with app.DbErrorInUse if any key matches the patterns, as Load does
+func (m *MemoryStore) loadable(patterns []string) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.unused(patterns)
+}
+
+// replace replaces all the stored records with the items, which are not copied
+func (m *MemoryStore) replace(items map[string][]byte) {
+	m.Lock()
+	defer m.Unlock()
+	m.items = items
+}
+
+// snapshot returns a copy of the stored records
+func (m *MemoryStore) snapshot() map[string][]byte {
+	m.Lock()
+	defer m.Unlock()
+	items := make(map[string][]byte, len(m.items))
+	for k, bin := range m.items {
+		items[k] = bin
+	}
+	return items
+}
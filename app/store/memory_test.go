@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+
+	assert.NoError(t, m.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+	assert.NoError(t, m.Set(ctx, "item:2", Item{Name: "Maria", Age: 33}))
+	assert.NoError(t, m.Set(ctx, "other:1", Item{Name: "Other"}))
+
+	// The items are got as copies
+	got, err := m.Get(ctx, "item:1", &Item{})
+	assert.NoError(t, err)
+	got.(*Item).Age = 0
+	got, err = m.Get(ctx, "item:1", &Item{})
+	assert.NoError(t, err)
+	assert.Equal(t, Item{Name: "Bartolo", Age: 22}, *got.(*Item))
+
+	// Only the keys matching the pattern are returned
+	items, err := m.GetAll(ctx, "item:*", func() interface{} { return &Item{} })
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{&Item{Name: "Bartolo", Age: 22}, &Item{Name: "Maria", Age: 33}}, items)
+
+	// As in Redis, missing keys are not found, and can be removed anyway
+	assert.NoError(t, m.Remove(ctx, "item:1"))
+	assert.NoError(t, m.Remove(ctx, "item:1"))
+	_, err = m.Get(ctx, "item:1", &Item{})
+	assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+	keys, err := m.Keys(ctx, "*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"item:2", "other:1"}, keys)
+
+	// The items which can't be serialized are rejected
+	assert.Error(t, m.Set(ctx, "item:3", func() {}))
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+// newClient returns the Redis client. Through Sentinel, the client follows the master on failovers.
+func newClient(cfg *config.Config) (*redis.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RedisSentinel() {
+		return redis.NewFailoverClient(failoverOptions(cfg, tlsConfig)), nil
+	}
+	return redis.NewClient(options(cfg, tlsConfig)), nil
+}
+
+func options(cfg *config.Config, tlsConfig *tls.Config) *redis.Options {
+	return &redis.Options{
+		Addr:         cfg.RedisAddr,
+		Password:     string(cfg.RedisPassword),
+		DB:           cfg.RedisDB,
+		DialTimeout:  cfg.RedisDialTimeout,
+		ReadTimeout:  cfg.StoreMaxTimeout(),
+		WriteTimeout: cfg.StoreMaxTimeout(),
+		PoolSize:     cfg.RedisPoolSize,
+		MinIdleConns: cfg.RedisMinIdleConns,
+		MaxConnAge:   cfg.RedisMaxConnAge,
+		PoolTimeout:  cfg.RedisPoolTimeout,
+		IdleTimeout:  cfg.RedisIdleTimeout,
+		TLSConfig:    tlsConfig,
+	}
+}
+
+func failoverOptions(cfg *config.Config, tlsConfig *tls.Config) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:    cfg.RedisSentinelMasterName,
+		SentinelAddrs: cfg.RedisSentinelAddrs,
+		Password:      string(cfg.RedisPassword),
+		DB:            cfg.RedisDB,
+		DialTimeout:   cfg.RedisDialTimeout,
+		ReadTimeout:   cfg.StoreMaxTimeout(),
+		WriteTimeout:  cfg.StoreMaxTimeout(),
+		PoolSize:      cfg.RedisPoolSize,
+		MinIdleConns:  cfg.RedisMinIdleConns,
+		MaxConnAge:    cfg.RedisMaxConnAge,
+		PoolTimeout:   cfg.RedisPoolTimeout,
+		IdleTimeout:   cfg.RedisIdleTimeout,
+		TLSConfig:     tlsConfig,
+	}
+}
+
+// newTLSConfig returns the TLS settings of the connection, or nil if it's not encrypted
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.RedisTLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.RedisTLSServerName,
+		InsecureSkipVerify: cfg.RedisTLSSkipVerify,
+	}
+	if len(tlsConfig.ServerName) == 0 && !cfg.RedisSentinel() {
+		host, _, err := net.SplitHostPort(cfg.RedisAddr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "redis address %s", cfg.RedisAddr)
+		}
+		tlsConfig.ServerName = host
+	}
+
+	if len(cfg.RedisTLSCAFile) > 0 {
+		pem, err := ioutil.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading the redis CA file")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in the redis CA file %s", cfg.RedisTLSCAFile)
+		}
+	}
+
+	if len(cfg.RedisTLSCertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(cfg.RedisTLSCertFile, cfg.RedisTLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading the redis client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+func TestOptions(t *testing.T) {
+	cfg := &config.Config{
+		RedisAddr:         "redis.local:6380",
+		RedisPassword:     "s3cr3t",
+		RedisDB:           2,
+		RedisPoolSize:     20,
+		RedisMinIdleConns: 5,
+		RedisDialTimeout:  time.Second,
+		StoreTimeout:      2 * time.Second,
+	}
+
+	o := options(cfg, nil)
+	assert.Equal(t, "redis.local:6380", o.Addr)
+	assert.Equal(t, "s3cr3t", o.Password)
+	assert.Equal(t, 2, o.DB)
+	assert.Equal(t, 20, o.PoolSize)
+	assert.Equal(t, 5, o.MinIdleConns)
+	assert.Equal(t, time.Second, o.DialTimeout)
+	assert.Equal(t, 2*time.Second, o.ReadTimeout)
+
+	cfg.StoreOpTimeouts = map[string]time.Duration{config.StoreOpLoad: 30 * time.Second, config.StoreOpSet: time.Second}
+	o = options(cfg, nil)
+	assert.Equal(t, 30*time.Second, o.ReadTimeout)
+	assert.Equal(t, 30*time.Second, o.WriteTimeout)
+
+	cfg.RedisSentinelAddrs = []string{"s1:26379"}
+	cfg.RedisSentinelMasterName = "mymaster"
+	f := failoverOptions(cfg, nil)
+	assert.Equal(t, "mymaster", f.MasterName)
+	assert.Equal(t, []string{"s1:26379"}, f.SentinelAddrs)
+	assert.Equal(t, "s3cr3t", f.Password)
+	assert.Equal(t, 2, f.DB)
+	assert.Equal(t, 20, f.PoolSize)
+	assert.Equal(t, 30*time.Second, f.ReadTimeout)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	badCA := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(badCA, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		wantNil        bool
+		wantServerName string
+		wantErr        bool
+	}{
+		{
+			name:    "given tls disabled, when the connection is set up, then it's not encrypted",
+			cfg:     &config.Config{RedisAddr: "redis.local:6379"},
+			wantNil: true,
+		},
+		{
+			name:           "given tls without server name, when the connection is set up, then the redis host is verified",
+			cfg:            &config.Config{RedisAddr: "redis.local:6379", RedisTLS: true},
+			wantServerName: "redis.local",
+		},
+		{
+			name:           "given tls with server name, when the connection is set up, then the name is verified",
+			cfg:            &config.Config{RedisAddr: "10.0.0.1:6379", RedisTLS: true, RedisTLSServerName: "redis.local"},
+			wantServerName: "redis.local",
+		},
+		{
+			name:    "given a wrong CA file, when the connection is set up, then it fails",
+			cfg:     &config.Config{RedisAddr: "redis.local:6379", RedisTLS: true, RedisTLSCAFile: badCA},
+			wantErr: true,
+		},
+		{
+			name:    "given a missing client certificate, when the connection is set up, then it fails",
+			cfg:     &config.Config{RedisAddr: "redis.local:6379", RedisTLS: true, RedisTLSCertFile: "missing.pem", RedisTLSKeyFile: "missing.key"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTLSConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.wantServerName, got.ServerName)
+		})
+	}
+}
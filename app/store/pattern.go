@@ -0,0 +1,80 @@
+package store
+
+// matchKey tells whether the key matches the pattern, with the syntax of the Redis KEYS command, so the local
+// stores select the same keys as Redis: * matches any sequence, slashes too, ? any character, [abc], [^abc]
+// and [a-c] the characters of the class, and \ escapes the next character.
+func matchKey(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchKey(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var ok bool
+			ok, pattern = matchClass(pattern[1:], key[0])
+			if !ok {
+				return false
+			}
+			key = key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass tells whether c belongs to the class at the start of the pattern, right after its [,
+// and returns the pattern after the class. As in Redis, a class without ] ends with the pattern.
+func matchClass(pattern string, c byte) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return match != not, pattern
+}
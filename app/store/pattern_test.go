@@ -0,0 +1,38 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{pattern: "*", key: "", want: true},
+		{pattern: "hosting:*", key: "hosting:1", want: true},
+		{pattern: "hosting:*", key: "hosting-name:web", want: false},
+		{pattern: "hosting-name:*", key: "hosting-name:web/api", want: true},
+		{pattern: "project-name:*:web", key: "project-name:c1:web", want: true},
+		{pattern: "project-name:*:web", key: "project-name:c1:api", want: false},
+		{pattern: "item:?", key: "item:1", want: true},
+		{pattern: "item:?", key: "item:10", want: false},
+		{pattern: "item:[12]", key: "item:2", want: true},
+		{pattern: "item:[^12]", key: "item:2", want: false},
+		{pattern: "item:[a-c]", key: "item:b", want: true},
+		{pattern: "item:[c-a]", key: "item:b", want: true},
+		{pattern: "item:[a-c]", key: "item:d", want: false},
+		{pattern: `item:\*`, key: "item:*", want: true},
+		{pattern: `item:\*`, key: "item:1", want: false},
+		{pattern: "item:[1", key: "item:1", want: true},
+		{pattern: "item", key: "item:1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchKey(tt.pattern, tt.key))
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+// The stored records start with a header line which tells how their payload is encoded:
+//
+//	cdmon2:<format>:<codec>:<schema>
+//
+// The format is the layout of the record, and the schema is the version of the item type,
+// which grows with each upgrade registered for it. The records written before the header
+// was introduced are read as gob encoded records of the schema 0.
+const (
+	recordMagic  = "cdmon2"
+	recordFormat = 1
+)
+
+type (
+	// Upgrade turns the payload of a record into the payload of the next schema of its type, both encoded with the codec.
+	// It's usually done by decoding the payload into the former type, and encoding its conversion to the current one.
+	Upgrade func(codec Codec, payload []byte) ([]byte, error)
+
+	// Records encodes the items as versioned records with a codec, and decodes
+	// the records of any codec and schema, upgrading them to the current schema
+	Records struct {
+		codec Codec
+	}
+
+	header struct {
+		format int
+		codec  string
+		schema int
+	}
+)
+
+// The upgrades of each type, by their schema. The current schema of a type is the number of its upgrades.
+var upgrades = struct {
+	sync.RWMutex
+	byKind map[string][]Upgrade
+}{byKind: make(map[string][]Upgrade)}
+
+// RegisterUpgrade registers the upgrade of the records of the item type, from the schema to the next one.
+// The upgrades of a type must be registered in order, usually from an init function, as gob.Register.
+func RegisterUpgrade(item interface{}, from int, upgrade Upgrade) {
+	upgrades.Lock()
+	defer upgrades.Unlock()
+
+	kind := kindOf(item)
+	if from != len(upgrades.byKind[kind]) {
+		panic(fmt.Sprintf("the upgrade of %s from the schema %d is registered out of order, the next one is from %d", kind, from, len(upgrades.byKind[kind])))
+	}
+	upgrades.byKind[kind] = append(upgrades.byKind[kind], upgrade)
+}
+
+// Schema returns the current schema of the item type
+func Schema(item interface{}) int {
+	upgrades.RLock()
+	defer upgrades.RUnlock()
+	return len(upgrades.byKind[kindOf(item)])
+}
+
+// kindOf names the type of the item, the same for its values and its pointers
+func kindOf(item interface{}) string {
+	t := reflect.TypeOf(item)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return fmt.Sprint(t)
+}
+
+func NewRecords(codec Codec) Records {
+	return Records{codec: codec}
+}
+
+// Encode encodes the item as a record of the current schema of its type
+func (r Records) Encode(item interface{}) ([]byte, error) {
+	payload, err := r.codec.Encode(item)
+	if err != nil {
+		return nil, err
+	}
+	h := fmt.Sprintf("%s:%d:%s:%d\n", recordMagic, recordFormat, r.codec.Name(), Schema(item))
+	return append([]byte(h), payload...), nil
+}
+
+// encodeAll encodes the items by key, failing on the first one which can't be encoded
+func (r Records) encodeAll(items map[string]interface{}) (map[string][]byte, error) {
+	bins := make(map[string][]byte, len(items))
+	for k, item := range items {
+		bin, err := r.Encode(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding %s", k)
+		}
+		bins[k] = bin
+	}
+	return bins, nil
+}
+
+// Decode decodes the record into the item, which must be a pointer. The records of former schemas are upgraded
+// before, while the ones written by a newer version of the service are refused, as their fields could be lost.
+func (r Records) Decode(b []byte, item interface{}) (interface{}, error) {
+	h, payload, err := parseRecord(b)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := NewCodec(h.codec)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := kindOf(item)
+	upgrades.RLock()
+	steps := upgrades.byKind[kind]
+	upgrades.RUnlock()
+	if h.schema > len(steps) {
+		return nil, errors.Errorf("the %s record has the schema %d, newer than the current %d", kind, h.schema, len(steps))
+	}
+	for schema := h.schema; schema < len(steps); schema++ {
+		payload, err = steps[schema](codec, payload)
+		if err != nil {
+			return nil, errors.Wrapf(err, "upgrading the %s record from the schema %d", kind, schema)
+		}
+	}
+
+	err = codec.Decode(payload, item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Outdated tells if the record is not written as Encode would write it, because it has no header,
+// or it's encoded with another codec, or it has a former schema of the item type
+func (r Records) Outdated(b []byte, item interface{}) (bool, error) {
+	h, _, err := parseRecord(b)
+	if err != nil {
+		return false, err
+	}
+	return h.format != recordFormat || h.codec != r.codec.Name() || h.schema != Schema(item), nil
+}
+
+// parseRecord splits the record into its header and its payload
+func parseRecord(b []byte) (header, []byte, error) {
+	if !bytes.HasPrefix(b, []byte(recordMagic+":")) {
+		return header{codec: GobCodec{}.Name()}, b, nil
+	}
+
+	end := bytes.IndexByte(b, '\n')
+	if end < 0 {
+		return header{}, nil, errors.New("the record header is not terminated")
+	}
+	fields := bytes.Split(b[len(recordMagic)+1:end], []byte(":"))
+	if len(fields) != 3 {
+		return header{}, nil, errors.Errorf("malformed record header %q", b[:end])
+	}
+
+	var h header
+	var err error
+	h.format, err = strconv.Atoi(string(fields[0]))
+	if err != nil || h.format != recordFormat {
+		return header{}, nil, errors.Errorf("unknown record format %q", fields[0])
+	}
+	h.codec = string(fields[1])
+	h.schema, err = strconv.Atoi(string(fields[2]))
+	if err != nil || h.schema < 0 {
+		return header{}, nil, errors.Errorf("malformed record schema %q", fields[2])
+	}
+	return h, b[end+1:], nil
+}
+
+// migrate rewrites the outdated records of the keys, and returns how many were rewritten.
+// The records removed since the keys were listed are skipped.
+func (r Records) migrate(keys []string, emptyRecordFunc config.EmptyRecordFunc, get func(key string) ([]byte, error), set func(key string, bin []byte) error) (int, error) {
+	migrated := 0
+	for _, k := range keys {
+		bin, err := get(k)
+		switch errors.Cause(err) {
+		case nil:
+		case app.DbErrorNotFound:
+			continue
+		default:
+			return migrated, err
+		}
+
+		item := emptyRecordFunc()
+		outdated, err := r.Outdated(bin, item)
+		if err != nil {
+			return migrated, errors.Wrapf(err, "migrating %s", k)
+		}
+		if !outdated {
+			continue
+		}
+
+		item, err = r.Decode(bin, item)
+		if err == nil {
+			bin, err = r.Encode(item)
+		}
+		if err == nil {
+			err = set(k, bin)
+		}
+		if err != nil {
+			return migrated, errors.Wrapf(err, "migrating %s", k)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
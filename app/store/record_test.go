@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// person is the current schema of a record type with two upgrades: the first one renamed
+	// its FullName field to Name, and the second one renamed its Years field to Age
+	person struct {
+		Name string
+		Age  int
+	}
+	personV0 struct {
+		FullName string
+		Years    int
+	}
+	personV1 struct {
+		Name  string
+		Years int
+	}
+)
+
+func init() {
+	RegisterUpgrade(person{}, 0, func(codec Codec, payload []byte) ([]byte, error) {
+		var v0 personV0
+		err := codec.Decode(payload, &v0)
+		if err != nil {
+			return nil, err
+		}
+		return codec.Encode(personV1{Name: v0.FullName, Years: v0.Years})
+	})
+	RegisterUpgrade(person{}, 1, func(codec Codec, payload []byte) ([]byte, error) {
+		var v1 personV1
+		err := codec.Decode(payload, &v1)
+		if err != nil {
+			return nil, err
+		}
+		return codec.Encode(person{Name: v1.Name, Age: v1.Years})
+	})
+}
+
+// record returns a record of the item with the header, or a header-less one if it's empty
+func record(t *testing.T, header string, codec Codec, item interface{}) []byte {
+	payload, err := codec.Encode(item)
+	require.NoError(t, err)
+	return append([]byte(header), payload...)
+}
+
+func TestRecords_Encode(t *testing.T) {
+	for _, codec := range []Codec{GobCodec{}, JSONCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			r := NewRecords(codec)
+
+			// The header tells the codec and the current schema of the type
+			b, err := r.Encode(Item{Name: "Bartolo", Age: 22})
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(b), "cdmon2:1:"+codec.Name()+":0\n"), string(b))
+			b, err = r.Encode(&person{Name: "Bartolo", Age: 22})
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(b), "cdmon2:1:"+codec.Name()+":2\n"), string(b))
+
+			got, err := r.Decode(b, &person{})
+			require.NoError(t, err)
+			assert.Equal(t, &person{Name: "Bartolo", Age: 22}, got)
+		})
+	}
+}
+
+func TestRecords_Decode(t *testing.T) {
+	want := &person{Name: "Bartolo", Age: 22}
+	tests := []struct {
+		name         string
+		record       []byte
+		wantOutdated bool
+		wantErr      string
+	}{
+		{
+			name:   "given a current record, when it's decoded, then it's read as is",
+			record: record(t, "cdmon2:1:gob:2\n", GobCodec{}, person{Name: "Bartolo", Age: 22}),
+		},
+		{
+			name:         "given a record written before the headers, when it's decoded, then it's upgraded from the schema 0",
+			record:       record(t, "", GobCodec{}, personV0{FullName: "Bartolo", Years: 22}),
+			wantOutdated: true,
+		},
+		{
+			name:         "given a record of another codec and a former schema, when it's decoded, then it's upgraded with that codec",
+			record:       record(t, "cdmon2:1:json:1\n", JSONCodec{}, personV1{Name: "Bartolo", Years: 22}),
+			wantOutdated: true,
+		},
+		{
+			name:    "given a record of a newer schema, when it's decoded, then it's refused",
+			record:  record(t, "cdmon2:1:gob:3\n", GobCodec{}, person{Name: "Bartolo", Age: 22}),
+			wantErr: "the store.person record has the schema 3, newer than the current 2",
+		},
+		{
+			name:    "given a record of an unknown codec, when it's decoded, then it's refused",
+			record:  record(t, "cdmon2:1:xml:2\n", GobCodec{}, person{Name: "Bartolo", Age: 22}),
+			wantErr: `unknown store codec "xml"`,
+		},
+		{
+			name:    "given a record of an unknown format, when it's decoded, then it's refused",
+			record:  record(t, "cdmon2:2:gob:2\n", GobCodec{}, person{Name: "Bartolo", Age: 22}),
+			wantErr: `unknown record format "2"`,
+		},
+		{
+			name:    "given a malformed header, when it's decoded, then it's refused",
+			record:  []byte("cdmon2:1:gob"),
+			wantErr: "the record header is not terminated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecords(GobCodec{})
+			got, err := r.Decode(tt.record, &person{})
+			if len(tt.wantErr) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			outdated, err := r.Outdated(tt.record, &person{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOutdated, outdated)
+		})
+	}
+}
+
+func TestRecords_RegisterUpgradeOutOfOrder(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterUpgrade(person{}, 1, func(codec Codec, payload []byte) ([]byte, error) { return payload, nil })
+	})
+}
+
+func TestMemoryStore_Migrate(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStoreWithCodec(JSONCodec{})
+	m.items["person:1"] = record(t, "", GobCodec{}, personV0{FullName: "Bartolo", Years: 22})
+	m.items["person:2"] = record(t, "cdmon2:1:gob:2\n", GobCodec{}, person{Name: "Maria", Age: 33})
+	require.NoError(t, m.Set(ctx, "person:3", person{Name: "Other"}))
+	require.NoError(t, m.Set(ctx, "other:1", person{Name: "Other"}))
+	emptyRecordFunc := func() interface{} { return &person{} }
+
+	// The records of other codecs and schemas are rewritten with the current ones
+	migrated, err := m.Migrate(ctx, "person:*", emptyRecordFunc)
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+	assert.Equal(t, `cdmon2:1:json:2`+"\n"+`{"Name":"Bartolo","Age":22}`, string(m.items["person:1"]))
+	got, err := m.Get(ctx, "person:2", &person{})
+	require.NoError(t, err)
+	assert.Equal(t, &person{Name: "Maria", Age: 33}, got)
+
+	// So a second migration has nothing to do
+	migrated, err = m.Migrate(ctx, "person:*", emptyRecordFunc)
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
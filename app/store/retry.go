@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Backoff is an exponential backoff with full jitter. The delay before each retry is random,
+	// up to Base doubled on each attempt, and never beyond Max. The jitter keeps the instances
+	// which failed at once from retrying all together.
+	Backoff struct {
+		Base time.Duration
+		Max  time.Duration
+	}
+
+	// Retry retries the failed operations, up to Retries more times
+	Retry struct {
+		Retries int
+		Backoff Backoff
+	}
+)
+
+// Delay returns the delay before the given retry, counting from zero
+func (b Backoff) Delay(retry int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	ceil := b.Base
+	for z := 0; z < retry && (b.Max <= 0 || ceil < b.Max); z++ {
+		ceil *= 2
+	}
+	if b.Max > 0 && ceil > b.Max {
+		ceil = b.Max
+	}
+	return time.Duration(rand.Int63n(int64(ceil) + 1))
+}
+
+// Do runs f until it succeeds, it fails with an error which is not worth retrying, or the retries are exhausted.
+// The context is honored while waiting to retry, and no retry is done once it's done.
+func (r Retry) Do(ctx context.Context, retryable func(err error) bool, f func() error) error {
+	for retry := 0; ; retry++ {
+		err := f()
+		if err == nil || retry >= r.Retries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(r.Backoff.Delay(retry)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// transient tells if the error is caused by Redis being unreachable, slow or not ready to serve, so the operation
+// could succeed if it's retried. The errors replied by a healthy Redis, like a missing key, are not transient.
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	cause := errors.Cause(err)
+	if cause == io.EOF || cause == io.ErrUnexpectedEOF || cause == context.DeadlineExceeded {
+		return true
+	}
+	if _, ok := cause.(net.Error); ok {
+		return true
+	}
+	s := cause.Error()
+	for _, prefix := range []string{"LOADING ", "READONLY ", "CLUSTERDOWN ", "ERR max number of clients reached", "redis: connection pool timeout"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
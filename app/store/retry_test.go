@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Base: 10 * time.Millisecond, Max: 40 * time.Millisecond}
+	for retry, ceil := range []time.Duration{10, 20, 40, 40, 40} {
+		for z := 0; z < 100; z++ {
+			d := b.Delay(retry)
+			assert.True(t, d >= 0 && d <= ceil*time.Millisecond, "retry %d, delay %s", retry, d)
+		}
+	}
+	assert.Equal(t, time.Duration(0), Backoff{}.Delay(3))
+}
+
+func TestRetry_Do(t *testing.T) {
+	errPermanent := errors.New("permanent")
+
+	tests := []struct {
+		name      string
+		retries   int
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "given a retry, when the operation succeeds, then it's not retried",
+			retries:   2,
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "given a retry, when the operation fails once, then it's retried until it succeeds",
+			retries:   2,
+			errs:      []error{io.EOF, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "given a retry, when the operation keeps failing, then the retries are bounded",
+			retries:   2,
+			errs:      []error{io.EOF, io.EOF, io.EOF, nil},
+			wantCalls: 3,
+			wantErr:   io.EOF,
+		},
+		{
+			name:      "given a retry, when the operation fails for good, then it's not retried",
+			retries:   2,
+			errs:      []error{errPermanent, nil},
+			wantCalls: 1,
+			wantErr:   errPermanent,
+		},
+		{
+			name:      "given a retry, when a key is missing, then it's not retried",
+			retries:   2,
+			errs:      []error{redis.Nil, nil},
+			wantCalls: 1,
+			wantErr:   redis.Nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r := Retry{Retries: tt.retries, Backoff: Backoff{Base: time.Millisecond}}
+			err := r.Do(context.Background(), transient, func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestRetry_DoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := Retry{Retries: 10, Backoff: Backoff{Base: time.Hour}}
+
+	// The wait to retry ends with the context
+	calls := 0
+	start := time.Now()
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := r.Do(ctx, transient, func() error {
+		calls++
+		return io.EOF
+	})
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, calls <= 2)
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -1,9 +1,13 @@
 package store
 
 import (
-	"bytes"
+	"context"
 	"encoding/gob"
-	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/theskyinflames/cdmon2/app"
 
@@ -11,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/requestid"
 
 	"github.com/go-redis/redis"
 )
@@ -18,78 +23,95 @@ import (
 func init() {
 	gob.Register(domain.Server{})
 	gob.Register(domain.Hosting{})
+	gob.Register(domain.Customer{})
+	gob.Register(domain.Project{})
+	gob.Register(domain.APIKey{})
 }
 
+// The delays between retries grow up to this number of times their base
+const maxBackoffFactor = 32
+
 type (
 	Store struct {
-		log  *logrus.Logger
-		cfg  *config.Config
-		conn *redis.Client
+		log     *logrus.Logger
+		cfg     *config.Config
+		conn    *redis.Client
+		retry   Retry
+		breaker *Breaker
+		records Records
 	}
 )
 
 func NewStore(cfg *config.Config, log *logrus.Logger) (*Store, error) {
+	codec, err := NewCodec(cfg.StoreCodec)
+	if err != nil {
+		return nil, err
+	}
 	s := &Store{
 		log: log,
 		cfg: cfg,
+		retry: Retry{
+			Retries: cfg.StoreRetries,
+			Backoff: Backoff{Base: cfg.StoreRetryBackoff, Max: maxBackoffFactor * cfg.StoreRetryBackoff},
+		},
+		breaker: NewBreaker(cfg.StoreBreakerFailures, cfg.StoreBreakerCooldown),
+		records: NewRecords(codec),
 	}
-	err := s.Connect()
+	err = s.Connect()
 	if err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-func (_ *Store) ItemToJSON(item interface{}) ([]byte, error) {
-	return json.Marshal(item)
-}
+// Connect connects to Redis. As Redis could be still starting, the connection is tried several times.
+func (s *Store) Connect() error {
 
-func (_ *Store) FromJSONToItem(b []byte, item interface{}) (interface{}, error) {
-	err := json.Unmarshal(b, item)
-	return item, err
-}
+	addr := s.cfg.RedisAddr
+	if s.cfg.RedisSentinel() {
+		addr = fmt.Sprintf("master %s through the sentinels %s", s.cfg.RedisSentinelMasterName, strings.Join(s.cfg.RedisSentinelAddrs, ","))
+	}
+	s.log.Infof("redis connection at %s, db %d, tls %t", addr, s.cfg.RedisDB, s.cfg.RedisTLS)
 
-// Item serializing
-func (_ *Store) ItemToGob(item interface{}) ([]byte, error) {
-	b := bytes.Buffer{}
-	e := gob.NewEncoder(&b)
-	err := e.Encode(item)
+	var err error
+	s.conn, err = newClient(s.cfg)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return b.Bytes(), nil
-}
 
-// Item deserializing
-func (_ *Store) FromGobToItem(b []byte, item interface{}) (interface{}, error) {
-	buff := bytes.Buffer{}
-	buff.Write(b)
-	d := gob.NewDecoder(&buff)
-	err := d.Decode(item) // item must be a pointer
-	if err != nil {
-		return nil, err
+	backoff := Backoff{Base: s.cfg.RedisConnectBackoff, Max: maxBackoffFactor * s.cfg.RedisConnectBackoff}
+	attempts := s.cfg.RedisConnectAttempts
+	if attempts < 1 {
+		attempts = 1
 	}
-	return item, nil
-}
 
-func (s *Store) Connect() error {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = s.conn.Ping().Err()
+		if err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			delay := backoff.Delay(attempt - 1)
+			s.log.Warnf("redis at %s is not reachable, attempt %d of %d, retrying in %s: %s", addr, attempt, attempts, delay, err.Error())
+			time.Sleep(delay)
+		}
+	}
+	s.conn.Close()
+	return errors.Wrapf(err, "redis at %s is not reachable after %d attempts", addr, attempts)
+}
 
-	s.log.Infof("redis connection at %s", s.cfg.RedisAddr)
-	s.conn = redis.NewClient(&redis.Options{
-		Addr:     s.cfg.RedisAddr,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+// Client returns the Redis connection, to be shared with other Redis based components
+func (s *Store) Client() *redis.Client {
+	return s.conn
+}
 
-	status := s.conn.Ping()
-	if status.Err() != nil {
-		return status.Err()
-	}
-	return nil
+func (s *Store) Ping() error {
+	return s.conn.Ping().Err()
 }
 
+// Flush removes all the keys of the Redis database of the store
 func (s *Store) Flush() error {
-	res := s.conn.FlushAll()
+	res := s.conn.FlushDB()
 	return res.Err()
 }
 
@@ -97,9 +119,80 @@ func (s *Store) Close() error {
 	return s.conn.Close()
 }
 
-func (s *Store) Get(key string, item interface{}) (interface{}, error) {
-	res := s.conn.Get(key)
-	err := res.Err()
+// Breaker returns the circuit breaker of the store operations
+func (s *Store) Breaker() *Breaker {
+	return s.breaker
+}
+
+// do runs the operation, retrying it if Redis fails. Getting, setting or removing a whole key are idempotent,
+// and an attempt of a write is never abandoned while it can still land, see attempt, so a retry or a rollback
+// of a write always comes after it. While the circuit breaker is open, the operation fails fast with
+// app.DbErrorUnavailable.
+func (s *Store) do(ctx context.Context, op string, f func(conn *redis.Client) error) error {
+	return s.guard(ctx, op, func() error {
+		return s.retry.Do(ctx, transient, func() error {
+			return s.attempt(ctx, op, f)
+		})
+	})
+}
+
+// guard runs the operation through the circuit breaker, which is told how Redis did
+func (s *Store) guard(ctx context.Context, op string, run func() error) error {
+	err := s.breaker.Allow()
+	if err != nil {
+		return errors.Wrapf(err, "store %s operation", op)
+	}
+
+	err = run()
+
+	// A caller which gives up doesn't tell anything about the Redis health
+	if err != nil && ctx.Err() != nil {
+		s.breaker.Release()
+		return err
+	}
+	s.breaker.Done(transient(err))
+	return err
+}
+
+// attempt runs the operation within its timeout. As the Redis client doesn't stop on the context cancellation,
+// a read is abandoned when the context is done, and it's left to end on its own connection timeouts. A write
+// is waited for instead, as it could still be waiting for a connection or be in flight, and land after the retry
+// or the rollback which follows its failure, bringing back a record already removed. The wait is bounded by the
+// pool and socket timeouts of the client.
+func (s *Store) attempt(ctx context.Context, op string, f func(conn *redis.Client) error) error {
+	if timeout := s.cfg.StoreOpTimeout(op); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f(s.conn.WithContext(ctx))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if writes(op) {
+			<-done
+		}
+		return errors.Wrapf(ctx.Err(), "store %s operation", op)
+	}
+}
+
+// writes tells if the store operation changes the records
+func writes(op string) bool {
+	return op == config.StoreOpSet || op == config.StoreOpRemove || op == config.StoreOpLoad
+}
+
+func (s *Store) Get(ctx context.Context, key string, item interface{}) (interface{}, error) {
+	var bin string
+	err := s.do(ctx, config.StoreOpGet, func(conn *redis.Client) (err error) {
+		bin, err = conn.Get(key).Result()
+		return
+	})
 	if err != nil {
 		switch errors.Cause(err) {
 		case redis.Nil:
@@ -108,44 +201,156 @@ func (s *Store) Get(key string, item interface{}) (interface{}, error) {
 			return nil, err
 		}
 	}
-
-	bin, err := res.Result()
-	if err != nil {
-		return nil, err
-	}
-	return s.FromGobToItem([]byte(bin), item)
+	return s.records.Decode([]byte(bin), item)
 }
 
-func (s *Store) GetAll(pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
+// GetAll returns the items whose keys match the pattern, sorted by their keys
+func (s *Store) GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error) {
 	if len(pattern) == 0 {
 		pattern = "*"
 	}
-	res := s.conn.Keys(pattern)
-	if res.Err() != nil {
-		return nil, res.Err()
-	}
 
-	var keys []string = res.Val()
-	s.log.Infof("retrieved %d hostings to GetAll()", len(keys))
-	slice := make([]interface{}, len(keys))
-	for z, k := range keys {
-		item, err := s.Get(k, emptyRecordFunc())
+	var slice []interface{}
+	err := s.do(ctx, config.StoreOpGetAll, func(conn *redis.Client) error {
+		keys, err := conn.Keys(pattern).Result()
 		if err != nil {
-			return nil, err
+			return err
+		}
+		sort.Strings(keys)
+
+		requestid.Logger(s.log, ctx).Infof("retrieved %d keys matching %s", len(keys), pattern)
+		slice = make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			bin, err := conn.Get(k).Result()
+			switch errors.Cause(err) {
+			case nil:
+			case redis.Nil:
+				// Removed since the keys were listed
+				continue
+			default:
+				return err
+			}
+
+			item, err := s.records.Decode([]byte(bin), emptyRecordFunc())
+			if err != nil {
+				return err
+			}
+			slice = append(slice, item)
 		}
-		slice[z] = item
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return slice, nil
 }
 
-func (s *Store) Set(key string, item interface{}) error {
-	bin, err := s.ItemToGob(item)
+func (s *Store) Set(ctx context.Context, key string, item interface{}) error {
+	bin, err := s.records.Encode(item)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s", key)
+	}
+	return s.do(ctx, config.StoreOpSet, func(conn *redis.Client) error {
+		return conn.Set(key, bin, 0).Err()
+	})
+}
+
+// Keys returns the keys matching the pattern, sorted
+func (s *Store) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := s.do(ctx, config.StoreOpKeys, func(conn *redis.Client) (err error) {
+		keys, err = conn.Keys(pattern).Result()
+		return
+	})
 	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *Store) Remove(ctx context.Context, key string) error {
+	return s.do(ctx, config.StoreOpRemove, func(conn *redis.Client) error {
+		return conn.Del(key).Err()
+	})
+}
+
+// Load writes all the items in a single MULTI/EXEC transaction, only if no key matches the patterns, else it fails
+// with app.DbErrorInUse. The keys of the items are watched from the check on, so the transaction is aborted if any
+// of them is written meanwhile. It's not retried, as a retry after a lost reply would find its own keys: if the
+// transaction could have been applied when it fails, it fails with app.DbErrorUnknownOutcome.
+func (s *Store) Load(ctx context.Context, patterns []string, items map[string]interface{}) error {
+	bins, err := s.records.encodeAll(items)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(bins))
+	for k := range bins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sent int32
+	err = s.guard(ctx, config.StoreOpLoad, func() error {
+		return s.attempt(ctx, config.StoreOpLoad, func(conn *redis.Client) error {
+			return conn.Watch(func(tx *redis.Tx) error {
+				for _, pattern := range patterns {
+					found, err := tx.Keys(pattern).Result()
+					if err != nil {
+						return err
+					}
+					if len(found) > 0 {
+						return errors.Wrapf(app.DbErrorInUse, "the redis database has %d keys matching %s", len(found), pattern)
+					}
+				}
+
+				atomic.StoreInt32(&sent, 1)
+				_, err := tx.TxPipelined(func(pipe redis.Pipeliner) error {
+					for _, k := range keys {
+						pipe.Set(k, bins[k], 0)
+					}
+					return nil
+				})
+				return err
+			}, keys...)
+		})
+	})
+	switch {
+	case err == nil:
 		return nil
+	case errors.Cause(err) == redis.TxFailedErr:
+		return errors.Wrap(app.DbErrorInUse, "the redis database changed while it was loaded")
+	case atomic.LoadInt32(&sent) == 1 && (transient(err) || ctx.Err() != nil):
+		return errors.Wrapf(app.DbErrorUnknownOutcome, "the reply of the load was lost, it may have been written: %s", err.Error())
+	default:
+		return err
 	}
-	return s.conn.Set(key, bin, 0).Err()
 }
 
-func (s *Store) Remove(key string) error {
-	return s.conn.Del(key).Err()
+// Migrate rewrites the records of the keys matching the pattern which are not written with the current
+// codec and schema, see Records.Outdated, and returns how many were rewritten. A record changed between
+// its read and its rewrite would lose the change, so it's meant to be run with the service stopped.
+func (s *Store) Migrate(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) (int, error) {
+	keys, err := s.Keys(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	get := func(key string) ([]byte, error) {
+		var bin string
+		err := s.do(ctx, config.StoreOpGet, func(conn *redis.Client) (err error) {
+			bin, err = conn.Get(key).Result()
+			return
+		})
+		if errors.Cause(err) == redis.Nil {
+			return nil, app.DbErrorNotFound
+		}
+		return []byte(bin), err
+	}
+	set := func(key string, bin []byte) error {
+		return s.do(ctx, config.StoreOpSet, func(conn *redis.Client) error {
+			return conn.Set(key, bin, 0).Err()
+		})
+	}
+	return s.records.migrate(keys, emptyRecordFunc, get, set)
 }
@@ -1,10 +1,24 @@
 package store
 
 import (
-	"reflect"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
 )
 
 type (
@@ -14,71 +28,285 @@ type (
 	}
 )
 
-func TestStorage_ItemToGob(t *testing.T) {
-	type args struct {
-		item interface{}
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name:    "given a storage, when a item is serialized, then all works fine",
-			args:    args{Item{Name: "Bartolo", Age: 22}},
-			wantErr: false,
-		},
+// newStuckStore returns a store connected to a server which never answers
+func newStuckStore(t *testing.T, cfg *config.Config) *Store {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			s := &Store{}
-			got, err := s.ItemToGob(tt.args.item)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Storage.ItemToGob() error = %v, wantErr %v", err, tt.wantErr)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
 				return
 			}
-			assert.True(t, len(got) > 0)
-		})
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	conn := redis.NewClient(&redis.Options{Addr: l.Addr().String(), ReadTimeout: time.Minute, MaxRetries: 0})
+	t.Cleanup(func() { conn.Close() })
+	return &Store{log: logrus.New(), cfg: cfg, conn: conn, records: NewRecords(GobCodec{})}
+}
+
+func TestStore_Timeouts(t *testing.T) {
+	cfg := &config.Config{
+		StoreTimeout:    time.Hour,
+		StoreOpTimeouts: map[string]time.Duration{config.StoreOpGet: 50 * time.Millisecond},
 	}
+	s := newStuckStore(t, cfg)
+
+	// The operation timeout is applied
+	start := time.Now()
+	_, err := s.Get(context.Background(), "k", &Item{})
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// So is the caller deadline, if it's shorter
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = s.Keys(ctx, "k*")
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	assert.True(t, time.Since(start) < time.Second)
 }
 
-func TestStorage_FromGobToItem(t *testing.T) {
+func TestStore_SetEncodingError(t *testing.T) {
+	s := newStuckStore(t, &config.Config{StoreTimeout: time.Hour})
 
-	item := Item{Name: "Bartolo", Age: 22}
+	// The item is rejected before reaching the server
+	err := s.Set(context.Background(), "k", func() {})
+	assert.Error(t, err)
+	assert.NotEqual(t, context.DeadlineExceeded, errors.Cause(err))
+}
 
-	s := &Store{}
-	bin, err := s.ItemToGob(item)
+// newDroppingStore returns a store connected to a server which drops every connection,
+// and the counter of the connections it has dropped
+func newDroppingStore(t *testing.T, cfg *config.Config) (*Store, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		t.Fail()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	var dropped int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&dropped, 1)
+			conn.Close()
+		}
+	}()
+
+	cfg.RedisAddr = l.Addr().String()
+	s := &Store{
+		log:     logrus.New(),
+		cfg:     cfg,
+		conn:    redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, MaxRetries: 0}),
+		retry:   Retry{Retries: cfg.StoreRetries, Backoff: Backoff{Base: cfg.StoreRetryBackoff}},
+		breaker: NewBreaker(cfg.StoreBreakerFailures, cfg.StoreBreakerCooldown),
+		records: NewRecords(GobCodec{}),
+	}
+	t.Cleanup(func() { s.conn.Close() })
+	return s, &dropped
+}
+
+func TestStore_RetriesAndBreaker(t *testing.T) {
+	cfg := &config.Config{
+		StoreTimeout:         time.Second,
+		StoreRetries:         2,
+		StoreRetryBackoff:    time.Millisecond,
+		StoreBreakerFailures: 2,
+		StoreBreakerCooldown: time.Hour,
 	}
+	s, dropped := newDroppingStore(t, cfg)
+
+	// Each operation is tried three times
+	_, err := s.Get(context.Background(), "k", &Item{})
+	assert.Error(t, err)
+	assert.NotEqual(t, app.DbErrorNotFound, errors.Cause(err))
+	assert.Equal(t, int32(3), atomic.LoadInt32(dropped))
+
+	// The second failed operation opens the breaker, and from then on the operations fail fast
+	err = s.Set(context.Background(), "k", Item{Name: "n"})
+	assert.Error(t, err)
+	assert.True(t, s.Breaker().Open())
+	assert.Equal(t, int32(6), atomic.LoadInt32(dropped))
+
+	err = s.Remove(context.Background(), "k")
+	assert.Equal(t, app.DbErrorUnavailable, errors.Cause(err))
+	assert.Equal(t, int32(6), atomic.LoadInt32(dropped))
+}
+
+func TestStore_BreakerTrialGivenUp(t *testing.T) {
+	cfg := &config.Config{StoreTimeout: time.Minute}
+	s := newStuckStore(t, cfg)
 
-	type args struct {
-		b    []byte
-		item *Item
+	now := time.Now()
+	s.breaker = NewBreaker(1, time.Minute)
+	s.breaker.now = func() time.Time { return now }
+	assert.NoError(t, s.breaker.Allow())
+	s.breaker.Done(true)
+
+	// The trial is let through after the cooldown, but its caller gives up before Redis answers
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.Get(ctx, "k", &Item{})
+	assert.Error(t, err)
+
+	// So it's not taken as a success, and the next call is a trial again
+	assert.True(t, s.Breaker().Open())
+	assert.NoError(t, s.breaker.Allow())
+}
+
+func TestStore_ConnectRetries(t *testing.T) {
+	cfg := &config.Config{
+		StoreTimeout:         time.Second,
+		RedisConnectAttempts: 3,
+		RedisConnectBackoff:  time.Millisecond,
+		StoreCodec:           config.StoreCodecGob,
 	}
-	tests := []struct {
-		name    string
-		args    args
-		want    Item
-		wantErr bool
-	}{
-		{
-			name:    "given a serialized item, when it's deserialized, then all works fine",
-			args:    args{b: bin, item: &Item{}},
-			want:    item,
-			wantErr: false,
-		},
+	_, dropped := newDroppingStore(t, cfg)
+
+	_, err := NewStore(cfg, logrus.New())
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(dropped))
+}
+
+// serveRedis serves a fake Redis, which answers each command with the reply of handle. The connection is dropped
+// when handle returns false. It returns the address of the server.
+func serveRedis(t *testing.T, handle func(command []string) (string, bool)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := s.FromGobToItem(tt.args.b, tt.args.item)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Storage.FromGobToItem() error = %v, wantErr %v", err, tt.wantErr)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
 				return
 			}
-			if !reflect.DeepEqual(*(got.(*Item)), tt.want) {
-				t.Errorf("Storage.FromGobToItem() = %v, want %v", got, tt.want)
-			}
-		})
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					var args int
+					_, err := fmt.Fscanf(r, "*%d\r\n", &args)
+					if err != nil {
+						return
+					}
+					command := make([]string, args)
+					for z := range command {
+						var size int
+						if _, err = fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+							return
+						}
+						b := make([]byte, size+2)
+						if _, err = io.ReadFull(r, b); err != nil {
+							return
+						}
+						command[z] = string(b[:size])
+					}
+					reply, ok := handle(command)
+					if !ok {
+						return
+					}
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// newFakeStore returns a store connected to the fake Redis at the address
+func newFakeStore(t *testing.T, cfg *config.Config, addr string) *Store {
+	s := &Store{
+		log:     logrus.New(),
+		cfg:     cfg,
+		conn:    redis.NewClient(&redis.Options{Addr: addr, ReadTimeout: time.Minute, MaxRetries: 0}),
+		retry:   Retry{Retries: cfg.StoreRetries, Backoff: Backoff{Base: cfg.StoreRetryBackoff}},
+		breaker: NewBreaker(cfg.StoreBreakerFailures, cfg.StoreBreakerCooldown),
+		records: NewRecords(GobCodec{}),
+	}
+	t.Cleanup(func() { s.conn.Close() })
+	return s
+}
+
+// newLosingStore returns a store connected to a server which finds no keys, and drops the connection on the
+// transactions, as if their reply was lost. It also returns the counter of the transactions it has received.
+func newLosingStore(t *testing.T, cfg *config.Config) (*Store, *int32) {
+	var transactions int32
+	addr := serveRedis(t, func(command []string) (string, bool) {
+		switch strings.ToUpper(command[0]) {
+		case "KEYS":
+			return "*0\r\n", true
+		case "MULTI":
+			atomic.AddInt32(&transactions, 1)
+			return "", false
+		default:
+			return "+OK\r\n", true
+		}
+	})
+	return newFakeStore(t, cfg, addr), &transactions
+}
+
+func TestStore_LoadReplyLost(t *testing.T) {
+	cfg := &config.Config{
+		StoreTimeout:      time.Second,
+		StoreRetries:      2,
+		StoreRetryBackoff: time.Millisecond,
 	}
+	s, transactions := newLosingStore(t, cfg)
+
+	// The load is not retried, as the lost transaction could have been applied
+	err := s.Load(context.Background(), []string{"item:*"}, map[string]interface{}{"item:1": Item{Name: "Bartolo", Age: 22}})
+	assert.Equal(t, app.DbErrorUnknownOutcome, errors.Cause(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(transactions))
+}
+
+func TestStore_AbandonedWrite(t *testing.T) {
+	// The server applies the sets late, as the commands waiting for a connection or in flight
+	var (
+		mutex sync.Mutex
+		keys  = map[string]bool{}
+	)
+	addr := serveRedis(t, func(command []string) (string, bool) {
+		switch strings.ToUpper(command[0]) {
+		case "SET":
+			time.Sleep(200 * time.Millisecond)
+			mutex.Lock()
+			keys[command[1]] = true
+			mutex.Unlock()
+			return "+OK\r\n", true
+		case "DEL":
+			mutex.Lock()
+			delete(keys, command[1])
+			mutex.Unlock()
+			return ":1\r\n", true
+		default:
+			return "+OK\r\n", true
+		}
+	})
+	s := newFakeStore(t, &config.Config{StoreTimeout: time.Minute}, addr)
+
+	// The caller gives up on the set, and rolls it back
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Set(ctx, "hosting:1", Item{Name: "Bartolo"})
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	assert.NoError(t, s.Remove(context.Background(), "hosting:1"))
+
+	// The set is not left to land after its rollback
+	time.Sleep(300 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Empty(t, keys)
 }
@@ -0,0 +1,226 @@
+// Package storetest is the contract of the stores of the repositories. The repositories rely on it,
+// so every store must pass it: the Redis one, the memory one and the file one.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/config"
+)
+
+type (
+	// Store is the part of a store which the repositories use
+	Store interface {
+		Get(ctx context.Context, key string, item interface{}) (interface{}, error)
+		GetAll(ctx context.Context, pattern string, emptyRecordFunc config.EmptyRecordFunc) ([]interface{}, error)
+		Set(ctx context.Context, key string, item interface{}) error
+		Remove(ctx context.Context, key string) error
+		Keys(ctx context.Context, pattern string) ([]string, error)
+		Load(ctx context.Context, patterns []string, items map[string]interface{}) error
+	}
+
+	// Item is the record stored by the contract
+	Item struct {
+		Name string
+		Age  int
+	}
+)
+
+func emptyItem() interface{} {
+	return &Item{}
+}
+
+// Run runs the contract against the stores returned by newStore. Each test gets its own store, which must be empty.
+func Run(t *testing.T, newStore func(t *testing.T) Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Store)
+	}{
+		{name: "given a stored item, when it's got, then a copy of it is returned", test: getSet},
+		{name: "given a missing key, when it's got or removed, then it's not found but removed anyway", test: notFound},
+		{name: "given several keys, when they're got by a pattern, then the matching ones are returned sorted", test: patterns},
+		{name: "given an item which can't be encoded, when it's set, then it's refused and not stored", test: encodingError},
+		{name: "given a batch of items, when it's loaded, then it's written whole and only if no key matches its patterns", test: load},
+		{name: "given concurrent callers, when they change and read the store, then every change is kept", test: concurrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func getSet(t *testing.T, s Store) {
+	ctx := context.Background()
+	require.NoError(t, s.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 22}))
+
+	got, err := s.Get(ctx, "item:1", &Item{})
+	require.NoError(t, err)
+	assert.Equal(t, &Item{Name: "Bartolo", Age: 22}, got)
+
+	// Changing the item got doesn't change the stored one
+	got.(*Item).Age = 0
+	got, err = s.Get(ctx, "item:1", &Item{})
+	require.NoError(t, err)
+	assert.Equal(t, &Item{Name: "Bartolo", Age: 22}, got)
+
+	// Setting a key again replaces its item
+	require.NoError(t, s.Set(ctx, "item:1", Item{Name: "Bartolo", Age: 23}))
+	got, err = s.Get(ctx, "item:1", &Item{})
+	require.NoError(t, err)
+	assert.Equal(t, &Item{Name: "Bartolo", Age: 23}, got)
+}
+
+func notFound(t *testing.T, s Store) {
+	ctx := context.Background()
+	_, err := s.Get(ctx, "item:1", &Item{})
+	assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+	assert.NoError(t, s.Remove(ctx, "item:1"))
+
+	require.NoError(t, s.Set(ctx, "item:1", Item{Name: "Bartolo"}))
+	require.NoError(t, s.Remove(ctx, "item:1"))
+	_, err = s.Get(ctx, "item:1", &Item{})
+	assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+	assert.NoError(t, s.Remove(ctx, "item:1"))
+
+	items, err := s.GetAll(ctx, "item:*", emptyItem)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	keys, err := s.Keys(ctx, "item:*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func patterns(t *testing.T, s Store) {
+	ctx := context.Background()
+	for _, key := range []string{"item:2", "item:10", "item:1", "item:a/b", "item-name:a", "other:1"} {
+		require.NoError(t, s.Set(ctx, key, Item{Name: key}))
+	}
+
+	for _, tt := range []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "item:*", want: []string{"item:1", "item:10", "item:2", "item:a/b"}},
+		{pattern: "item:?", want: []string{"item:1", "item:2"}},
+		{pattern: "item:[12]*", want: []string{"item:1", "item:10", "item:2"}},
+		{pattern: "item:[^1]", want: []string{"item:2"}},
+		{pattern: "*:a*", want: []string{"item-name:a", "item:a/b"}},
+		{pattern: "*", want: []string{"item-name:a", "item:1", "item:10", "item:2", "item:a/b", "other:1"}},
+	} {
+		keys, err := s.Keys(ctx, tt.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, keys, tt.pattern)
+
+		items, err := s.GetAll(ctx, tt.pattern, emptyItem)
+		require.NoError(t, err)
+		names := make([]string, 0, len(items))
+		for _, item := range items {
+			names = append(names, item.(*Item).Name)
+		}
+		assert.Equal(t, tt.want, names, tt.pattern)
+	}
+
+	// An empty pattern gets all the items
+	items, err := s.GetAll(ctx, "", emptyItem)
+	require.NoError(t, err)
+	assert.Len(t, items, 6)
+}
+
+func encodingError(t *testing.T, s Store) {
+	ctx := context.Background()
+	assert.Error(t, s.Set(ctx, "item:1", func() {}))
+
+	_, err := s.Get(ctx, "item:1", &Item{})
+	assert.Equal(t, app.DbErrorNotFound, errors.Cause(err))
+}
+
+func load(t *testing.T, s Store) {
+	ctx := context.Background()
+	patterns := []string{"item:*", "other"}
+
+	// A batch with an item which can't be encoded writes nothing
+	assert.Error(t, s.Load(ctx, patterns, map[string]interface{}{"item:1": Item{Name: "Bartolo"}, "item:2": func() {}}))
+	keys, err := s.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// The keys which don't match the patterns don't stop the batch
+	require.NoError(t, s.Set(ctx, "unrelated", Item{Name: "Juan"}))
+	batch := map[string]interface{}{
+		"item:1": Item{Name: "Bartolo", Age: 22},
+		"item:2": Item{Name: "Maria", Age: 33},
+		"other":  "1",
+	}
+	require.NoError(t, s.Load(ctx, patterns, batch))
+	items, err := s.GetAll(ctx, "item:*", emptyItem)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{&Item{Name: "Bartolo", Age: 22}, &Item{Name: "Maria", Age: 33}}, items)
+
+	// Once any key matches the patterns, nothing else is loaded
+	err = s.Load(ctx, patterns, map[string]interface{}{"item:3": Item{Name: "Juan", Age: 44}})
+	assert.Equal(t, app.DbErrorInUse, errors.Cause(err))
+	keys, err = s.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item:1", "item:2", "other", "unrelated"}, keys)
+}
+
+// concurrent runs several callers at once, each one on its own keys and all of them on a shared one,
+// while others read all the keys. Run along with the race detector, it finds the unsynchronized stores.
+func concurrent(t *testing.T, s Store) {
+	const callers, changes = 8, 20
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*callers*changes)
+	for c := 0; c < callers; c++ {
+		wg.Add(2)
+		go func(c int) {
+			defer wg.Done()
+			for z := 0; z < changes; z++ {
+				key := fmt.Sprintf("item:%d:%d", c, z)
+				errs <- s.Set(ctx, key, Item{Name: key, Age: z})
+				errs <- s.Set(ctx, "shared", Item{Name: key, Age: z})
+				if z%2 == 1 {
+					errs <- s.Remove(ctx, key)
+				}
+			}
+		}(c)
+		go func() {
+			defer wg.Done()
+			for z := 0; z < changes; z++ {
+				_, err := s.GetAll(ctx, "item:*", emptyItem)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// The odd items were removed by their callers
+	keys, err := s.Keys(ctx, "item:*")
+	require.NoError(t, err)
+	assert.Len(t, keys, callers*changes/2)
+	for _, key := range keys {
+		got, err := s.Get(ctx, key, &Item{})
+		require.NoError(t, err)
+		assert.Equal(t, key, got.(*Item).Name)
+		assert.Equal(t, 0, got.(*Item).Age%2)
+	}
+
+	// The shared item is the last one set, whole
+	got, err := s.Get(ctx, "shared", &Item{})
+	require.NoError(t, err)
+	assert.Equal(t, changes-1, got.(*Item).Age)
+}
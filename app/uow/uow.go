@@ -0,0 +1,103 @@
+// Package uow implements a unit of work over changes which can't be applied atomically,
+// like the server resources reservation and the writes to the store.
+// Each change is staged along with the action which undoes it. If any change fails,
+// the ones already done are undone in reverse order, so the work is applied as one or not at all.
+package uow
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Action is a change, or the undoing of a change, of the unit of work
+	Action func(ctx context.Context) error
+
+	UnitOfWork struct {
+		ctx       context.Context
+		undos     []Action
+		committed bool
+	}
+
+	// detached keeps the values of its parent context, as the request ID, but not its deadline nor its cancellation
+	detached struct {
+		parent context.Context
+	}
+)
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+// New starts a unit of work. The undos are run out of the cancellation of the context,
+// so a request which times out doesn't leave its work half done.
+func New(ctx context.Context) *UnitOfWork {
+	return &UnitOfWork{ctx: detached{parent: ctx}}
+}
+
+// Do applies the change and stages its undo. Nothing is staged if the change fails,
+// as it's expected to fail without side effects.
+func (u *UnitOfWork) Do(ctx context.Context, change, undo Action) error {
+	if u.committed {
+		return errors.New("the unit of work is already committed")
+	}
+	err := change(ctx)
+	if err != nil {
+		return err
+	}
+	if undo != nil {
+		u.undos = append(u.undos, undo)
+	}
+	return nil
+}
+
+// Try applies the change and stages its undo, even if the change fails. It's meant for
+// the changes which could have been applied despite failing, like a write whose reply is lost,
+// so the undo must be harmless when the change wasn't applied.
+func (u *UnitOfWork) Try(ctx context.Context, change, undo Action) error {
+	if u.committed {
+		return errors.New("the unit of work is already committed")
+	}
+	if undo != nil {
+		u.undos = append(u.undos, undo)
+	}
+	return change(ctx)
+}
+
+// Commit makes the done changes permanent, so they are no longer undone by Rollback
+func (u *UnitOfWork) Commit() {
+	u.committed = true
+	u.undos = nil
+}
+
+// Rollback undoes the done changes in reverse order. All the undos are tried,
+// and their failures are returned together. It does nothing once committed.
+func (u *UnitOfWork) Rollback() error {
+	var failures []string
+	for i := len(u.undos) - 1; i >= 0; i-- {
+		err := u.undos[i](u.ctx)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	u.undos = nil
+
+	if len(failures) > 0 {
+		return errors.Errorf("rollback: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Abort rolls back the unit of work because of err, and returns it. The cause of err is kept,
+// so the caller can still tell why the work failed, even if the rollback fails too.
+func (u *UnitOfWork) Abort(err error) error {
+	rerr := u.Rollback()
+	if rerr != nil {
+		return errors.Wrap(err, rerr.Error())
+	}
+	return err
+}
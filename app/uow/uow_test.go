@@ -0,0 +1,127 @@
+package uow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	errChange := errors.New("change failed")
+	errUndo := errors.New("undo failed")
+
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(err error) Action {
+		return func(ctx context.Context) error { return err }
+	}
+
+	tests := []struct {
+		name      string
+		changes   []Action
+		undos     []Action
+		commit    bool
+		wantErr   bool
+		wantUndos []int
+	}{
+		{
+			name:      "given a work, when all the changes are done and it's committed, then nothing is undone",
+			changes:   []Action{ok, ok},
+			undos:     []Action{ok, ok},
+			commit:    true,
+			wantUndos: nil,
+		},
+		{
+			name:      "given a work, when a change fails, then the done ones are undone in reverse order",
+			changes:   []Action{ok, ok, fail(errChange)},
+			undos:     []Action{ok, ok, ok},
+			wantErr:   true,
+			wantUndos: []int{1, 0},
+		},
+		{
+			name:      "given a work, when an undo fails, then the rest are undone anyway",
+			changes:   []Action{ok, ok, fail(errChange)},
+			undos:     []Action{ok, fail(errUndo), ok},
+			wantErr:   true,
+			wantUndos: []int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var undone []int
+			work := New(context.Background())
+
+			var err error
+			for z := range tt.changes {
+				z := z
+				undo := func(ctx context.Context) error {
+					undone = append(undone, z)
+					return tt.undos[z](ctx)
+				}
+				err = work.Do(context.Background(), tt.changes[z], undo)
+				if err != nil {
+					err = work.Abort(err)
+					break
+				}
+			}
+			if tt.commit {
+				work.Commit()
+				assert.NoError(t, work.Rollback())
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnitOfWork error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				// The cause of the failure is kept
+				assert.Equal(t, errChange, errors.Cause(err))
+			}
+			assert.Equal(t, tt.wantUndos, undone)
+		})
+	}
+}
+
+func TestUnitOfWork_Try(t *testing.T) {
+	errChange := errors.New("change failed")
+	work := New(context.Background())
+
+	undone := 0
+	undo := func(ctx context.Context) error {
+		undone++
+		return nil
+	}
+
+	// A failed change which could have been applied is undone too
+	err := work.Try(context.Background(), func(ctx context.Context) error { return errChange }, undo)
+	assert.Equal(t, errChange, err)
+	assert.Equal(t, errChange, errors.Cause(work.Abort(err)))
+	assert.Equal(t, 1, undone)
+}
+
+func TestUnitOfWork_RollbackOutOfCancellation(t *testing.T) {
+	type key struct{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "request1"), time.Hour)
+	work := New(ctx)
+
+	var (
+		undoErr     error
+		hasDeadline bool
+		value       interface{}
+	)
+	err := work.Do(ctx, func(ctx context.Context) error { return nil }, func(ctx context.Context) error {
+		undoErr = ctx.Err()
+		_, hasDeadline = ctx.Deadline()
+		value = ctx.Value(key{})
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// The request is gone, but its work is still undone, with the values of its context
+	cancel()
+	assert.NoError(t, work.Rollback())
+	assert.NoError(t, undoErr)
+	assert.False(t, hasDeadline)
+	assert.Equal(t, "request1", value)
+}
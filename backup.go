@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/backup"
+	"github.com/theskyinflames/cdmon2/app/service"
+)
+
+// backupStore writes a snapshot of the store. It's written to a temporary file first,
+// so a failed backup doesn't replace the previous one.
+func backupStore(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	output := flags.String("o", "", "file of the snapshot, the standard output if not given")
+	err := flags.Parse(args)
+	if err != nil {
+		return usageError{err}
+	}
+	err = noArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	log := newLogger(cfg, os.Stderr)
+	repos, err := openRepositories(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repos.store.Close()
+
+	ctx := context.Background()
+	serverDomain, _, err := loadServer(ctx, cfg, log, repos)
+	if err != nil {
+		return err
+	}
+	snapshot, err := service.NewBackup(repos.hostings, repos.customers, repos.projects, repos.apiKeys, repos.restore, serverDomain, cfg, log).Backup(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(*output) == 0 {
+		return snapshot.Write(stdout)
+	}
+	err = writeFile(*output, snapshot.Write)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backup of %d hostings written to %s\n", len(snapshot.Data.Hostings), *output)
+	return nil
+}
+
+// restoreStore loads a snapshot file, or the standard input if it's "-", into an empty store
+func restoreStore(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageError{errors.New("the snapshot file is required, or - for the standard input")}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	snapshot, err := backup.Read(in)
+	if err != nil {
+		return err
+	}
+
+	log := newLogger(cfg, os.Stderr)
+	repos, err := openRepositories(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repos.store.Close()
+
+	// The snapshot is validated against a server of the current capacity, so the server state is not needed
+	err = service.NewBackup(repos.hostings, repos.customers, repos.projects, repos.apiKeys, repos.restore, nil, cfg, log).Restore(context.Background(), snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "restored %d hostings, %d customers, %d projects and %d api keys of the snapshot taken at %s\n",
+		len(snapshot.Data.Hostings), len(snapshot.Data.Customers), len(snapshot.Data.Projects), len(snapshot.Data.APIKeys), snapshot.CreatedAt)
+	return nil
+}
+
+// writeFile writes a temporary file next to the given one, and renames it once written and synced
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
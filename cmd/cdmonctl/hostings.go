@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/client"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func list(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	_, err := parseArgs(flagSet("list"), args, 0)
+	if err != nil {
+		return err
+	}
+
+	rs, err := c.GetHostings(ctx)
+	if err != nil {
+		return err
+	}
+	hostings := rs.Hostings
+	if hostings == nil {
+		hostings = []domain.Hosting{}
+	}
+
+	return p.print(hostings, func(w io.Writer) {
+		fmt.Fprintln(w, "UUID\tNAME\tCORES\tMEMORY MB\tDISK MB\tOWNER\tPROJECT")
+		for _, h := range hostings {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", h.UUID, h.Name, h.Cores, h.MemoryMb, h.DiskMb, orNone(h.Owner), orNone(h.Project))
+		}
+	})
+}
+
+func get(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	refs, err := parseArgs(flagSet("get"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	hosting, err := find(ctx, c, refs[0])
+	if err != nil {
+		return err
+	}
+	return p.print(hosting, hostingTable(hosting))
+}
+
+func create(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	var rq api.CreateHostingRq
+	flags := flagSet("create")
+	flags.StringVar(&rq.Name, "name", "", "name of the hosting")
+	flags.IntVar(&rq.Cores, "cores", 0, "number of cores")
+	flags.IntVar(&rq.MemoryMb, "memory", 0, "memory size in mb")
+	flags.IntVar(&rq.DiskMb, "disk", 0, "disk size in mb")
+	owner := flags.String("owner", "", "UUID of the owner customer")
+	project := flags.String("project", "", "UUID of the project")
+	_, err := parseArgs(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"name", "cores", "memory", "disk"} {
+		if !visited(flags)[name] {
+			return usageError{errors.Errorf("-%s is required", name)}
+		}
+	}
+	rq.Owner, rq.Project = domain.UUID(*owner), domain.UUID(*project)
+
+	rs, err := c.CreateHosting(ctx, rq)
+	if err != nil {
+		return err
+	}
+	return p.print(rs, func(w io.Writer) {
+		fmt.Fprintf(w, "created hosting %s\n", rs.UUID)
+	})
+}
+
+// resize changes the resources of the hosting. The ones not given are kept.
+func resize(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	flags := flagSet("resize")
+	cores := flags.Int("cores", 0, "number of cores")
+	memory := flags.Int("memory", 0, "memory size in mb")
+	disk := flags.Int("disk", 0, "disk size in mb")
+	refs, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	given := visited(flags)
+	if len(given) == 0 {
+		return usageError{errors.New("at least one of -cores, -memory or -disk is required")}
+	}
+
+	hosting, err := find(ctx, c, refs[0])
+	if err != nil {
+		return err
+	}
+	if given["cores"] {
+		hosting.Cores = *cores
+	}
+	if given["memory"] {
+		hosting.MemoryMb = *memory
+	}
+	if given["disk"] {
+		hosting.DiskMb = *disk
+	}
+
+	_, err = c.UpdateHosting(ctx, api.UpdateHostingRq{Hosting: hosting})
+	if err != nil {
+		return err
+	}
+	return p.print(hosting, hostingTable(hosting))
+}
+
+func remove(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	refs, err := parseArgs(flagSet("delete"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	hosting, err := find(ctx, c, refs[0])
+	if err != nil {
+		return err
+	}
+	rs, err := c.RemoveHosting(ctx, hosting.UUID)
+	if err != nil {
+		return err
+	}
+	return p.print(rs, func(w io.Writer) {
+		fmt.Fprintf(w, "removed hosting %s\n", rs.UUID)
+	})
+}
+
+func capacity(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	_, err := parseArgs(flagSet("capacity"), args, 0)
+	if err != nil {
+		return err
+	}
+
+	rs, err := c.Health(ctx)
+	if err != nil {
+		return err
+	}
+	s := rs.ServerStatus
+	return p.print(rs, func(w io.Writer) {
+		fmt.Fprintln(w, "RESOURCE\tTOTAL\tAVAILABLE\tUTILIZATION")
+		fmt.Fprintf(w, "cores\t%d\t%d\t%.1f%%\n", s.TotalCores, s.AvailableCores, s.Utilization.Cores)
+		fmt.Fprintf(w, "memory mb\t%d\t%d\t%.1f%%\n", s.TotalSizeOfMemoryMb, s.AvailableSizeOfMemoryMb, s.Utilization.MemoryMb)
+		fmt.Fprintf(w, "disk mb\t%d\t%d\t%.1f%%\n", s.TotalSizeOfDiskMb, s.AvailableSizeOfDiskMb, s.Utilization.DiskMb)
+	})
+}
+
+// find returns the hosting given by its UUID or by its name, which is unique too
+func find(ctx context.Context, c *client.Client, ref string) (domain.Hosting, error) {
+	rs, err := c.GetHostings(ctx)
+	if err != nil {
+		return domain.Hosting{}, err
+	}
+	for _, h := range rs.Hostings {
+		if string(h.UUID) == ref || h.Name == ref {
+			return h, nil
+		}
+	}
+	return domain.Hosting{}, errors.Wrapf(client.ErrNotFound, "hosting %s", ref)
+}
+
+func hostingTable(h domain.Hosting) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintf(w, "UUID\t%s\n", h.UUID)
+		fmt.Fprintf(w, "NAME\t%s\n", h.Name)
+		fmt.Fprintf(w, "CORES\t%d\n", h.Cores)
+		fmt.Fprintf(w, "MEMORY MB\t%d\n", h.MemoryMb)
+		fmt.Fprintf(w, "DISK MB\t%d\n", h.DiskMb)
+		fmt.Fprintf(w, "OWNER\t%s\n", orNone(h.Owner))
+		fmt.Fprintf(w, "PROJECT\t%s\n", orNone(h.Project))
+	}
+}
+
+func orNone(uuid domain.UUID) string {
+	if len(uuid) == 0 {
+		return "-"
+	}
+	return string(uuid)
+}
@@ -0,0 +1,166 @@
+// cdmonctl is the command line tool of the operators, to manage the hostings and check the server
+// capacity through the hosting API. It's built on the app/client package.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/client"
+)
+
+const (
+	// The API address and the credentials are taken from these variables, unless they're given as flags
+	EnvAddr   = "CDMONCTL_ADDR"
+	EnvAPIKey = "CDMONCTL_API_KEY"
+	EnvToken  = "CDMONCTL_TOKEN"
+
+	defaultAddr = "http://localhost:8080"
+
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type (
+	// command is a subcommand of the tool, which talks to the API through the client,
+	// and writes its result with the printer
+	command struct {
+		name    string
+		usage   string
+		summary string
+		run     func(ctx context.Context, c *client.Client, p *printer, args []string) error
+	}
+
+	// usageError is a command called with the wrong arguments
+	usageError struct {
+		error
+	}
+)
+
+var commands = []command{
+	{name: "list", usage: "list", summary: "list the hostings", run: list},
+	{name: "get", usage: "get <hosting>", summary: "show a hosting, given its UUID or name", run: get},
+	{name: "create", usage: "create -name <name> -cores <n> -memory <mb> -disk <mb> [-owner <uuid>] [-project <uuid>]", summary: "create a hosting", run: create},
+	{name: "resize", usage: "resize <hosting> [-cores <n>] [-memory <mb>] [-disk <mb>]", summary: "change the resources of a hosting", run: resize},
+	{name: "delete", usage: "delete <hosting>", summary: "remove a hosting", run: remove},
+	{name: "capacity", usage: "capacity", summary: "show the server resources and their availability", run: capacity},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command given by the arguments, and returns the exit status:
+// 1 if the command fails, and 2 if it's misused
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cdmonctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", envDefault(EnvAddr, defaultAddr), "address of the hosting API, or "+EnvAddr)
+	apiKey := flags.String("api-key", os.Getenv(EnvAPIKey), "API key, or "+EnvAPIKey)
+	token := flags.String("token", os.Getenv(EnvToken), "JWT, or "+EnvToken)
+	output := flags.String("o", outputTable, "output format, table or json")
+	flags.Usage = func() { usage(stderr, flags) }
+
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(stderr, "cdmonctl: unknown output format %q, it must be table or json\n", *output)
+		return 2
+	}
+
+	cmd, ok := findCommand(flags.Arg(0))
+	if !ok {
+		if flags.NArg() > 0 {
+			fmt.Fprintf(stderr, "cdmonctl: unknown command %q\n", flags.Arg(0))
+		}
+		usage(stderr, flags)
+		return 2
+	}
+
+	c := client.New(*addr, client.Credentials{APIKey: *apiKey, Token: *token})
+	p := &printer{w: stdout, json: *output == outputJSON}
+	err = cmd.run(context.Background(), c, p, flags.Args()[1:])
+	switch err.(type) {
+	case nil:
+		return 0
+	case usageError:
+		fmt.Fprintf(stderr, "cdmonctl %s: %s\nusage: cdmonctl %s\n", cmd.name, err.Error(), cmd.usage)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "cdmonctl %s: %s\n", cmd.name, err.Error())
+		return 1
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: cdmonctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+func envDefault(key, value string) string {
+	if v, ok := os.LookupEnv(key); ok && len(v) > 0 {
+		return v
+	}
+	return value
+}
+
+// parseArgs parses the flags of a command, wherever they're given among its positional arguments,
+// and returns the positional ones, which must be want
+func parseArgs(flags *flag.FlagSet, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, usageError{err}
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != want {
+		return nil, usageError{errors.Errorf("wrong number of arguments, %d expected and %d given", want, len(positional))}
+	}
+	return positional, nil
+}
+
+// flagSet returns the flags of a command. Their errors are reported along with the command usage.
+func flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	return flags
+}
+
+// visited returns the names of the flags given
+func visited(flags *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/api/apitest"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+// cdmonctl runs the tool with the arguments, and returns its exit status and output
+func cdmonctl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCdmonctl(t *testing.T) {
+	s := apitest.New(t, 10, 100, 100)
+	t.Setenv(EnvAddr, s.URL)
+	t.Setenv(EnvAPIKey, apitest.AdminKey)
+
+	status, out, errOut := cdmonctl("create", "-name", "web", "-cores", "2", "-memory", "10", "-disk", "20")
+	require.Equal(t, 0, status, errOut)
+	assert.True(t, strings.HasPrefix(out, "created hosting "))
+
+	// The hostings are listed as a table, or as JSON
+	status, out, _ = cdmonctl("list")
+	assert.Equal(t, 0, status)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"UUID", "NAME", "CORES", "MEMORY", "MB", "DISK", "MB", "OWNER", "PROJECT"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"web", "2", "10", "20", "-", "-"}, strings.Fields(lines[1])[1:])
+
+	status, out, _ = cdmonctl("-o", "json", "list")
+	assert.Equal(t, 0, status)
+	var hostings []domain.Hosting
+	require.NoError(t, json.Unmarshal([]byte(out), &hostings))
+	require.Len(t, hostings, 1)
+	uuid := string(hostings[0].UUID)
+
+	// A hosting is given by its name or its UUID, and only the given resources are changed
+	status, out, errOut = cdmonctl("-o", "json", "resize", "web", "-cores", "4")
+	require.Equal(t, 0, status, errOut)
+	var resized domain.Hosting
+	require.NoError(t, json.Unmarshal([]byte(out), &resized))
+	assert.Equal(t, domain.Hosting{UUID: domain.UUID(uuid), Name: "web", Cores: 4, MemoryMb: 10, DiskMb: 20}, resized)
+
+	status, out, _ = cdmonctl("get", uuid)
+	assert.Equal(t, 0, status)
+	assert.Contains(t, out, "CORES      4\n")
+
+	status, out, _ = cdmonctl("capacity")
+	assert.Equal(t, 0, status)
+	assert.Contains(t, out, "cores      10     6          40.0%\n")
+
+	// The errors of the API are reported
+	status, _, errOut = cdmonctl("resize", "web", "-cores", "11")
+	assert.Equal(t, 1, status)
+	assert.Contains(t, errOut, "insufficient capacity")
+
+	status, _, errOut = cdmonctl("-api-key", "wrong", "list")
+	assert.Equal(t, 1, status)
+	assert.Contains(t, errOut, "unauthenticated")
+
+	status, out, _ = cdmonctl("delete", "web")
+	assert.Equal(t, 0, status)
+	assert.Equal(t, "removed hosting "+uuid+"\n", out)
+
+	status, _, errOut = cdmonctl("get", "web")
+	assert.Equal(t, 1, status)
+	assert.Contains(t, errOut, "hosting web: not found")
+}
+
+func TestCdmonctl_Usage(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{
+			name:       "given no command, when it's run, then the usage is shown",
+			wantStderr: "usage: cdmonctl",
+		},
+		{
+			name:       "given an unknown command, when it's run, then it's reported",
+			args:       []string{"scale"},
+			wantStderr: `unknown command "scale"`,
+		},
+		{
+			name:       "given an unknown output format, when it's run, then it's reported",
+			args:       []string{"-o", "yaml", "list"},
+			wantStderr: `unknown output format "yaml"`,
+		},
+		{
+			name:       "given a create without the required flags, when it's run, then the command usage is shown",
+			args:       []string{"create", "-name", "web"},
+			wantStderr: "-cores is required\nusage: cdmonctl create",
+		},
+		{
+			name:       "given a resize without resources, when it's run, then it's reported",
+			args:       []string{"resize", "web"},
+			wantStderr: "at least one of -cores, -memory or -disk is required",
+		},
+		{
+			name:       "given a get without hosting, when it's run, then it's reported",
+			args:       []string{"get"},
+			wantStderr: "wrong number of arguments, 1 expected and 0 given",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, errOut := cdmonctl(tt.args...)
+			assert.Equal(t, 2, status)
+			assert.Contains(t, errOut, tt.wantStderr)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"text/tabwriter"
+)
+
+// printer writes the result of a command, as aligned columns or as JSON
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// print writes v as JSON, or writes the table, whose cells are separated by tabs
+func (p *printer) print(v interface{}, table func(w io.Writer)) error {
+	if p.json {
+		e := json.NewEncoder(p.w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
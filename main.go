@@ -1,59 +1,237 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
-	"github.com/theskyinflames/cdmon2/app/store"
-
-	"github.com/davecgh/go-spew/spew"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
-	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app"
 	"github.com/theskyinflames/cdmon2/app/config"
 	"github.com/theskyinflames/cdmon2/app/domain"
 	"github.com/theskyinflames/cdmon2/app/repository"
 	"github.com/theskyinflames/cdmon2/app/service"
+	"github.com/theskyinflames/cdmon2/app/store"
 )
 
+type (
+	// command is a subcommand of the binary, like "config validate". All of them load the configuration
+	// the same way, so a maintenance task runs against the same store the service uses.
+	command struct {
+		name    string
+		summary string
+		run     func(args []string, stdout io.Writer) error
+	}
+
+	// configError is a configuration which could not be loaded or is not valid
+	configError struct {
+		error
+	}
+
+	// usageError is a command called with the wrong arguments
+	usageError struct {
+		error
+	}
+
+	// storeBackend is the store of the service, Redis or a local file, as the configuration says
+	storeBackend interface {
+		repository.Store
+		repository.Migrator
+		Ping() error
+		Flush() error
+		repository.Loader
+	}
+
+	// repositories are the repositories over the store of the service, as the maintenance commands use them
+	repositories struct {
+		store     storeBackend
+		hostings  *repository.HostingRepostitoryMap
+		customers *repository.CustomerRepositoryMap
+		projects  *repository.ProjectRepositoryMap
+		apiKeys   *repository.APIKeyRepositoryMap
+		restore   *repository.RestoreRepositoryMap
+	}
+)
+
+var commands = []command{
+	{name: "serve", summary: "serve the hosting API, the default command", run: serve},
+	{name: "config validate", summary: "load the configuration and report all its problems", run: validateConfig},
+	{name: "reconcile", summary: "check the stored hostings against the server and their indexes, -repair to fix them", run: reconcile},
+	{name: "backup", summary: "write a snapshot of the stored data, to the -o file or the standard output", run: backupStore},
+	{name: "restore", summary: "load a snapshot file into an empty store", run: restoreStore},
+	{name: "migrate", summary: "rewrite the stored records with the configured codec and their current schema", run: migrate},
+}
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	// Set logging service
-	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetOutput(os.Stdout)
-	logrus.SetLevel(logrus.InfoLevel)
-	logrus.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-		ForceColors:   true,
-	})
-	log := logrus.New()
+// run runs the command given by the arguments, and returns the exit status:
+// 1 if the command fails, and 2 if it's misused or the configuration is not valid
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		usage(stdout)
+		return 0
+	}
+
+	cmd, args, ok := findCommand(args)
+	if !ok {
+		fmt.Fprintf(stderr, "cdmon2: unknown command %q\n", strings.Join(args, " "))
+		usage(stderr)
+		return 2
+	}
+
+	err := cmd.run(args, stdout)
+	switch err := err.(type) {
+	case nil:
+		return 0
+	case configError:
+		reportConfigError(stderr, err.error)
+		return 2
+	case usageError:
+		fmt.Fprintf(stderr, "cdmon2 %s: %s\n", cmd.name, err.Error())
+		return 2
+	default:
+		fmt.Fprintf(stderr, "cdmon2 %s: %s\n", cmd.name, err.Error())
+		return 1
+	}
+}
+
+// findCommand returns the command named by the leading arguments, and the rest of them.
+// Without arguments, the service is served.
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) == 0 {
+		return commands[0], nil, true
+	}
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, args, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cdmon2 [command] [arguments]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nThe configuration is taken from the environment and the file set by "+config.ConfigFile+".")
+}
 
-	// Sever config loading
+// loadConfig loads and validates the configuration, as every command does before touching the store
+func loadConfig() (*config.Config, error) {
 	cfg := &config.Config{}
-	cfg.Load()
-	log.Info(spew.Sdump(cfg))
+	err := cfg.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return nil, configError{err}
+	}
+	return cfg, nil
+}
 
-	// Init the hostings server domain
-	serverDomain, err := domain.NewServer(cfg)
+// newLogger returns the logger set by the configuration, whose log level has already been validated
+func newLogger(cfg *config.Config, out io.Writer) *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(out)
+	level, _ := logrus.ParseLevel(cfg.LogLevel)
+	log.SetLevel(level)
+	if cfg.LogFormat == config.LogFormatText {
+		log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	} else {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	}
+	return log
+}
+
+// openStore connects to the store of the configured backend. The caller closes it.
+func openStore(cfg *config.Config, log *logrus.Logger) (storeBackend, error) {
+	if cfg.StoreBackend == config.StoreBackendFile {
+		s, err := store.NewFileStore(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	s, err := store.NewStore(cfg, log)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return s, nil
+}
 
-	// Init the hostings repository
-	store, err := store.NewStore(cfg, log)
+// openRepositories connects to the store of the service. The caller closes it.
+func openRepositories(cfg *config.Config, log *logrus.Logger) (*repositories, error) {
+	s, err := openStore(cfg, log)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	store.Flush() // Empty for each execution.
-	defer store.Close()
-	hostingsRepository := repository.NewHostingReposytoryMap(cfg, store)
+	return &repositories{
+		store:     s,
+		hostings:  repository.NewHostingReposytoryMap(cfg, s),
+		customers: repository.NewCustomerRepositoryMap(cfg, s),
+		projects:  repository.NewProjectRepositoryMap(cfg, s),
+		apiKeys:   repository.NewAPIKeyRepositoryMap(cfg, s),
+		restore:   repository.NewRestoreRepositoryMap(cfg, s),
+	}, nil
+}
 
-	// Init the hostings server service
-	service := service.NewServer(hostingsRepository, serverDomain, cfg, log)
+// loadServer builds the server from the stored hostings, as the service does at start up.
+// The hostings which don't fit in it are tolerated, as the commands report them.
+func loadServer(ctx context.Context, cfg *config.Config, log *logrus.Logger, repos *repositories) (*domain.Server, *service.ServerService, error) {
+	serverDomain, err := domain.NewServer(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverService := service.NewServer(repos.hostings, repos.customers, repos.projects, serverDomain, cfg, log)
+	err = serverService.LoadServer(ctx)
+	if err != nil && errors.Cause(err) != app.DomainErrorQuotaExceeded {
+		return nil, nil, err
+	}
+	return serverDomain, serverService, nil
+}
+
+// reportConfigError reports the configuration problems, one by line
+func reportConfigError(w io.Writer, err error) {
+	fmt.Fprintln(w, "cdmon2: the configuration is not valid")
+	if problems, ok := err.(config.Problems); ok {
+		for _, p := range problems {
+			fmt.Fprintf(w, "  - %s\n", p)
+		}
+	} else {
+		fmt.Fprintf(w, "  - %s\n", err.Error())
+	}
+}
 
-	// Init the controller
-	controller := api.NewController(service, log)
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return usageError{errors.Errorf("unexpected arguments %q", strings.Join(args, " "))}
+	}
+	return nil
+}
 
-	// Start the API
-	api := api.NewApi(controller, log, cfg)
-	api.Start()
+// validateConfig checks the configuration without starting the service, as before deploying a change
+func validateConfig(args []string, stdout io.Writer) error {
+	err := noArgs(args)
+	if err != nil {
+		return err
+	}
+
+	_, err = loadConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "the configuration is valid")
+	return nil
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theskyinflames/cdmon2/app/backup"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+func TestRun(t *testing.T) {
+	validConfig := `{
+		"total_number_of_cores": 8,
+		"total_size_of_memory": 2048,
+		"total_size_of_disk": 4096,
+		"redis_addr": "localhost:6379"
+	}`
+
+	tests := []struct {
+		name       string
+		args       []string
+		file       string
+		stdin      string
+		wantStatus int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "given the help command, when it's run, then the commands are listed",
+			args:       []string{"help"},
+			wantStatus: 0,
+			wantStdout: "config validate",
+		},
+		{
+			name:       "given an unknown command, when it's run, then it's reported along with the usage",
+			args:       []string{"config", "print"},
+			wantStatus: 2,
+			wantStderr: `unknown command "config print"`,
+		},
+		{
+			name:       "given a valid configuration, when it's validated, then it's reported valid",
+			args:       []string{"config", "validate"},
+			file:       validConfig,
+			wantStatus: 0,
+			wantStdout: "the configuration is valid",
+		},
+		{
+			name:       "given a configuration with problems, when it's validated, then all of them are reported",
+			args:       []string{"config", "validate"},
+			file:       `{"total_number_of_cores": 8}`,
+			wantStatus: 2,
+			wantStderr: "  - " + config.TotalSizeOfMemoryMb + " is required\n  - " + config.TotalSizeOfDiskMb + " is required\n",
+		},
+		{
+			name:       "given unexpected arguments, when the command is run, then it's misused",
+			args:       []string{"config", "validate", "now"},
+			file:       validConfig,
+			wantStatus: 2,
+			wantStderr: `unexpected arguments "now"`,
+		},
+		{
+			name:       "given an unknown flag, when the reconcile is run, then it's misused",
+			args:       []string{"reconcile", "-fix"},
+			file:       validConfig,
+			wantStatus: 2,
+			wantStderr: "flag provided but not defined: -fix",
+		},
+		{
+			name:       "given a restore without snapshot, when it's run, then it's misused",
+			args:       []string{"restore"},
+			file:       validConfig,
+			wantStatus: 2,
+			wantStderr: "the snapshot file is required",
+		},
+		{
+			name:       "given a snapshot of an unknown version, when it's restored, then it's refused before touching the store",
+			args:       []string{"restore", "-"},
+			file:       validConfig,
+			stdin:      `{"version": 0, "data": {}}`,
+			wantStatus: 1,
+			wantStderr: "the snapshot version 0 is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cdmon2.json")
+			if err := ioutil.WriteFile(file, []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(config.ConfigFile, file)
+			if len(tt.stdin) > 0 {
+				stdin := filepath.Join(t.TempDir(), "stdin")
+				if err := ioutil.WriteFile(stdin, []byte(tt.stdin), 0600); err != nil {
+					t.Fatal(err)
+				}
+				f, err := os.Open(stdin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+				os.Stdin = f
+			}
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.wantStatus, run(tt.args, &stdout, &stderr))
+			assert.Contains(t, stdout.String(), tt.wantStdout)
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
+
+func TestRun_FileBackend(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "cdmon2.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fmt.Sprintf(`{
+		"total_number_of_cores": 8,
+		"total_size_of_memory": 2048,
+		"total_size_of_disk": 4096,
+		"store_backend": "file",
+		"store_file": %q,
+		"log_level": "error"
+	}`, filepath.Join(dir, "cdmon2.store"))), 0600))
+	t.Setenv(config.ConfigFile, file)
+
+	snapshot, err := backup.New(backup.Data{
+		Hostings: []domain.Hosting{{UUID: "h1", Name: "web", Cores: 2, MemoryMb: 10, DiskMb: 10}},
+		Indexes:  backup.Indexes{HostingNames: map[string]domain.UUID{"web": "h1"}},
+	}, time.Now())
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, snapshot.Write(&b))
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, ioutil.WriteFile(snapshotFile, b.Bytes(), 0600))
+
+	// The data written by a command is found by the next ones, without Redis
+	for _, step := range []struct {
+		args       []string
+		wantStdout string
+	}{
+		{args: []string{"restore", snapshotFile}, wantStdout: "restored 1 hostings"},
+		{args: []string{"reconcile"}, wantStdout: "no discrepancies found"},
+		{args: []string{"backup"}, wantStdout: `"name": "web"`},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run(step.args, &stdout, &stderr), stderr.String())
+		assert.Contains(t, stdout.String(), step.wantStdout)
+	}
+}
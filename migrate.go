@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/theskyinflames/cdmon2/app/repository"
+)
+
+// migrate rewrites the stored records with the codec of the configuration and the current schema of their type.
+// The records are upgraded when they're read anyway, so it's only needed to change the codec of the store,
+// or to drop the upgrades of a former schema.
+func migrate(args []string, stdout io.Writer) error {
+	err := noArgs(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	log := newLogger(cfg, os.Stderr)
+	repos, err := openRepositories(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repos.store.Close()
+
+	migrated, err := repository.Migrate(context.Background(), repos.store)
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RECORDS\tMIGRATED")
+	for _, m := range migrated {
+		fmt.Fprintf(tw, "%s\t%d\n", m.Pattern, m.Count)
+	}
+	tw.Flush()
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app/domain"
+)
+
+// reconcile checks the names index against the stored hostings, and whether they fit in the server, with
+// the service stopped. The server is built from the stored hostings, so its availability is consistent
+// by construction. A running service is checked, availability included, through its /admin/reconcile endpoint.
+func reconcile(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	repair := flags.Bool("repair", false, "repair the discrepancies found")
+	err := flags.Parse(args)
+	if err != nil {
+		return usageError{err}
+	}
+	err = noArgs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	log := newLogger(cfg, os.Stderr)
+
+	repos, err := openRepositories(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer repos.store.Close()
+
+	// Hostings which don't fit in the server are reported as a discrepancy
+	ctx := context.Background()
+	_, serverService, err := loadServer(ctx, cfg, log, repos)
+	if err != nil {
+		return err
+	}
+
+	discrepancies, err := serverService.Reconcile(ctx, *repair)
+	if err != nil {
+		return err
+	}
+	return reportDiscrepancies(stdout, discrepancies)
+}
+
+// reportDiscrepancies writes the discrepancies, and fails if any of them is left unrepaired
+func reportDiscrepancies(w io.Writer, discrepancies []domain.Discrepancy) error {
+	if len(discrepancies) == 0 {
+		fmt.Fprintln(w, "no discrepancies found")
+		return nil
+	}
+
+	unrepaired := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tSUBJECT\tREPAIRED\tDETAIL")
+	for _, d := range discrepancies {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", d.Kind, d.Subject, d.Repaired, d.Detail)
+		if !d.Repaired {
+			unrepaired++
+		}
+	}
+	tw.Flush()
+
+	if unrepaired > 0 {
+		return errors.Errorf("%d of %d discrepancies are not repaired", unrepaired, len(discrepancies))
+	}
+	return nil
+}
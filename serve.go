@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/theskyinflames/cdmon2/app/store"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/pkg/errors"
+
+	"github.com/theskyinflames/cdmon2/app"
+	"github.com/theskyinflames/cdmon2/app/api"
+	"github.com/theskyinflames/cdmon2/app/config"
+	"github.com/theskyinflames/cdmon2/app/domain"
+	"github.com/theskyinflames/cdmon2/app/metrics"
+	"github.com/theskyinflames/cdmon2/app/ratelimit"
+	"github.com/theskyinflames/cdmon2/app/repository"
+	"github.com/theskyinflames/cdmon2/app/service"
+)
+
+// serve runs the hosting service until it's asked to stop
+func serve(args []string, stdout io.Writer) error {
+	err := noArgs(args)
+	if err != nil {
+		return err
+	}
+
+	// The signals are handled from the start, so the ones received during the start up don't kill the process
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// The service logger is used all along, not the logrus global one
+	log := newLogger(cfg, stdout)
+	log.Info(spew.Sdump(cfg))
+
+	// Init the hostings server domain
+	serverDomain, err := domain.NewServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Init the hostings repository, on Redis or on a local file
+	s, err := openStore(cfg, log)
+	if err != nil {
+		return err
+	}
+	redisStore, isRedis := s.(*store.Store)
+	if cfg.FlushOnStart {
+		log.Warn("flushing the store, all the stored data is removed")
+		err = s.Flush()
+		if err != nil {
+			s.Close()
+			return errors.Wrap(err, "flushing the store")
+		}
+	}
+
+	// The store operations are measured
+	registry := metrics.NewRegistry()
+	instrumentedStore := metrics.NewInstrumentedStore(s, registry)
+	if isRedis {
+		metrics.RegisterBreaker(registry, redisStore.Breaker())
+	}
+	hostingsRepository := repository.NewHostingReposytoryMap(cfg, instrumentedStore)
+	customersRepository := repository.NewCustomerRepositoryMap(cfg, instrumentedStore)
+	projectsRepository := repository.NewProjectRepositoryMap(cfg, instrumentedStore)
+
+	// Init the hostings server service
+	serverService := service.NewServer(hostingsRepository, customersRepository, projectsRepository, serverDomain, cfg, log)
+	metrics.RegisterServer(registry, serverService)
+
+	// The server takes the resources of the hostings stored by the previous executions, even if they don't fit in it.
+	// The discrepancies left by them are reported before serving, and only repaired if it's configured so.
+	err = serverService.LoadServer(context.Background())
+	if errors.Cause(err) == app.DomainErrorQuotaExceeded {
+		log.WithError(err).Error("the stored hostings don't fit in the server, no hosting can be created until some are removed or the capacity is raised")
+		err = nil
+	}
+	if err == nil {
+		_, err = serverService.Reconcile(context.Background(), cfg.RepairOnStart)
+	}
+	if err != nil {
+		s.Close()
+		return errors.Wrap(err, "the stored hostings could not be loaded")
+	}
+
+	// Init the customers service
+	customerService := service.NewCustomer(customersRepository, projectsRepository, hostingsRepository, serverService, cfg, log)
+
+	// Init the authentication service
+	authService := service.NewAuth(repository.NewAPIKeyRepositoryMap(cfg, instrumentedStore), cfg, log)
+	err = authService.Bootstrap(context.Background())
+	if err != nil {
+		s.Close()
+		return err
+	}
+
+	// Init the controller
+	controller := api.NewController(serverService, customerService, authService, log, cfg)
+
+	// Init the rate limiter
+	var limiter api.RateLimiter = ratelimit.NewMemoryLimiter()
+	if cfg.RateLimitStore == config.RateLimitStoreRedis && isRedis {
+		limiter = ratelimit.NewRedisLimiter(redisStore.Client())
+	}
+
+	// Init the readiness checks. The service is ready once the start up tasks are done.
+	probes := api.NewProbes()
+	probes.AddCheck(cfg.StoreBackend, s.Ping)
+	probes.SetStarted()
+
+	// A stop asked during the start up is honoured before serving
+	select {
+	case sig := <-signals:
+		log.Infof("received %s signal during the start up", sig.String())
+		err = s.Close()
+		if err != nil {
+			log.Errorf("the store could not be closed: %s", err.Error())
+		}
+		log.Info("hosting service stopped")
+		return nil
+	default:
+	}
+
+	// Start the API
+	apiServer := api.NewApi(controller, limiter, probes, registry, log, cfg)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- apiServer.Start()
+	}()
+
+	// The capacity is reloaded on SIGHUP, as through the admin endpoint. Its outcome is logged by the service.
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for range reloads {
+			log.Info("received SIGHUP signal, reloading the capacity")
+			serverService.ReloadCapacity(context.Background())
+		}
+	}()
+
+	// Serve until the API fails or the process is asked to stop
+	var failure error
+	select {
+	case failure = <-errs:
+		if failure != nil {
+			log.Errorf("hosting service failed: %s", failure.Error())
+		}
+	case sig := <-signals:
+		log.Infof("received %s signal", sig.String())
+	}
+
+	// No capacity reload is started from now on, and the one in progress is waited for
+	signal.Stop(reloads)
+	close(reloads)
+	<-reloaded
+
+	// Drain the in-flight requests before closing the store, so none of them is cut
+	// between the server resources reservation and its persistence
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = apiServer.Shutdown(ctx)
+	if err != nil {
+		log.Errorf("the in-flight requests could not be drained: %s", err.Error())
+	}
+
+	err = s.Close()
+	if err != nil {
+		log.Errorf("the store could not be closed: %s", err.Error())
+	}
+	log.Info("hosting service stopped")
+	return errors.Wrap(failure, "hosting service failed")
+}
@@ -6,5 +6,36 @@ export CDMON2_TOTAL_SIZE_OF_MEMORY=100
 export CDMON2_TOTAL_SIZE_OF_DISK=100
 export CDMON2_MINIMAL_NUMBER_OF_CORES=1
 export CDMON2_MINIMAL_SIZE_OF_MEMORY=1
-export CDMON2_MININAML_SIZE_OF_DISK=1
+export CDMON2_MINIMAL_SIZE_OF_DISK=1
 export CDMON2_REDIS_ADDR=localhost:6379
+export CDMON2_REDIS_PASSWORD=
+export CDMON2_REDIS_DB=0
+export CDMON2_REDIS_TLS=false
+export CDMON2_AUTH_ENABLED=true
+export CDMON2_JWT_SECRET=change-me
+export CDMON2_BOOTSTRAP_API_KEY=change-me
+export CDMON2_RATE_LIMIT=20:40
+export CDMON2_RATE_LIMIT_ROUTES="POST /hosting=1:5"
+export CDMON2_RATE_LIMIT_STORE=memory
+export CDMON2_HTTP_READ_TIMEOUT=10s
+export CDMON2_HTTP_WRITE_TIMEOUT=30s
+export CDMON2_HTTP_IDLE_TIMEOUT=2m
+export CDMON2_SHUTDOWN_TIMEOUT=30s
+export CDMON2_LOG_LEVEL=info
+export CDMON2_LOG_FORMAT=json
+export CDMON2_STORE_TIMEOUT=2s
+export CDMON2_STORE_OP_TIMEOUTS=get_all=5s
+export CDMON2_REDIS_CONNECT_ATTEMPTS=10
+export CDMON2_REDIS_CONNECT_BACKOFF=500ms
+export CDMON2_STORE_RETRIES=2
+export CDMON2_STORE_RETRY_BACKOFF=50ms
+export CDMON2_STORE_BREAKER_FAILURES=5
+export CDMON2_STORE_BREAKER_COOLDOWN=10s
+export CDMON2_STORE_CODEC=gob
+export CDMON2_STORE_BACKEND=redis
+export CDMON2_STORE_FILE=cdmon2.store
+export CDMON2_STORE_FSYNC=always
+export CDMON2_STORE_FSYNC_INTERVAL=1s
+export CDMON2_STORE_COMPACT_INTERVAL=10m
+export CDMON2_REPAIR_ON_START=false
+export CDMON2_FLUSH_ON_START=false